@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	localkafka "github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// defaultServer is the thoth instance asked for topics and subjects when
+// THOTH_URL is not set.
+const defaultServer = "http://localhost:8080"
+
+// diffSource is an argument of "thoth diff", in the layout of the schema
+// references accepted by "/schema/diff". Files are loaded into schema.
+type diffSource struct {
+	Schema  json.RawMessage `json:"schema,omitempty"`
+	Topic   string          `json:"topic,omitempty"`
+	Subject string          `json:"subject,omitempty"`
+	Version int             `json:"version,omitempty"`
+
+	schema *schema.Schema
+}
+
+// runDiff implements "thoth diff OLD NEW". Each argument is a file holding a
+// schema document or a sample message, "topic:<name>" for the schema
+// accumulated from the messages of a topic, or "subject:<name>[@<version>]"
+// for a registered version, the latest by default. Topics and subjects are
+// diffed by the running thoth at THOTH_URL, as only it holds them. The report
+// is printed as JSON and the exit status is 1 when a change breaks backward or
+// forward compatibility.
+func runDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: thoth diff OLD NEW")
+		fmt.Fprintln(os.Stderr, "OLD and NEW are files, topic:<name> or subject:<name>[@<version>]")
+		return 2
+	}
+
+	oldSource, err := parseSource(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", args[0], err)
+		return 2
+	}
+	newSource, err := parseSource(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", args[1], err)
+		return 2
+	}
+
+	var report schema.DiffReport
+	if oldSource.schema != nil && newSource.schema != nil {
+		report = schema.Compare(oldSource.schema, newSource.schema)
+	} else if report, err = remoteDiff(oldSource, newSource); err != nil {
+		fmt.Fprintf(os.Stderr, "Error diffing: %v\n", err)
+		return 2
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding report: %v\n", err)
+		return 2
+	}
+	fmt.Println(string(output))
+
+	if !report.BackwardCompatible || !report.ForwardCompatible {
+		return 1
+	}
+	return 0
+}
+
+// parseSource reads an argument of "thoth diff", loading files right away.
+func parseSource(arg string) (diffSource, error) {
+	if topic, found := strings.CutPrefix(arg, "topic:"); found {
+		return diffSource{Topic: topic}, nil
+	}
+	if subject, found := strings.CutPrefix(arg, "subject:"); found {
+		name, version, hasVersion := strings.Cut(subject, "@")
+		source := diffSource{Subject: name}
+		if hasVersion {
+			number, err := strconv.Atoi(version)
+			if err != nil || number <= 0 {
+				return diffSource{}, fmt.Errorf("invalid version %q", version)
+			}
+			source.Version = number
+		}
+		return source, nil
+	}
+
+	s, err := loadSchema(arg)
+	if err != nil {
+		return diffSource{}, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return diffSource{}, fmt.Errorf("error encoding schema: %v", err)
+	}
+	return diffSource{Schema: data, schema: s}, nil
+}
+
+// loadSchema reads a schema document, falling back to inferring the schema of
+// a sample message.
+func loadSchema(path string) (*schema.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if s, err := schema.Parse(data); err == nil {
+		return s, nil
+	}
+	return localkafka.InferSchema("", data)
+}
+
+// remoteDiff asks the thoth at THOTH_URL to diff two sources.
+func remoteDiff(oldSource, newSource diffSource) (schema.DiffReport, error) {
+	var report schema.DiffReport
+	server := os.Getenv("THOTH_URL")
+	if server == "" {
+		server = defaultServer
+	}
+	body, err := json.Marshal(map[string]diffSource{"old": oldSource, "new": newSource})
+	if err != nil {
+		return report, fmt.Errorf("error encoding diff request: %v", err)
+	}
+	resp, err := http.Post(strings.TrimSuffix(server, "/")+"/schema/diff", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return report, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return report, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return report, fmt.Errorf("error decoding diff report: %v", err)
+	}
+	return report, nil
+}
@@ -1,20 +1,51 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/segmentio/kafka-go"
 	localkafka "github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/profile"
+	"github.com/wolfchristopher/thoth/internal/registry"
 	"github.com/wolfchristopher/thoth/internal/routes"
+	"github.com/wolfchristopher/thoth/internal/storage"
 	"math/rand"
 	"net/http"
+	"os"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+
 	rand.Seed(time.Now().UnixNano())
 
 	http.HandleFunc("/kafka_config", routes.UpdateKafkaConfig)
 	http.HandleFunc("/schema", routes.ReceiveSchemaHandler)
+	http.HandleFunc("/schema/subjects", routes.SubjectsHandler)
+	http.HandleFunc("/schema/versions", routes.VersionsHandler)
+	http.HandleFunc("/schema/state", routes.StateHandler)
+	http.HandleFunc("/schema/metadata", routes.MetadataHandler)
+	http.HandleFunc("/schema/search", routes.SearchHandler)
+	http.HandleFunc("/schema/ids", routes.SchemaByIDHandler)
+	http.HandleFunc("/schema/export", routes.ExportSchemaHandler)
+	http.HandleFunc("/schema/fingerprints", routes.SchemaFingerprintsHandler)
+	http.HandleFunc("/schema/diff", routes.DiffSchemaHandler)
+	http.HandleFunc("/schema/enums", routes.EnumSuggestionsHandler)
+	http.HandleFunc("/schema/observed", routes.ObservedSchemaHandler)
+	http.HandleFunc("/profile", routes.ProfileHandler)
+	http.HandleFunc("/validation", routes.ValidationHandler)
+	http.HandleFunc("/mapping", routes.MappingHandler)
+
+	// Confluent Schema Registry compatible API
+	http.HandleFunc("/subjects", routes.ConfluentSubjectsHandler)
+	http.HandleFunc("/subjects/", routes.ConfluentSubjectsHandler)
+	http.HandleFunc("/schemas/ids/", routes.ConfluentSchemaIDsHandler)
+	http.HandleFunc("/config", routes.ConfluentConfigHandler)
+	http.HandleFunc("/config/", routes.ConfluentConfigHandler)
+	http.HandleFunc("/compatibility/", routes.ConfluentCompatibilityHandler)
 
 	writer := &localkafka.LocalKafkaWriter{
 		Writer: &kafka.Writer{
@@ -31,15 +62,76 @@ func main() {
 
 	go localkafka.StartKafkaProducer(writer, localkafka.GenerateTransaction)
 
-	// Start Kafka consumer with mock logic
-	go localkafka.StartKafkaConsumer(
-		func(value []byte) (map[string]interface{}, error) {
-			return map[string]interface{}{"message": string(value)}, nil
-		},
-		func(data map[string]interface{}) map[string]string {
-			return map[string]string{"schema": "mock-schema"}
-		},
+	observer := localkafka.NewSchemaObserver()
+	routes.SetSchemaObserver(observer)
+
+	profiler := profile.NewProfiler()
+	routes.SetProfiler(profiler)
+
+	validator := localkafka.NewSchemaValidator()
+	routes.SetSchemaValidator(validator)
+
+	// The drift detector and the consumer are connected to the brokers of
+	// the Kafka config, and connected again whenever it changes.
+	driftDetector := localkafka.NewDriftDetector(nil, localkafka.DefaultDriftTopic)
+	defer func() {
+		if err := driftDetector.Close(); err != nil {
+			fmt.Printf("Error closing drift writer: %v\n", err)
+		}
+	}()
+	routes.SetDriftDetector(driftDetector)
+
+	consumer := localkafka.NewConsumer(
+		localkafka.ParseMessage,
+		localkafka.MapSchema,
+		observer.Handle,
+		driftDetector.Handle,
+		profiler.Handle,
+		validator.Handle,
 	)
+	routes.SetConsumer(consumer)
+
+	mappingPipeline := localkafka.NewMappingPipeline()
+	routes.SetMappingPipeline(mappingPipeline)
+
+	// THOTH_STORAGE selects where state is kept: "memory" (the default),
+	// "file:<path>", "bolt:<path>" or "kafka:<brokers>/<topic>?instance=<url>".
+	store, err := storage.Open(os.Getenv("THOTH_STORAGE"))
+	if err != nil {
+		fmt.Printf("Error opening storage: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	schemaRegistry, err := registry.Open(store)
+	if err != nil {
+		fmt.Printf("Error loading registry: %v\n", err)
+		os.Exit(1)
+	}
+	routes.SetRegistry(schemaRegistry)
+	if err := routes.SetStore(store); err != nil {
+		fmt.Printf("Error loading state: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Map the pre-service topic to the post-service topic, on the brokers of
+	// the Kafka config, once both topics and a mapping are configured.
+	go mappingPipeline.Run(context.Background(), func(topic string, connection localkafka.Connection) (localkafka.MessageReader, localkafka.KafkaWriter, error) {
+		reader, err := connection.NewReader(topic, "thoth-mapping-pipeline")
+		if err != nil {
+			return nil, nil, err
+		}
+		writer, err := connection.NewWriter()
+		if err != nil {
+			reader.Close()
+			return nil, nil, err
+		}
+		return reader, writer, nil
+	})
+
+	// Consume the topics of the Kafka config, "transactions" by default.
+	go consumer.Run(context.Background(), func(topic, groupID string, connection localkafka.Connection) (localkafka.MessageReader, error) {
+		return connection.NewReader(topic, groupID)
+	})
 
 	fmt.Println("Starting server on :8080...")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -9,3 +9,18 @@ kafka-topics.sh --create \
   --replication-factor 1 \
   --partitions 1 \
   --topic transactions || echo "Topic 'transactions' already exists"
+
+# Create the 'schema-drift' topic receiving schema drift events
+kafka-topics.sh --create \
+  --bootstrap-server localhost:9092 \
+  --replication-factor 1 \
+  --partitions 1 \
+  --topic schema-drift || echo "Topic 'schema-drift' already exists"
+
+# Create the compacted '_thoth' topic thoth instances share their state through
+kafka-topics.sh --create \
+  --bootstrap-server localhost:9092 \
+  --replication-factor 1 \
+  --partitions 1 \
+  --config cleanup.policy=compact \
+  --topic _thoth || echo "Topic '_thoth' already exists"
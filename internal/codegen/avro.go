@@ -0,0 +1,172 @@
+package codegen
+
+import (
+	"encoding/json"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// defaultDecimalPrecision is used for decimal fields whose precision was not
+// observed, which Avro requires to be positive.
+const defaultDecimalPrecision = 38
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Doc       string      `json:"doc,omitempty"`
+	Owners    []string    `json:"owners,omitempty"`
+	Tags      []string    `json:"tags,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name           string          `json:"name"`
+	Doc            string          `json:"doc,omitempty"`
+	Type           interface{}     `json:"type"`
+	Default        json.RawMessage `json:"default,omitempty"`
+	OriginalName   string          `json:"originalName,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Classification string          `json:"classification,omitempty"`
+}
+
+type avroArray struct {
+	Type  string      `json:"type"`
+	Items interface{} `json:"items"`
+}
+
+type avroMap struct {
+	Type   string      `json:"type"`
+	Values interface{} `json:"values"`
+}
+
+type avroEnum struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols"`
+}
+
+type avroLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+	Precision   int    `json:"precision,omitempty"`
+	Scale       int    `json:"scale,omitempty"`
+}
+
+// Avro renders the schema as an Avro record schema. Optional fields become
+// unions with null defaulting to null, timestamps and decimals use Avro
+// logical types, string enums whose symbols are valid Avro names become Avro
+// enums and field names that are not valid Avro names are rewritten,
+// keeping the original in an "originalName" attribute. Field docs become Avro
+// docs; tags, classifications and owners are kept as custom attributes.
+// Discriminated unions become a union of one record per variant, named after
+// its discriminator value.
+func Avro(s *schema.Schema) ([]byte, error) {
+	names := uniqueNames{}
+	if len(s.Variants) > 0 {
+		union := make([]avroRecord, 0, len(s.Variants))
+		for _, variant := range s.Variants {
+			union = append(union, avroRootRecord(s, variant.Value, variant.Fields, names))
+		}
+		return json.MarshalIndent(union, "", "  ")
+	}
+	name := s.Name
+	if name == "" {
+		name = "Record"
+	}
+	return json.MarshalIndent(avroRootRecord(s, name, s.Fields, names), "", "  ")
+}
+
+// avroRootRecord returns a top level record of the schema holding fields.
+func avroRootRecord(s *schema.Schema, name string, fields []*schema.Field, names uniqueNames) avroRecord {
+	return avroRecord{
+		Type:      "record",
+		Name:      names.next(pascalCase(name)),
+		Namespace: s.Namespace,
+		Doc:       s.Metadata.Description,
+		Owners:    s.Metadata.Owners,
+		Tags:      s.Metadata.Tags,
+		Fields:    avroFields(fields, names),
+	}
+}
+
+func avroFields(fields []*schema.Field, names uniqueNames) []avroField {
+	fieldNames := uniqueNames{}
+	result := make([]avroField, 0, len(fields))
+	for _, field := range fields {
+		af := avroField{
+			Name:           fieldNames.next(identifier(field.Name)),
+			Doc:            field.Doc,
+			Type:           avroType(field, field.Name, names),
+			Tags:           field.Tags,
+			Classification: string(field.Classification),
+		}
+		if af.Name != field.Name {
+			af.OriginalName = field.Name
+		}
+		if !field.Required || field.Type == schema.TypeNull {
+			if field.Type != schema.TypeNull {
+				af.Type = []interface{}{"null", af.Type}
+			}
+			af.Default = json.RawMessage("null")
+		}
+		result = append(result, af)
+	}
+	return result
+}
+
+// avroType returns the Avro type of the field. Records are named after name,
+// which for array elements is the name of the enclosing array field.
+func avroType(field *schema.Field, name string, names uniqueNames) interface{} {
+	switch field.Type {
+	case schema.TypeNull:
+		return "null"
+	case schema.TypeBoolean:
+		return "boolean"
+	case schema.TypeInteger:
+		return "long"
+	case schema.TypeNumber:
+		return "double"
+	case schema.TypeTimestamp:
+		return avroLogical{Type: "long", LogicalType: "timestamp-millis"}
+	case schema.TypeDecimal:
+		precision := field.Precision
+		if precision <= 0 {
+			precision = defaultDecimalPrecision
+		}
+		return avroLogical{Type: "bytes", LogicalType: "decimal", Precision: precision, Scale: field.Scale}
+	case schema.TypeRecord:
+		return avroRecord{
+			Type:   "record",
+			Name:   names.next(pascalCase(name)),
+			Fields: avroFields(field.Fields, names),
+		}
+	case schema.TypeArray:
+		if field.Items == nil {
+			return avroArray{Type: "array", Items: "string"}
+		}
+		return avroArray{Type: "array", Items: avroType(field.Items, name, names)}
+	case schema.TypeMap:
+		if field.Values == nil {
+			return avroMap{Type: "map", Values: "string"}
+		}
+		return avroMap{Type: "map", Values: avroType(field.Values, name, names)}
+	case schema.TypeString:
+		if len(field.Enum) > 0 && avroSymbols(field.Enum) {
+			return avroEnum{Type: "enum", Name: names.next(pascalCase(name)), Symbols: field.Enum}
+		}
+		return "string"
+	default:
+		return "string"
+	}
+}
+
+// avroSymbols reports whether every symbol is a valid Avro enum symbol.
+func avroSymbols(symbols []string) bool {
+	for _, symbol := range symbols {
+		if identifier(symbol) != symbol {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package codegen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestAvro(t *testing.T) {
+	s := &schema.Schema{
+		Name:      "transaction",
+		Namespace: "com.example",
+		Fields: []*schema.Field{
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Timestamp", Type: schema.TypeTimestamp, Required: true},
+			{Name: "Amount", Type: schema.TypeDecimal, Required: true, Precision: 6, Scale: 2},
+			{Name: "PromotionCode", Type: schema.TypeString},
+			{Name: "item-count", Type: schema.TypeInteger, Required: true},
+			{Name: "Status", Type: schema.TypeString, Required: true, Enum: []string{"PENDING", "SETTLED"}},
+			{Name: "Currency", Type: schema.TypeString, Required: true, Enum: []string{"EUR", "US-D"}},
+			{Name: "Stock", Type: schema.TypeMap, Required: true, Values: &schema.Field{Type: schema.TypeInteger, Required: true}},
+			{Name: "Customer", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+				{Name: "Email", Type: schema.TypeString, Required: true},
+			}},
+			{Name: "CardNumber", Type: schema.TypeString, Required: true, Doc: "Masked card number", Tags: []string{"card"}, Classification: schema.ClassificationPII},
+		},
+		Metadata: schema.Metadata{Owners: []string{"payments-team"}},
+	}
+
+	output, err := Avro(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(output, &record); err != nil {
+		t.Fatalf("Failed to unmarshal Avro schema: %v", err)
+	}
+	fields := map[string]map[string]interface{}{}
+	for _, f := range record["fields"].([]interface{}) {
+		field := f.(map[string]interface{})
+		fields[field["name"].(string)] = field
+	}
+
+	t.Run("Given documented fields, it should keep their metadata", func(t *testing.T) {
+		field := fields["CardNumber"]
+		if field["doc"] != "Masked card number" || field["classification"] != "pii" || !reflect.DeepEqual(field["tags"], []interface{}{"card"}) {
+			t.Errorf("Unexpected field metadata: %v", field)
+		}
+		if !reflect.DeepEqual(record["owners"], []interface{}{"payments-team"}) {
+			t.Errorf("Expected the owners, got %v", record["owners"])
+		}
+	})
+
+	t.Run("Given a schema, it should produce a namespaced record", func(t *testing.T) {
+		if record["name"] != "Transaction" || record["namespace"] != "com.example" {
+			t.Errorf("Expected com.example.Transaction, got %v.%v", record["namespace"], record["name"])
+		}
+	})
+
+	t.Run("Given an optional field, it should produce a nullable union defaulting to null", func(t *testing.T) {
+		field := fields["PromotionCode"]
+		if !reflect.DeepEqual(field["type"], []interface{}{"null", "string"}) {
+			t.Errorf("Expected [null string], got %v", field["type"])
+		}
+		if value, ok := field["default"]; !ok || value != nil {
+			t.Errorf("Expected a null default, got %v", value)
+		}
+	})
+
+	t.Run("Given timestamps and decimals, it should use logical types", func(t *testing.T) {
+		timestamp := fields["Timestamp"]["type"].(map[string]interface{})
+		if timestamp["logicalType"] != "timestamp-millis" {
+			t.Errorf("Expected timestamp-millis, got %v", timestamp)
+		}
+		amount := fields["Amount"]["type"].(map[string]interface{})
+		if amount["logicalType"] != "decimal" || amount["precision"] != float64(6) || amount["scale"] != float64(2) {
+			t.Errorf("Expected decimal(6,2), got %v", amount)
+		}
+	})
+
+	t.Run("Given an invalid field name, it should rewrite it and keep the original", func(t *testing.T) {
+		field, ok := fields["item_count"]
+		if !ok {
+			t.Fatalf("Expected field item_count, got %v", fields)
+		}
+		if field["originalName"] != "item-count" {
+			t.Errorf("Expected originalName item-count, got %v", field["originalName"])
+		}
+	})
+
+	t.Run("Given a nested record, it should name the nested type after the field", func(t *testing.T) {
+		customer := fields["Customer"]["type"].(map[string]interface{})
+		if customer["type"] != "record" || customer["name"] != "Customer" {
+			t.Errorf("Expected record Customer, got %v", customer)
+		}
+	})
+
+	t.Run("Given a string enum, it should produce an Avro enum", func(t *testing.T) {
+		expected := map[string]interface{}{"type": "enum", "name": "Status", "symbols": []interface{}{"PENDING", "SETTLED"}}
+		if !reflect.DeepEqual(fields["Status"]["type"], expected) {
+			t.Errorf("Expected %v, got %v", expected, fields["Status"]["type"])
+		}
+	})
+
+	t.Run("Given enum symbols that are not Avro names, it should keep a string", func(t *testing.T) {
+		if fields["Currency"]["type"] != "string" {
+			t.Errorf("Expected string, got %v", fields["Currency"]["type"])
+		}
+	})
+
+	t.Run("Given a map, it should produce an Avro map", func(t *testing.T) {
+		expected := map[string]interface{}{"type": "map", "values": "long"}
+		if !reflect.DeepEqual(fields["Stock"]["type"], expected) {
+			t.Errorf("Expected %v, got %v", expected, fields["Stock"]["type"])
+		}
+	})
+
+	t.Run("Given a discriminated union, it should produce a union of variant records", func(t *testing.T) {
+		output, err := Avro(eventUnion())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var records []map[string]interface{}
+		if err := json.Unmarshal(output, &records); err != nil {
+			t.Fatalf("Expected a JSON array: %v\n%s", err, output)
+		}
+		if len(records) != 2 || records[0]["name"] != "Created" || records[1]["name"] != "Shipped" {
+			t.Fatalf("Expected the records Created and Shipped, got %s", output)
+		}
+		if fields := records[1]["fields"].([]interface{}); len(fields) != 2 || fields[1].(map[string]interface{})["name"] != "carrier" {
+			t.Errorf("Expected Shipped to keep its carrier field, got %v", fields)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// defaultGoPackage is the package of generated structs when the schema has no
+// namespace.
+const defaultGoPackage = "models"
+
+type goWriter struct {
+	types []string
+	names uniqueNames
+	xml   bool
+}
+
+// GoStructs renders the schema as Go struct definitions with xml and json
+// tags. Optional fields use pointer types and nested records become their own
+// named types. Records of XML schemas that only wrap a list of elements
+// become slices. Discriminated unions become one struct per variant, named
+// after its discriminator value. The package is the last element of the
+// schema namespace, or "models" when there is none.
+func GoStructs(s *schema.Schema) ([]byte, error) {
+	w := &goWriter{names: uniqueNames{}, xml: s.Format == schema.FormatXML}
+	name := pascalCase(s.Name)
+	if s.Name == "" {
+		name = "Record"
+	}
+	xmlName := s.Name
+	if xmlName == "" {
+		xmlName = name
+	}
+	if len(s.Variants) == 0 {
+		w.writeStruct(w.names.next(name), xmlName, s.Fields, true)
+	}
+	for _, variant := range s.Variants {
+		w.writeStruct(w.names.next(pascalCase(variant.Value)), xmlName, variant.Fields, true)
+	}
+
+	pkg := defaultGoPackage
+	if s.Namespace != "" {
+		pkg = identifier(s.Namespace[strings.LastIndex(s.Namespace, ".")+1:])
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by thoth. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import \"encoding/xml\"\n\n")
+	for _, typ := range w.types {
+		b.WriteString(typ)
+	}
+	return format.Source([]byte(b.String()))
+}
+
+// writeStruct renders a struct type. The slot of the type is reserved before
+// its nested types are rendered so that parents precede their children.
+func (w *goWriter) writeStruct(name, xmlName string, fields []*schema.Field, root bool) {
+	index := len(w.types)
+	w.types = append(w.types, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s structure\n", name)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	if root {
+		fmt.Fprintf(&b, "XMLName xml.Name `xml:%q json:\"-\"`\n", xmlName)
+	}
+	fieldNames := uniqueNames{"XMLName": 1}
+	for _, field := range fields {
+		xmlTag := field.Name
+		var typ string
+		if wrapped := wrappedArray(field); wrapped != nil && w.xml {
+			// <Items><Item/><Item/></Items> maps to `xml:"Items>Item"`
+			xmlTag = field.Name + ">" + wrapped.Name
+			typ = w.goType(wrapped)
+		} else {
+			typ = w.goType(field)
+		}
+		jsonTag := field.Name
+		switch {
+		case field.Attribute:
+			xmlTag += ",attr"
+		case field.Name == schema.TextField:
+			xmlTag, jsonTag = ",chardata", "text"
+		}
+		if !field.Required {
+			xmlTag, jsonTag = xmlTag+",omitempty", jsonTag+",omitempty"
+		}
+		if field.Type == schema.TypeMap {
+			// encoding/xml cannot marshal maps.
+			xmlTag = "-"
+		}
+		tags := fmt.Sprintf("xml:%q json:%q", xmlTag, jsonTag)
+		fmt.Fprintf(&b, "%s %s `%s`\n", fieldNames.next(pascalCase(field.Name)), typ, tags)
+	}
+	b.WriteString("}\n\n")
+	w.types[index] = b.String()
+}
+
+// goType returns the Go type of the field, declaring struct types for records.
+func (w *goWriter) goType(field *schema.Field) string {
+	var typ string
+	switch field.Type {
+	case schema.TypeString, schema.TypeTimestamp:
+		typ = "string"
+	case schema.TypeInteger:
+		typ = "int"
+	case schema.TypeNumber, schema.TypeDecimal:
+		typ = "float64"
+	case schema.TypeBoolean:
+		typ = "bool"
+	case schema.TypeRecord:
+		typ = w.names.next(pascalCase(field.Name))
+		w.writeStruct(typ, field.Name, field.Fields, false)
+	case schema.TypeArray:
+		if field.Items == nil {
+			return "[]interface{}"
+		}
+		item := *field.Items
+		item.Name, item.Required = singular(field.Name), true
+		return "[]" + w.goType(&item)
+	case schema.TypeMap:
+		if field.Values == nil {
+			return "map[string]interface{}"
+		}
+		value := *field.Values
+		value.Name, value.Required = singular(field.Name), true
+		return "map[string]" + w.goType(&value)
+	default:
+		return "interface{}"
+	}
+	if !field.Required {
+		return "*" + typ
+	}
+	return typ
+}
+
+// wrappedArray returns the repeated child of a record that only wraps an
+// array, as XML documents do for lists of elements.
+func wrappedArray(field *schema.Field) *schema.Field {
+	if field.Type != schema.TypeRecord || len(field.Fields) != 1 || field.Fields[0].Type != schema.TypeArray {
+		return nil
+	}
+	return field.Fields[0]
+}
+
+// singular makes a best effort to name the element of a plural array field.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") &&
+		!strings.HasSuffix(name, "us") && !strings.HasSuffix(name, "is"):
+		return name[:len(name)-1]
+	default:
+		return name
+	}
+}
@@ -0,0 +1,134 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestGoStructs(t *testing.T) {
+	t.Run("Given the transaction schema, it should generate the transaction models", func(t *testing.T) {
+		s := &schema.Schema{
+			Name:   "Transaction",
+			Format: schema.FormatXML,
+			Fields: []*schema.Field{
+				{Name: "ID", Type: schema.TypeString, Required: true},
+				{Name: "Amount", Type: schema.TypeDecimal, Required: true, Precision: 5, Scale: 2},
+				{Name: "Customer", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "Name", Type: schema.TypeString, Required: true},
+				}},
+				{Name: "Items", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "Item", Type: schema.TypeArray, Required: true, Items: &schema.Field{
+						Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+							{Name: "Quantity", Type: schema.TypeInteger, Required: true},
+						},
+					}},
+				}},
+				{Name: "PromotionCode", Type: schema.TypeString},
+				{Name: "Discount", Type: schema.TypeDecimal},
+			},
+		}
+
+		output, err := GoStructs(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		source := string(output)
+
+		for _, expected := range []string{
+			"package models",
+			"type Transaction struct {",
+			"XMLName       xml.Name `xml:\"Transaction\" json:\"-\"`",
+			"ID            string   `xml:\"ID\" json:\"ID\"`",
+			"Customer      Customer `xml:\"Customer\" json:\"Customer\"`",
+			"Items         []Item   `xml:\"Items>Item\" json:\"Items\"`",
+			"PromotionCode *string  `xml:\"PromotionCode,omitempty\" json:\"PromotionCode,omitempty\"`",
+			"Discount      *float64 `xml:\"Discount,omitempty\" json:\"Discount,omitempty\"`",
+			"type Customer struct {",
+			"type Item struct {",
+			"Quantity int `xml:\"Quantity\" json:\"Quantity\"`",
+		} {
+			if !strings.Contains(source, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, source)
+			}
+		}
+		if strings.Index(source, "type Transaction") > strings.Index(source, "type Customer") {
+			t.Errorf("Expected the root type to come first:\n%s", source)
+		}
+	})
+
+	t.Run("Given a namespace, it should use its last element as the package", func(t *testing.T) {
+		s := &schema.Schema{Name: "user", Namespace: "com.example.users", Fields: []*schema.Field{
+			{Name: "tags", Type: schema.TypeArray, Required: true, Items: &schema.Field{Type: schema.TypeString, Required: true}},
+		}}
+
+		output, err := GoStructs(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		source := string(output)
+
+		for _, expected := range []string{"package users", "type User struct {", "Tags    []string `xml:\"tags\" json:\"tags\"`"} {
+			if !strings.Contains(source, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, source)
+			}
+		}
+	})
+
+	t.Run("Given a JSON record holding only a list, it should keep the record", func(t *testing.T) {
+		s := &schema.Schema{Name: "post", Fields: []*schema.Field{
+			{Name: "tags", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+				{Name: "list", Type: schema.TypeArray, Required: true, Items: &schema.Field{Type: schema.TypeString, Required: true}},
+			}},
+		}}
+
+		output, err := GoStructs(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		source := string(output)
+
+		for _, expected := range []string{"Tags    Tags     `xml:\"tags\" json:\"tags\"`", "List []string `xml:\"list\" json:\"list\"`"} {
+			if !strings.Contains(source, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, source)
+			}
+		}
+	})
+
+	t.Run("Given a map, it should use a Go map left out of XML", func(t *testing.T) {
+		output, err := GoStructs(&schema.Schema{Name: "order", Fields: []*schema.Field{
+			{Name: "id", Type: schema.TypeString, Required: true},
+			{Name: "items", Type: schema.TypeMap, Required: true, Values: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(output), "map[string]Item `xml:\"-\" json:\"items\"`") {
+			t.Errorf("Expected a map of Item in:\n%s", output)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should generate a struct per variant", func(t *testing.T) {
+		output, err := GoStructs(eventUnion())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		source := string(output)
+
+		for _, expected := range []string{
+			"type Created struct {",
+			"Total   float64  `xml:\"total\" json:\"total\"`",
+			"type Shipped struct {",
+			"Carrier *string  `xml:\"carrier,omitempty\" json:\"carrier,omitempty\"`",
+		} {
+			if !strings.Contains(source, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, source)
+			}
+		}
+	})
+}
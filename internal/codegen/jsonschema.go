@@ -0,0 +1,37 @@
+package codegen
+
+import (
+	"encoding/json"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// jsonSchemaDialect is the JSON Schema draft the documents declare.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema renders the schema as a JSON Schema document whose root
+// references the definition of the record, with nested records under $defs.
+// Optional fields are optional properties that may also be null and
+// discriminated unions are defined as one of their variants.
+func JSONSchema(s *schema.Schema) ([]byte, error) {
+	b := newJSONSchemaBuilder("#/$defs/")
+	name := s.Name
+	if name == "" {
+		name = "Record"
+	}
+	root := b.defineSchema(name, s)
+
+	document := map[string]interface{}{
+		"$schema": jsonSchemaDialect,
+		"title":   name,
+		"$ref":    "#/$defs/" + root,
+		"$defs":   b.definitions,
+	}
+	if s.Namespace != "" {
+		document["$id"] = s.Namespace + "." + root
+	}
+	if s.Metadata.Description != "" {
+		document["description"] = s.Metadata.Description
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
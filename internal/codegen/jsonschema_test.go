@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Run("Given a schema with an enum, it should render a draft 2020-12 document", func(t *testing.T) {
+		s := &schema.Schema{
+			Name:      "Transaction",
+			Namespace: "com.example",
+			Fields: []*schema.Field{
+				{Name: "ID", Type: schema.TypeString, Required: true},
+				{Name: "Status", Type: schema.TypeString, Required: true, Enum: []string{"PENDING", "SETTLED"}},
+				{Name: "Customer", Type: schema.TypeRecord, Fields: []*schema.Field{
+					{Name: "Email", Type: schema.TypeString, Required: true},
+				}},
+				{Name: "Channel", Type: schema.TypeString, Enum: []string{"WEB", "STORE"}},
+			},
+		}
+
+		output, err := JSONSchema(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document struct {
+			Schema string                            `json:"$schema"`
+			ID     string                            `json:"$id"`
+			Ref    string                            `json:"$ref"`
+			Defs   map[string]map[string]interface{} `json:"$defs"`
+		}
+		if err := json.Unmarshal(output, &document); err != nil {
+			t.Fatalf("Failed to unmarshal JSON Schema: %v", err)
+		}
+
+		if document.Schema != "https://json-schema.org/draft/2020-12/schema" || document.Ref != "#/$defs/Transaction" {
+			t.Errorf("Unexpected header: %s %s", document.Schema, document.Ref)
+		}
+		if document.ID != "com.example.Transaction" {
+			t.Errorf("Expected $id com.example.Transaction, got %s", document.ID)
+		}
+		properties := document.Defs["Transaction"]["properties"].(map[string]interface{})
+		expected := map[string]interface{}{"type": "string", "enum": []interface{}{"PENDING", "SETTLED"}}
+		if !reflect.DeepEqual(properties["Status"], expected) {
+			t.Errorf("Expected %v, got %v", expected, properties["Status"])
+		}
+		customer := map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/Customer"},
+			map[string]interface{}{"type": "null"},
+		}}
+		if !reflect.DeepEqual(properties["Customer"], customer) {
+			t.Errorf("Expected a nullable Customer reference, got %v", properties["Customer"])
+		}
+		channel := map[string]interface{}{"type": []interface{}{"string", "null"}, "enum": []interface{}{"WEB", "STORE", nil}}
+		if !reflect.DeepEqual(properties["Channel"], channel) {
+			t.Errorf("Expected %v, got %v", channel, properties["Channel"])
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// identifier turns an arbitrary field name into a name made of letters,
+// digits and underscores that does not start with a digit.
+func identifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	return id
+}
+
+// pascalCase turns a field name such as "promotion_code" or "item-id" into
+// "PromotionCode" or "ItemId".
+func pascalCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	var b strings.Builder
+	for _, word := range words {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "X" + id
+	}
+	return id
+}
+
+// uniqueNames hands out names that have not been used before by appending a
+// numeric suffix to repeated names.
+type uniqueNames map[string]int
+
+func (u uniqueNames) next(name string) string {
+	u[name]++
+	if u[name] == 1 {
+		return name
+	}
+	candidate := name + strconv.Itoa(u[name])
+	for u[candidate] > 0 {
+		u[name]++
+		candidate = name + strconv.Itoa(u[name])
+	}
+	u[candidate]++
+	return candidate
+}
@@ -0,0 +1,167 @@
+package codegen
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// openAPIVersion is the OpenAPI release whose schema objects are plain JSON
+// Schema 2020-12.
+const openAPIVersion = "3.1.0"
+
+// jsonSchemaBuilder turns records into named JSON Schema definitions that
+// reference each other with refPrefix. With openAPI set, unions also carry an
+// OpenAPI discriminator object.
+type jsonSchemaBuilder struct {
+	refPrefix   string
+	openAPI     bool
+	definitions map[string]interface{}
+	names       uniqueNames
+}
+
+func newJSONSchemaBuilder(refPrefix string) *jsonSchemaBuilder {
+	return &jsonSchemaBuilder{refPrefix: refPrefix, definitions: map[string]interface{}{}, names: uniqueNames{}}
+}
+
+// defineSchema adds the definition of a schema and returns its name. The
+// schema of a discriminated union is defined as one of the definitions of its
+// variants, which are named after their discriminator value.
+func (b *jsonSchemaBuilder) defineSchema(name string, s *schema.Schema) string {
+	if len(s.Variants) == 0 {
+		return b.define(name, s.Fields)
+	}
+	name = b.names.next(pascalCase(name))
+	oneOf := make([]interface{}, 0, len(s.Variants))
+	mapping := map[string]interface{}{}
+	for _, variant := range s.Variants {
+		ref := b.refPrefix + b.define(variant.Value, variant.Fields)
+		oneOf = append(oneOf, map[string]interface{}{"$ref": ref})
+		mapping[variant.Value] = ref
+	}
+	definition := map[string]interface{}{"oneOf": oneOf}
+	if b.openAPI {
+		definition["discriminator"] = map[string]interface{}{"propertyName": s.Discriminator, "mapping": mapping}
+	}
+	b.definitions[name] = definition
+	return name
+}
+
+// define adds the object definition of fields under a unique name derived
+// from name and returns that name.
+func (b *jsonSchemaBuilder) define(name string, fields []*schema.Field) string {
+	name = b.names.next(pascalCase(name))
+	properties := map[string]interface{}{}
+	var required []string
+	for _, field := range fields {
+		property := b.fieldSchema(field, field.Name)
+		if !field.Required {
+			property = nullable(property)
+		}
+		if field.Doc != "" {
+			property["description"] = field.Doc
+		}
+		properties[field.Name] = property
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+	definition := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		definition["required"] = required
+	}
+	b.definitions[name] = definition
+	return name
+}
+
+// nullable lets a property also be null, as optional fields may have been
+// seen as null in consumed messages. Properties accepting any value are kept.
+func nullable(property map[string]interface{}) map[string]interface{} {
+	switch t := property["type"].(type) {
+	case string:
+		if t == "null" {
+			return property
+		}
+		property["type"] = []string{t, "null"}
+		if symbols, ok := property["enum"].([]string); ok {
+			enum := make([]interface{}, 0, len(symbols)+1)
+			for _, symbol := range symbols {
+				enum = append(enum, symbol)
+			}
+			property["enum"] = append(enum, nil)
+		}
+		return property
+	case nil:
+		if len(property) == 0 {
+			return property
+		}
+		return map[string]interface{}{"anyOf": []interface{}{property, map[string]interface{}{"type": "null"}}}
+	}
+	return property
+}
+
+// fieldSchema returns the JSON Schema of a field. Records are named after name,
+// which for array elements is the singular of the enclosing array field.
+func (b *jsonSchemaBuilder) fieldSchema(field *schema.Field, name string) map[string]interface{} {
+	switch field.Type {
+	case schema.TypeNull:
+		return map[string]interface{}{"type": "null"}
+	case schema.TypeString:
+		if len(field.Enum) > 0 {
+			return map[string]interface{}{"type": "string", "enum": field.Enum}
+		}
+		return map[string]interface{}{"type": "string"}
+	case schema.TypeInteger:
+		return map[string]interface{}{"type": "integer"}
+	case schema.TypeNumber, schema.TypeDecimal:
+		return map[string]interface{}{"type": "number"}
+	case schema.TypeBoolean:
+		return map[string]interface{}{"type": "boolean"}
+	case schema.TypeTimestamp:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case schema.TypeRecord:
+		return map[string]interface{}{"$ref": b.refPrefix + b.define(name, field.Fields)}
+	case schema.TypeArray:
+		items := map[string]interface{}{}
+		if field.Items != nil {
+			items = b.fieldSchema(field.Items, singular(name))
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case schema.TypeMap:
+		values := map[string]interface{}{}
+		if field.Values != nil {
+			values = b.fieldSchema(field.Values, singular(name))
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// OpenAPI renders the schema and its nested records as OpenAPI 3.1 component
+// schemas. Optional fields are optional properties that may also be null and
+// discriminated unions become oneOf schemas with a discriminator.
+func OpenAPI(s *schema.Schema) ([]byte, error) {
+	b := newJSONSchemaBuilder("#/components/schemas/")
+	b.openAPI = true
+	name := s.Name
+	if name == "" {
+		name = "Record"
+	}
+	b.defineSchema(name, s)
+
+	info := map[string]interface{}{"title": name, "version": "1"}
+	if s.Metadata.Version > 0 {
+		info["version"] = strconv.Itoa(s.Metadata.Version)
+	}
+	if s.Metadata.Description != "" {
+		info["description"] = s.Metadata.Description
+	}
+	document := map[string]interface{}{
+		"openapi":    openAPIVersion,
+		"info":       info,
+		"components": map[string]interface{}{"schemas": b.definitions},
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
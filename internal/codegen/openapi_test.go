@@ -0,0 +1,107 @@
+package codegen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("Given a nested schema, it should render referenced component schemas", func(t *testing.T) {
+		s := &schema.Schema{
+			Name:     "Transaction",
+			Metadata: schema.Metadata{Version: 2},
+			Fields: []*schema.Field{
+				{Name: "ID", Type: schema.TypeString, Required: true},
+				{Name: "Timestamp", Type: schema.TypeTimestamp, Required: true},
+				{Name: "Customer", Type: schema.TypeRecord, Fields: []*schema.Field{
+					{Name: "Email", Type: schema.TypeString, Required: true},
+				}},
+				{Name: "Note", Type: schema.TypeString},
+			},
+		}
+
+		output, err := OpenAPI(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document struct {
+			OpenAPI string `json:"openapi"`
+			Info    struct {
+				Version string `json:"version"`
+			} `json:"info"`
+			Components struct {
+				Schemas map[string]map[string]interface{} `json:"schemas"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(output, &document); err != nil {
+			t.Fatalf("Failed to unmarshal OpenAPI document: %v", err)
+		}
+
+		if document.OpenAPI != "3.1.0" || document.Info.Version != "2" {
+			t.Errorf("Unexpected header: %s %s", document.OpenAPI, document.Info.Version)
+		}
+		transaction := document.Components.Schemas["Transaction"]
+		if !reflect.DeepEqual(transaction["required"], []interface{}{"ID", "Timestamp"}) {
+			t.Errorf("Expected ID and Timestamp to be required, got %v", transaction["required"])
+		}
+		properties := transaction["properties"].(map[string]interface{})
+		customer := map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/Customer"},
+			map[string]interface{}{"type": "null"},
+		}}
+		if !reflect.DeepEqual(properties["Customer"], customer) {
+			t.Errorf("Expected a nullable Customer reference, got %v", properties["Customer"])
+		}
+		if !reflect.DeepEqual(properties["Note"], map[string]interface{}{"type": []interface{}{"string", "null"}}) {
+			t.Errorf("Expected a nullable string, got %v", properties["Note"])
+		}
+		if !reflect.DeepEqual(properties["Timestamp"], map[string]interface{}{"type": "string", "format": "date-time"}) {
+			t.Errorf("Expected a date-time string, got %v", properties["Timestamp"])
+		}
+		if _, ok := document.Components.Schemas["Customer"]; !ok {
+			t.Errorf("Expected a Customer component, got %v", document.Components.Schemas)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should render oneOf with a discriminator", func(t *testing.T) {
+		s := &schema.Schema{Name: "Event", Discriminator: "type",
+			Fields: []*schema.Field{{Name: "type", Type: schema.TypeString, Required: true}},
+			Variants: []*schema.Variant{
+				{Value: "order.created", Fields: []*schema.Field{{Name: "type", Type: schema.TypeString, Required: true}}},
+				{Value: "order.shipped", Fields: []*schema.Field{{Name: "type", Type: schema.TypeString, Required: true}}},
+			},
+		}
+
+		output, err := OpenAPI(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document struct {
+			Components struct {
+				Schemas map[string]map[string]interface{} `json:"schemas"`
+			} `json:"components"`
+		}
+		if err := json.Unmarshal(output, &document); err != nil {
+			t.Fatalf("Failed to unmarshal OpenAPI document: %v", err)
+		}
+
+		event := document.Components.Schemas["Event"]
+		expectedOneOf := []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/OrderCreated"},
+			map[string]interface{}{"$ref": "#/components/schemas/OrderShipped"},
+		}
+		if !reflect.DeepEqual(event["oneOf"], expectedOneOf) {
+			t.Errorf("Expected %v, got %v", expectedOneOf, event["oneOf"])
+		}
+		discriminator := event["discriminator"].(map[string]interface{})
+		if discriminator["propertyName"] != "type" {
+			t.Errorf("Expected the type discriminator, got %v", discriminator)
+		}
+		if _, ok := document.Components.Schemas["OrderShipped"]; !ok {
+			t.Errorf("Expected an OrderShipped component, got %v", document.Components.Schemas)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// FieldNumbers remembers the protobuf field numbers handed out for a subject,
+// keyed by message path and field name. Passing the same FieldNumbers to every
+// regeneration of a subject keeps existing numbers stable, gives new fields
+// fresh numbers and reserves the numbers of removed fields.
+type FieldNumbers struct {
+	Messages map[string]map[string]int `json:"messages"`
+}
+
+// NewFieldNumbers returns an empty numbering.
+func NewFieldNumbers() *FieldNumbers {
+	return &FieldNumbers{Messages: map[string]map[string]int{}}
+}
+
+// Clone returns a copy of the numbering.
+func (n *FieldNumbers) Clone() *FieldNumbers {
+	clone := NewFieldNumbers()
+	for path, fields := range n.Messages {
+		clone.Messages[path] = make(map[string]int, len(fields))
+		for name, number := range fields {
+			clone.Messages[path][name] = number
+		}
+	}
+	return clone
+}
+
+// number returns the field number of name in the message at path, assigning
+// the next free number when the field has not been seen before.
+func (n *FieldNumbers) number(path, name string) int {
+	fields, ok := n.Messages[path]
+	if !ok {
+		fields = map[string]int{}
+		n.Messages[path] = fields
+	}
+	if number, ok := fields[name]; ok {
+		return number
+	}
+	next := 1
+	for _, number := range fields {
+		if number >= next {
+			next = number + 1
+		}
+	}
+	fields[name] = next
+	return next
+}
+
+// removed returns the numbered fields of the message at path that are no
+// longer part of fields.
+func (n *FieldNumbers) removed(path string, fields []*schema.Field) map[string]int {
+	removed := map[string]int{}
+	for name, number := range n.Messages[path] {
+		if !hasField(fields, name) {
+			removed[name] = number
+		}
+	}
+	return removed
+}
+
+type protoWriter struct {
+	numbers *FieldNumbers
+	imports map[string]bool
+}
+
+// Proto renders the schema as a proto3 file. Records become nested messages,
+// arrays become repeated fields, maps become map<string, T> fields and
+// timestamps use google.protobuf.Timestamp. Discriminated unions keep their
+// shared fields and hold the other fields of each variant in a nested message
+// of a oneof. Field numbers are taken from and recorded in numbers.
+func Proto(s *schema.Schema, numbers *FieldNumbers) ([]byte, error) {
+	if numbers.Messages == nil {
+		numbers.Messages = map[string]map[string]int{}
+	}
+	w := &protoWriter{numbers: numbers, imports: map[string]bool{}}
+	name := pascalCase(s.Name)
+	if s.Name == "" {
+		name = "Record"
+	}
+	fields := s.Fields
+	var oneof *protoOneof
+	if len(s.Variants) > 0 {
+		fields = append([]*schema.Field(nil), s.Fields...)
+		oneof = &protoOneof{name: snakeCase(s.Discriminator) + "_variant", members: map[string]bool{}}
+		for _, variant := range s.Variants {
+			fields = append(fields, &schema.Field{Name: variant.Value, Type: schema.TypeRecord, Required: true, Fields: s.VariantFields(variant)})
+			oneof.members[variant.Value] = true
+		}
+	}
+	var body strings.Builder
+	w.writeMessage(&body, name, name, fields, oneof, 0)
+
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	if s.Namespace != "" {
+		fmt.Fprintf(&b, "package %s;\n\n", s.Namespace)
+	}
+	if len(w.imports) > 0 {
+		imports := make([]string, 0, len(w.imports))
+		for imp := range w.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		for _, imp := range imports {
+			fmt.Fprintf(&b, "import %q;\n", imp)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(body.String())
+	return []byte(b.String()), nil
+}
+
+// protoOneof names the fields of a message that make up its oneof.
+type protoOneof struct {
+	name    string
+	members map[string]bool
+}
+
+// writeMessage renders a message holding fields, the members of oneof, when
+// it is not nil, being written inside it.
+func (w *protoWriter) writeMessage(b *strings.Builder, name, path string, fields []*schema.Field, oneof *protoOneof, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(b, "%smessage %s {\n", indent, name)
+
+	nested := uniqueNames{}
+	var nestedBody strings.Builder
+	var fieldsBody, oneofBody strings.Builder
+	fieldNames := uniqueNames{}
+	for _, field := range fields {
+		typ, repeated := w.fieldType(field, path, nested, &nestedBody, depth+1)
+		member := oneof != nil && oneof.members[field.Name]
+		label := ""
+		switch {
+		case member:
+		case repeated:
+			label = "repeated "
+		case !field.Required && isProtoScalar(typ):
+			label = "optional "
+		}
+		body, fieldIndent := &fieldsBody, indent
+		if member {
+			body, fieldIndent = &oneofBody, indent+"  "
+		}
+		protoName := fieldNames.next(snakeCase(field.Name))
+		fmt.Fprintf(body, "%s  %s%s %s = %d", fieldIndent, label, typ, protoName, w.numbers.number(path, field.Name))
+		if protoName != field.Name {
+			fmt.Fprintf(body, " [json_name = %q]", field.Name)
+		}
+		body.WriteString(";\n")
+	}
+	if oneofBody.Len() > 0 {
+		fmt.Fprintf(&fieldsBody, "%s  oneof %s {\n%s%s  }\n", indent, fieldNames.next(oneof.name), oneofBody.String(), indent)
+	}
+
+	b.WriteString(nestedBody.String())
+	removed := w.numbers.removed(path, fields)
+	if len(removed) > 0 {
+		names := make([]string, 0, len(removed))
+		for name := range removed {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return removed[names[i]] < removed[names[j]] })
+		reserved := make([]string, len(names))
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			reserved[i] = fmt.Sprint(removed[name])
+			quoted[i] = fmt.Sprintf("%q", snakeCase(name))
+		}
+		fmt.Fprintf(b, "%s  reserved %s;\n", indent, strings.Join(reserved, ", "))
+		fmt.Fprintf(b, "%s  reserved %s;\n", indent, strings.Join(quoted, ", "))
+	}
+	b.WriteString(fieldsBody.String())
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// fieldType returns the protobuf type of the field and whether it repeats,
+// writing nested message definitions into nestedBody.
+func (w *protoWriter) fieldType(field *schema.Field, path string, nested uniqueNames, nestedBody *strings.Builder, depth int) (string, bool) {
+	switch field.Type {
+	case schema.TypeString, schema.TypeDecimal:
+		return "string", false
+	case schema.TypeInteger:
+		return "int64", false
+	case schema.TypeNumber:
+		return "double", false
+	case schema.TypeBoolean:
+		return "bool", false
+	case schema.TypeTimestamp:
+		w.imports["google/protobuf/timestamp.proto"] = true
+		return "google.protobuf.Timestamp", false
+	case schema.TypeRecord:
+		name := nested.next(pascalCase(field.Name))
+		w.writeMessage(nestedBody, name, path+"."+field.Name, field.Fields, nil, depth)
+		return name, false
+	case schema.TypeArray:
+		if field.Items == nil || field.Items.Type == schema.TypeArray {
+			w.imports["google/protobuf/struct.proto"] = true
+			return "google.protobuf.ListValue", true
+		}
+		item := *field.Items
+		item.Name = field.Name
+		typ, _ := w.fieldType(&item, path, nested, nestedBody, depth)
+		return typ, true
+	case schema.TypeMap:
+		// Map values cannot repeat, so nested collections use the struct
+		// well-known types.
+		switch {
+		case field.Values == nil:
+			w.imports["google/protobuf/struct.proto"] = true
+			return "map<string, google.protobuf.Value>", false
+		case field.Values.Type == schema.TypeArray:
+			w.imports["google/protobuf/struct.proto"] = true
+			return "map<string, google.protobuf.ListValue>", false
+		case field.Values.Type == schema.TypeMap:
+			w.imports["google/protobuf/struct.proto"] = true
+			return "map<string, google.protobuf.Struct>", false
+		}
+		value := *field.Values
+		value.Name = field.Name
+		typ, _ := w.fieldType(&value, path, nested, nestedBody, depth)
+		return "map<string, " + typ + ">", false
+	default:
+		w.imports["google/protobuf/struct.proto"] = true
+		return "google.protobuf.Value", false
+	}
+}
+
+func isProtoScalar(typ string) bool {
+	switch typ {
+	case "string", "int64", "double", "bool":
+		return true
+	}
+	return false
+}
+
+// snakeCase turns a field name such as "PromotionCode" or "item-id" into
+// "promotion_code" or "item_id".
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(identifier(name))
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func hasField(fields []*schema.Field, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
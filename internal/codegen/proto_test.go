@@ -0,0 +1,120 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestProto(t *testing.T) {
+	transaction := &schema.Schema{
+		Name:      "Transaction",
+		Namespace: "example.v1",
+		Fields: []*schema.Field{
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Timestamp", Type: schema.TypeTimestamp, Required: true},
+			{Name: "PromotionCode", Type: schema.TypeString},
+			{Name: "Items", Type: schema.TypeArray, Required: true, Items: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "Price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+		},
+	}
+
+	t.Run("Given a schema, it should render a proto3 file", func(t *testing.T) {
+		output, err := Proto(transaction, NewFieldNumbers())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		proto := string(output)
+
+		for _, expected := range []string{
+			`syntax = "proto3";`,
+			`package example.v1;`,
+			`import "google/protobuf/timestamp.proto";`,
+			`message Transaction {`,
+			`  message Items {`,
+			`    double price = 1 [json_name = "Price"];`,
+			`  string id = 1 [json_name = "ID"];`,
+			`  google.protobuf.Timestamp timestamp = 2 [json_name = "Timestamp"];`,
+			`  optional string promotion_code = 3 [json_name = "PromotionCode"];`,
+			`  repeated Items items = 4 [json_name = "Items"];`,
+		} {
+			if !strings.Contains(proto, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, proto)
+			}
+		}
+	})
+
+	t.Run("Given a regenerated subject, it should keep field numbers stable", func(t *testing.T) {
+		numbers := NewFieldNumbers()
+		if _, err := Proto(transaction, numbers); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		changed := &schema.Schema{Name: "Transaction", Fields: []*schema.Field{
+			{Name: "Currency", Type: schema.TypeString, Required: true},
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Items", Type: schema.TypeArray, Required: true, Items: transaction.Fields[3].Items},
+		}}
+		output, err := Proto(changed, numbers)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		proto := string(output)
+
+		for _, expected := range []string{
+			`  string currency = 5 [json_name = "Currency"];`,
+			`  string id = 1 [json_name = "ID"];`,
+			`  repeated Items items = 4 [json_name = "Items"];`,
+			`  reserved 2, 3;`,
+			`  reserved "timestamp", "promotion_code";`,
+		} {
+			if !strings.Contains(proto, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, proto)
+			}
+		}
+	})
+
+	t.Run("Given a map, it should render a map field", func(t *testing.T) {
+		output, err := Proto(&schema.Schema{Name: "order", Fields: []*schema.Field{
+			{Name: "id", Type: schema.TypeString, Required: true},
+			{Name: "items", Type: schema.TypeMap, Required: true, Values: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+		}}, NewFieldNumbers())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(output), "  map<string, Items> items = 2;") {
+			t.Errorf("Expected a map field in:\n%s", output)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should render a oneof of variant messages", func(t *testing.T) {
+		output, err := Proto(eventUnion(), NewFieldNumbers())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `message Event {
+  message Created {
+    double total = 1;
+  }
+  message Shipped {
+    optional string carrier = 1;
+  }
+  string type = 1;
+  oneof type_variant {
+    Created created = 2;
+    Shipped shipped = 3;
+  }
+}`
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected:\n%s\nin:\n%s", expected, output)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// SQL dialects supported by SQL.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+type sqlColumn struct {
+	name       string
+	typ        string
+	constraint string
+}
+
+type sqlTable struct {
+	name    string
+	columns []sqlColumn
+	foreign string
+}
+
+type sqlWriter struct {
+	dialect string
+	tables  []*sqlTable
+	names   uniqueNames
+}
+
+// SQL renders CREATE TABLE statements for the schema. Nested records are
+// flattened into prefixed columns, and arrays and maps become child tables
+// holding a foreign key to the row of their parent table. Discriminated
+// unions get a table per variant, suffixed with its discriminator value.
+func SQL(s *schema.Schema, dialect string) ([]byte, error) {
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	w := &sqlWriter{dialect: dialect, names: uniqueNames{}}
+	name := snakeCase(s.Name)
+	if s.Name == "" {
+		name = "record"
+	}
+	if len(s.Variants) == 0 {
+		w.table(w.names.next(name), s.Fields, nil)
+	}
+	for _, variant := range s.Variants {
+		w.table(w.names.next(name+"_"+snakeCase(variant.Value)), variant.Fields, nil)
+	}
+
+	var b strings.Builder
+	for i, table := range w.tables {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n", quoteSQL(table.name))
+		lines := make([]string, 0, len(table.columns)+1)
+		for _, column := range table.columns {
+			line := "  " + quoteSQL(column.name) + " " + column.typ
+			if column.constraint != "" {
+				line += " " + column.constraint
+			}
+			lines = append(lines, line)
+		}
+		if table.foreign != "" {
+			lines = append(lines, "  "+table.foreign)
+		}
+		b.WriteString(strings.Join(lines, ",\n"))
+		b.WriteString("\n);\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// table declares a table for fields. parent is the table the rows belong to,
+// or nil for the root table.
+func (w *sqlWriter) table(name string, fields []*schema.Field, parent *sqlTable) *sqlTable {
+	table := &sqlTable{name: name}
+	w.tables = append(w.tables, table)
+
+	// Root rows are keyed by their own id field when they have one, element
+	// rows always get a generated key.
+	var key *schema.Field
+	if parent == nil {
+		key = keyField(fields)
+	}
+	if key != nil {
+		table.columns = append(table.columns, sqlColumn{name: snakeCase(key.Name), typ: w.columnType(key), constraint: "PRIMARY KEY"})
+	} else {
+		table.columns = append(table.columns, sqlColumn{name: "id", typ: w.serialType(), constraint: "PRIMARY KEY"})
+	}
+	if parent != nil {
+		parentKey := parent.columns[0]
+		column := parent.name + "_" + parentKey.name
+		table.columns = append(table.columns, sqlColumn{name: column, typ: w.referenceType(parentKey), constraint: "NOT NULL"})
+		table.foreign = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			quoteSQL(column), quoteSQL(parent.name), quoteSQL(parentKey.name))
+	}
+
+	columns := uniqueNames{}
+	for _, column := range table.columns {
+		columns[column.name]++
+	}
+	w.columns(table, "", fields, true, key, columns)
+	return table
+}
+
+// columns adds a column per scalar field, flattening records with prefix and
+// declaring child tables for arrays.
+func (w *sqlWriter) columns(table *sqlTable, prefix string, fields []*schema.Field, required bool, key *schema.Field, names uniqueNames) {
+	for _, field := range fields {
+		name := prefix + snakeCase(field.Name)
+		switch field.Type {
+		case schema.TypeRecord:
+			w.columns(table, name+"_", field.Fields, required && field.Required, nil, names)
+		case schema.TypeArray:
+			w.childTable(table, name, field)
+		case schema.TypeMap:
+			w.mapTable(table, name, field)
+		default:
+			if field == key {
+				continue
+			}
+			column := sqlColumn{name: names.next(name), typ: w.columnType(field)}
+			if required && field.Required {
+				column.constraint = "NOT NULL"
+			}
+			table.columns = append(table.columns, column)
+		}
+	}
+}
+
+// childTable declares the table holding the elements of an array field.
+func (w *sqlWriter) childTable(parent *sqlTable, name string, field *schema.Field) {
+	items := field.Items
+	if items == nil {
+		items = &schema.Field{Type: schema.TypeAny, Required: true}
+	}
+	tableName := w.names.next(parent.name + "_" + name)
+	switch items.Type {
+	case schema.TypeRecord:
+		w.table(tableName, items.Fields, parent)
+	default:
+		value := *items
+		value.Name = "value"
+		w.table(tableName, []*schema.Field{&value}, parent)
+	}
+}
+
+// mapTable declares the table holding the entries of a map field, keyed by a
+// "key" column next to the reference to the parent row.
+func (w *sqlWriter) mapTable(parent *sqlTable, name string, field *schema.Field) {
+	values := field.Values
+	if values == nil {
+		values = &schema.Field{Type: schema.TypeAny, Required: true}
+	}
+	fields := []*schema.Field{{Name: "key", Type: schema.TypeString, Required: true}}
+	if values.Type == schema.TypeRecord {
+		fields = append(fields, values.Fields...)
+	} else {
+		value := *values
+		value.Name = "value"
+		fields = append(fields, &value)
+	}
+	w.table(w.names.next(parent.name+"_"+name), fields, parent)
+}
+
+func (w *sqlWriter) columnType(field *schema.Field) string {
+	postgres := w.dialect == DialectPostgres
+	switch field.Type {
+	case schema.TypeInteger:
+		if postgres {
+			return "BIGINT"
+		}
+		return "INTEGER"
+	case schema.TypeNumber:
+		if postgres {
+			return "DOUBLE PRECISION"
+		}
+		return "REAL"
+	case schema.TypeDecimal:
+		if field.Precision > 0 {
+			return fmt.Sprintf("NUMERIC(%d, %d)", field.Precision, field.Scale)
+		}
+		return "NUMERIC"
+	case schema.TypeBoolean:
+		if postgres {
+			return "BOOLEAN"
+		}
+		return "INTEGER"
+	case schema.TypeTimestamp:
+		if postgres {
+			return "TIMESTAMP"
+		}
+		return "TEXT"
+	case schema.TypeString:
+		return "TEXT"
+	default:
+		if postgres {
+			return "JSONB"
+		}
+		return "TEXT"
+	}
+}
+
+func (w *sqlWriter) serialType() string {
+	if w.dialect == DialectPostgres {
+		return "BIGSERIAL"
+	}
+	return "INTEGER"
+}
+
+// referenceType is the type of a column referencing key.
+func (w *sqlWriter) referenceType(key sqlColumn) string {
+	if key.typ == "BIGSERIAL" {
+		return "BIGINT"
+	}
+	return key.typ
+}
+
+// keyField returns the scalar field named "id", compared case insensitively.
+func keyField(fields []*schema.Field) *schema.Field {
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, "id") && field.Type != schema.TypeRecord && field.Type != schema.TypeArray && field.Type != schema.TypeMap {
+			return field
+		}
+	}
+	return nil
+}
+
+func quoteSQL(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,125 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestSQL(t *testing.T) {
+	transaction := &schema.Schema{
+		Name: "Transaction",
+		Fields: []*schema.Field{
+			{Name: "Amount", Type: schema.TypeDecimal, Required: true, Precision: 6, Scale: 2},
+			{Name: "Customer", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+				{Name: "Email", Type: schema.TypeString, Required: true},
+			}},
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Items", Type: schema.TypeArray, Required: true, Items: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "Price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+			{Name: "PromotionCode", Type: schema.TypeString},
+		},
+	}
+
+	t.Run("Given the SQLite dialect, it should flatten records and explode arrays", func(t *testing.T) {
+		output, err := SQL(transaction, DialectSQLite)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `CREATE TABLE "transaction" (
+  "id" TEXT PRIMARY KEY,
+  "amount" NUMERIC(6, 2) NOT NULL,
+  "customer_email" TEXT NOT NULL,
+  "promotion_code" TEXT
+);
+
+CREATE TABLE "transaction_items" (
+  "id" INTEGER PRIMARY KEY,
+  "transaction_id" TEXT NOT NULL,
+  "price" REAL NOT NULL,
+  FOREIGN KEY ("transaction_id") REFERENCES "transaction" ("id")
+);
+`
+		if string(output) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Given the Postgres dialect, it should use Postgres types", func(t *testing.T) {
+		s := &schema.Schema{Name: "events", Fields: []*schema.Field{
+			{Name: "at", Type: schema.TypeTimestamp, Required: true},
+			{Name: "tags", Type: schema.TypeArray, Required: true, Items: &schema.Field{Type: schema.TypeString, Required: true}},
+		}}
+		output, err := SQL(s, DialectPostgres)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, expected := range []string{
+			`"id" BIGSERIAL PRIMARY KEY`,
+			`"at" TIMESTAMP NOT NULL`,
+			`CREATE TABLE "events_tags"`,
+			`"events_id" BIGINT NOT NULL`,
+			`"value" TEXT NOT NULL`,
+		} {
+			if !strings.Contains(string(output), expected) {
+				t.Errorf("Expected %q in:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("Given an unknown dialect, it should return an error", func(t *testing.T) {
+		if _, err := SQL(transaction, "oracle"); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given a map, it should declare a table keyed by the map key", func(t *testing.T) {
+		output, err := SQL(&schema.Schema{Name: "order", Fields: []*schema.Field{
+			{Name: "id", Type: schema.TypeString, Required: true},
+			{Name: "items", Type: schema.TypeMap, Required: true, Values: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+		}}, DialectSQLite)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `CREATE TABLE "order_items" (
+  "id" INTEGER PRIMARY KEY,
+  "order_id" TEXT NOT NULL,
+  "key" TEXT NOT NULL,
+  "price" REAL NOT NULL,
+  FOREIGN KEY ("order_id") REFERENCES "order" ("id")
+);`
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected:\n%s\nin:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should declare a table per variant", func(t *testing.T) {
+		output, err := SQL(eventUnion(), DialectSQLite)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `CREATE TABLE "event_created" (
+  "id" INTEGER PRIMARY KEY,
+  "type" TEXT NOT NULL,
+  "total" REAL NOT NULL
+);
+
+CREATE TABLE "event_shipped" (
+  "id" INTEGER PRIMARY KEY,
+  "type" TEXT NOT NULL,
+  "carrier" TEXT
+);`
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected:\n%s\nin:\n%s", expected, output)
+		}
+	})
+}
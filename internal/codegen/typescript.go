@@ -0,0 +1,108 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// tsIdentifier matches property names that need no quoting in TypeScript.
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+type tsWriter struct {
+	interfaces []string
+	names      uniqueNames
+}
+
+// TypeScript renders the schema as exported TypeScript interfaces, one per
+// record. Optional fields are optional properties that may also be null, enums
+// are unions of string literals and discriminated unions become a union type
+// of one interface per variant.
+func TypeScript(s *schema.Schema) ([]byte, error) {
+	w := &tsWriter{names: uniqueNames{}}
+	name := s.Name
+	if name == "" {
+		name = "Record"
+	}
+	if len(s.Variants) == 0 {
+		w.writeInterface(name, s.Fields)
+		return []byte(strings.Join(w.interfaces, "\n")), nil
+	}
+
+	name = w.names.next(pascalCase(name))
+	w.interfaces = append(w.interfaces, "")
+	variants := make([]string, 0, len(s.Variants))
+	for _, variant := range s.Variants {
+		variants = append(variants, w.writeInterface(variant.Value, variant.Fields))
+	}
+	w.interfaces[0] = fmt.Sprintf("export type %s = %s;\n", name, strings.Join(variants, " | "))
+	return []byte(strings.Join(w.interfaces, "\n")), nil
+}
+
+// writeInterface renders an interface and returns its name. The slot of the
+// interface is reserved first so that parents precede their children.
+func (w *tsWriter) writeInterface(name string, fields []*schema.Field) string {
+	name = w.names.next(pascalCase(name))
+	index := len(w.interfaces)
+	w.interfaces = append(w.interfaces, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "export interface %s {\n", name)
+	for _, field := range fields {
+		property := field.Name
+		if !tsIdentifier.MatchString(property) {
+			property = fmt.Sprintf("%q", property)
+		}
+		typ := w.tsType(field, field.Name)
+		if !field.Required && field.Type != schema.TypeNull {
+			fmt.Fprintf(&b, "  %s?: %s | null;\n", property, typ)
+		} else {
+			fmt.Fprintf(&b, "  %s: %s;\n", property, typ)
+		}
+	}
+	b.WriteString("}\n")
+	w.interfaces[index] = b.String()
+	return name
+}
+
+func (w *tsWriter) tsType(field *schema.Field, name string) string {
+	switch field.Type {
+	case schema.TypeNull:
+		return "null"
+	case schema.TypeString:
+		if len(field.Enum) > 0 {
+			literals := make([]string, len(field.Enum))
+			for i, symbol := range field.Enum {
+				literals[i] = fmt.Sprintf("%q", symbol)
+			}
+			return strings.Join(literals, " | ")
+		}
+		return "string"
+	case schema.TypeTimestamp:
+		return "string"
+	case schema.TypeInteger, schema.TypeNumber, schema.TypeDecimal:
+		return "number"
+	case schema.TypeBoolean:
+		return "boolean"
+	case schema.TypeRecord:
+		return w.writeInterface(name, field.Fields)
+	case schema.TypeArray:
+		if field.Items == nil {
+			return "unknown[]"
+		}
+		item := w.tsType(field.Items, singular(name))
+		if strings.Contains(item, " ") {
+			return "Array<" + item + ">"
+		}
+		return item + "[]"
+	case schema.TypeMap:
+		if field.Values == nil {
+			return "Record<string, unknown>"
+		}
+		return "Record<string, " + w.tsType(field.Values, singular(name)) + ">"
+	default:
+		return "unknown"
+	}
+}
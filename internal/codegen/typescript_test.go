@@ -0,0 +1,105 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestTypeScript(t *testing.T) {
+	t.Run("Given a nested schema, it should render one interface per record", func(t *testing.T) {
+		s := &schema.Schema{Name: "order", Fields: []*schema.Field{
+			{Name: "id", Type: schema.TypeString, Required: true},
+			{Name: "items", Type: schema.TypeArray, Required: true, Items: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+			{Name: "placed-at", Type: schema.TypeTimestamp},
+		}}
+
+		output, err := TypeScript(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `export interface Order {
+  id: string;
+  items: Item[];
+  "placed-at"?: string | null;
+}
+
+export interface Item {
+  price: number;
+}
+`
+		if string(output) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should render a union of variant interfaces", func(t *testing.T) {
+		output, err := TypeScript(eventUnion())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `export type Event = Created | Shipped;
+
+export interface Created {
+  type: "created";
+  total: number;
+}
+
+export interface Shipped {
+  type: "shipped";
+  carrier?: string | null;
+}
+`
+		if string(output) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Given a map, it should render a Record type", func(t *testing.T) {
+		output, err := TypeScript(&schema.Schema{Name: "order", Fields: []*schema.Field{
+			{Name: "id", Type: schema.TypeString, Required: true},
+			{Name: "items", Type: schema.TypeMap, Required: true, Values: &schema.Field{
+				Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+					{Name: "price", Type: schema.TypeNumber, Required: true},
+				},
+			}},
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `export interface Order {
+  id: string;
+  items: Record<string, Item>;
+}
+
+export interface Item {
+  price: number;
+}
+`
+		if string(output) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+}
+
+// eventUnion returns a discriminated union whose variants share the type
+// field.
+func eventUnion() *schema.Schema {
+	return &schema.Schema{Name: "event", Discriminator: "type",
+		Fields: []*schema.Field{{Name: "type", Type: schema.TypeString, Required: true, Enum: []string{"created", "shipped"}}},
+		Variants: []*schema.Variant{
+			{Value: "created", Fields: []*schema.Field{
+				{Name: "type", Type: schema.TypeString, Required: true, Enum: []string{"created"}},
+				{Name: "total", Type: schema.TypeNumber, Required: true},
+			}},
+			{Value: "shipped", Fields: []*schema.Field{
+				{Name: "type", Type: schema.TypeString, Required: true, Enum: []string{"shipped"}},
+				{Name: "carrier", Type: schema.TypeString},
+			}},
+		},
+	}
+}
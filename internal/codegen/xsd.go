@@ -0,0 +1,188 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+type xsdWriter struct {
+	b strings.Builder
+}
+
+// XSD renders the schema of XML messages as an XML Schema document. Optional
+// elements get minOccurs="0", repeated elements maxOccurs="unbounded" and
+// fields read from attributes are declared as attributes. The elements of the
+// variants of discriminated unions follow the shared ones in an xs:choice.
+func XSD(s *schema.Schema) ([]byte, error) {
+	name := s.Name
+	if name == "" {
+		name = "Record"
+	}
+	w := &xsdWriter{}
+	w.b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	w.b.WriteString(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" elementFormDefault="qualified">` + "\n")
+	if len(s.Variants) == 0 {
+		w.element(&schema.Field{Name: name, Type: schema.TypeRecord, Required: true, Fields: s.Fields}, 1)
+	} else {
+		choice := make([][]*schema.Field, 0, len(s.Variants))
+		for _, variant := range s.Variants {
+			choice = append(choice, s.VariantFields(variant))
+		}
+		w.line(1, `<xs:element name=%q>`, name)
+		w.complexType(s.Fields, choice, 2)
+		w.line(1, `</xs:element>`)
+	}
+	w.b.WriteString("</xs:schema>\n")
+	return []byte(w.b.String()), nil
+}
+
+func (w *xsdWriter) line(depth int, format string, args ...interface{}) {
+	w.b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteString("\n")
+}
+
+// element declares an element for the field, describing repetition with
+// maxOccurs and optionality with minOccurs.
+func (w *xsdWriter) element(field *schema.Field, depth int) {
+	occurs := ""
+	if !field.Required || field.Type == schema.TypeNull {
+		occurs += ` minOccurs="0"`
+	}
+	content := field
+	if field.Type == schema.TypeArray {
+		occurs += ` maxOccurs="unbounded"`
+		for content.Type == schema.TypeArray && content.Items != nil {
+			content = content.Items
+		}
+	}
+
+	if content.Type == schema.TypeMap {
+		// Elements named after dynamic keys cannot be declared one by one.
+		w.line(depth, `<xs:element name=%q%s>`, field.Name, occurs)
+		w.line(depth+1, `<xs:complexType>`)
+		w.line(depth+2, `<xs:sequence>`)
+		w.line(depth+3, `<xs:any processContents="lax" minOccurs="0" maxOccurs="unbounded"/>`)
+		w.line(depth+2, `</xs:sequence>`)
+		w.line(depth+1, `</xs:complexType>`)
+		w.line(depth, `</xs:element>`)
+		return
+	}
+	if content.Type != schema.TypeRecord {
+		w.line(depth, `<xs:element name=%q%s%s/>`, field.Name, xsdTypeAttr(content), occurs)
+		return
+	}
+	w.line(depth, `<xs:element name=%q%s>`, field.Name, occurs)
+	w.complexType(content.Fields, nil, depth+1)
+	w.line(depth, `</xs:element>`)
+}
+
+// complexType declares the content of a record, followed by a choice between
+// the elements of each group in choice. Records holding text next to
+// attributes become simple content extended with those attributes; the
+// attributes of the choice are optional.
+func (w *xsdWriter) complexType(fields []*schema.Field, choice [][]*schema.Field, depth int) {
+	var elements, attributes []*schema.Field
+	var text *schema.Field
+	for _, field := range fields {
+		switch {
+		case field.Attribute:
+			attributes = append(attributes, field)
+		case field.Name == schema.TextField:
+			text = field
+		default:
+			elements = append(elements, field)
+		}
+	}
+	groups := make([][]*schema.Field, len(choice))
+	for i, group := range choice {
+		for _, field := range group {
+			if field.Attribute && !hasField(attributes, field.Name) {
+				optional := *field
+				optional.Required = false
+				attributes = append(attributes, &optional)
+			} else if !field.Attribute && field.Name != schema.TextField {
+				groups[i] = append(groups[i], field)
+			}
+		}
+	}
+
+	if len(elements) == 0 && len(attributes) == 0 && text == nil && len(groups) == 0 {
+		w.line(depth, `<xs:complexType/>`)
+		return
+	}
+	if text != nil && len(elements) == 0 && len(groups) == 0 {
+		w.line(depth, `<xs:complexType>`)
+		w.line(depth+1, `<xs:simpleContent>`)
+		w.line(depth+2, `<xs:extension base=%q>`, xsdType(text.Type))
+		w.attributes(attributes, depth+3)
+		w.line(depth+2, `</xs:extension>`)
+		w.line(depth+1, `</xs:simpleContent>`)
+		w.line(depth, `</xs:complexType>`)
+		return
+	}
+
+	if text != nil {
+		w.line(depth, `<xs:complexType mixed="true">`)
+	} else {
+		w.line(depth, `<xs:complexType>`)
+	}
+	if len(elements) > 0 || len(groups) > 0 {
+		w.line(depth+1, `<xs:sequence>`)
+		for _, field := range elements {
+			w.element(field, depth+2)
+		}
+		if len(groups) > 0 {
+			w.line(depth+2, `<xs:choice>`)
+			for _, group := range groups {
+				w.line(depth+3, `<xs:sequence>`)
+				for _, field := range group {
+					w.element(field, depth+4)
+				}
+				w.line(depth+3, `</xs:sequence>`)
+			}
+			w.line(depth+2, `</xs:choice>`)
+		}
+		w.line(depth+1, `</xs:sequence>`)
+	}
+	w.attributes(attributes, depth+1)
+	w.line(depth, `</xs:complexType>`)
+}
+
+func (w *xsdWriter) attributes(attributes []*schema.Field, depth int) {
+	for _, field := range attributes {
+		use := ""
+		if field.Required {
+			use = ` use="required"`
+		}
+		w.line(depth, `<xs:attribute name=%q type=%q%s/>`, field.Name, xsdType(field.Type), use)
+	}
+}
+
+// xsdTypeAttr returns the type attribute of a simple element, or nothing for
+// elements whose content is unknown.
+func xsdTypeAttr(field *schema.Field) string {
+	if field.Type == schema.TypeAny {
+		return ""
+	}
+	return fmt.Sprintf(` type=%q`, xsdType(field.Type))
+}
+
+func xsdType(t schema.Type) string {
+	switch t {
+	case schema.TypeInteger:
+		return "xs:long"
+	case schema.TypeNumber:
+		return "xs:double"
+	case schema.TypeDecimal:
+		return "xs:decimal"
+	case schema.TypeBoolean:
+		return "xs:boolean"
+	case schema.TypeTimestamp:
+		return "xs:dateTime"
+	default:
+		return "xs:string"
+	}
+}
@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestXSD(t *testing.T) {
+	t.Run("Given an XML schema, it should derive occurrences, types and attributes", func(t *testing.T) {
+		s := &schema.Schema{Name: "Transaction", Fields: []*schema.Field{
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Amount", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+				{Name: "currency", Type: schema.TypeString, Required: true, Attribute: true},
+				{Name: schema.TextField, Type: schema.TypeDecimal, Required: true},
+			}},
+			{Name: "Items", Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+				{Name: "Item", Type: schema.TypeArray, Required: true, Items: &schema.Field{
+					Type: schema.TypeRecord, Required: true, Fields: []*schema.Field{
+						{Name: "Quantity", Type: schema.TypeInteger, Required: true},
+					},
+				}},
+			}},
+			{Name: "PromotionCode", Type: schema.TypeString},
+			{Name: "version", Type: schema.TypeInteger, Attribute: true},
+		}}
+
+		output, err := XSD(s)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" elementFormDefault="qualified">
+  <xs:element name="Transaction">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="ID" type="xs:string"/>
+        <xs:element name="Amount">
+          <xs:complexType>
+            <xs:simpleContent>
+              <xs:extension base="xs:decimal">
+                <xs:attribute name="currency" type="xs:string" use="required"/>
+              </xs:extension>
+            </xs:simpleContent>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="Items">
+          <xs:complexType>
+            <xs:sequence>
+              <xs:element name="Item" maxOccurs="unbounded">
+                <xs:complexType>
+                  <xs:sequence>
+                    <xs:element name="Quantity" type="xs:long"/>
+                  </xs:sequence>
+                </xs:complexType>
+              </xs:element>
+            </xs:sequence>
+          </xs:complexType>
+        </xs:element>
+        <xs:element name="PromotionCode" type="xs:string" minOccurs="0"/>
+      </xs:sequence>
+      <xs:attribute name="version" type="xs:long"/>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>
+`
+		if string(output) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+		}
+		if strings.Count(string(output), "<xs:schema") != 1 {
+			t.Errorf("Expected a single schema element")
+		}
+	})
+
+	t.Run("Given a discriminated union, it should declare a choice between the variant elements", func(t *testing.T) {
+		output, err := XSD(eventUnion())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `  <xs:element name="event">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="type" type="xs:string"/>
+        <xs:choice>
+          <xs:sequence>
+            <xs:element name="total" type="xs:double"/>
+          </xs:sequence>
+          <xs:sequence>
+            <xs:element name="carrier" type="xs:string" minOccurs="0"/>
+          </xs:sequence>
+        </xs:choice>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>`
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected:\n%s\nin:\n%s", expected, output)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CatalogEntry is a distinct schema shape together with how often and on
+// which topics it was seen.
+type CatalogEntry struct {
+	Fingerprint string         `json:"fingerprint"`
+	Schema      interface{}    `json:"schema"`
+	Count       int            `json:"count"`
+	Topics      map[string]int `json:"topics"`
+	FirstSeen   time.Time      `json:"first_seen"`
+	LastSeen    time.Time      `json:"last_seen"`
+}
+
+// SchemaCatalog stores every distinct schema produced by the consumer once,
+// keyed by its fingerprint.
+type SchemaCatalog struct {
+	mu      sync.RWMutex
+	entries map[string]*CatalogEntry
+}
+
+// DefaultCatalog is the catalog a Consumer records mapped schemas in.
+var DefaultCatalog = NewSchemaCatalog()
+
+// NewSchemaCatalog returns an empty catalog.
+func NewSchemaCatalog() *SchemaCatalog {
+	return &SchemaCatalog{entries: make(map[string]*CatalogEntry)}
+}
+
+// CanonicalSchema returns the normalized JSON form of a schema such as the
+// maps produced by MapSchema. Object keys are sorted at every level.
+func CanonicalSchema(schema interface{}) ([]byte, error) {
+	return json.Marshal(schema)
+}
+
+// SchemaFingerprint returns the hex encoded SHA-256 of the canonical form.
+func SchemaFingerprint(schema interface{}) (string, error) {
+	canonical, err := CanonicalSchema(schema)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Add counts an occurrence of the schema on a topic. It returns the schema
+// fingerprint and whether the shape had not been seen before.
+func (c *SchemaCatalog) Add(topic string, schema interface{}) (string, bool, error) {
+	fingerprint, err := SchemaFingerprint(schema)
+	if err != nil {
+		return "", false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	entry, found := c.entries[fingerprint]
+	if !found {
+		entry = &CatalogEntry{Fingerprint: fingerprint, Schema: schema, Topics: map[string]int{}, FirstSeen: now}
+		c.entries[fingerprint] = entry
+	}
+	entry.Count++
+	entry.Topics[topic]++
+	entry.LastSeen = now
+	return fingerprint, !found, nil
+}
+
+// Get returns a copy of the entry with the given fingerprint.
+func (c *SchemaCatalog) Get(fingerprint string) (CatalogEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, found := c.entries[fingerprint]
+	if !found {
+		return CatalogEntry{}, false
+	}
+	return entry.copy(), true
+}
+
+// Entries returns copies of all entries, most frequent first.
+func (c *SchemaCatalog) Entries() []CatalogEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries := make([]CatalogEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry.copy())
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Fingerprint < entries[j].Fingerprint
+	})
+	return entries
+}
+
+func (e *CatalogEntry) copy() CatalogEntry {
+	c := *e
+	c.Topics = make(map[string]int, len(e.Topics))
+	for topic, count := range e.Topics {
+		c.Topics[topic] = count
+	}
+	return c
+}
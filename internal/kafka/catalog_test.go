@@ -0,0 +1,38 @@
+package kafka
+
+import "testing"
+
+func TestSchemaCatalog(t *testing.T) {
+	t.Run("Given equal schemas built in a different order, it should fingerprint them alike", func(t *testing.T) {
+		a := map[string]interface{}{"id": "string", "user": map[string]interface{}{"age": "int", "name": "string"}}
+		b := map[string]interface{}{"user": map[string]interface{}{"name": "string", "age": "int"}, "id": "string"}
+
+		fa, err := SchemaFingerprint(a)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		fb, _ := SchemaFingerprint(b)
+		if fa != fb || len(fa) != 64 {
+			t.Errorf("Expected equal SHA-256 fingerprints, got %s and %s", fa, fb)
+		}
+	})
+
+	t.Run("Given a repeated schema, it should count it and report it as known", func(t *testing.T) {
+		catalog := NewSchemaCatalog()
+		schema := MapSchema(map[string]interface{}{"id": "1"})
+
+		fingerprint, isNew, _ := catalog.Add("orders", schema)
+		if !isNew {
+			t.Error("Expected the first schema to be new")
+		}
+		_, isNew, _ = catalog.Add("refunds", schema)
+		if isNew {
+			t.Error("Expected the repeated schema to be known")
+		}
+
+		entry, found := catalog.Get(fingerprint)
+		if !found || entry.Count != 2 || entry.Topics["orders"] != 1 || entry.Topics["refunds"] != 1 {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	})
+}
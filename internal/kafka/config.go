@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"slices"
 	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
 )
 
 // MapSchema dynamically maps the schema of the input map
@@ -24,12 +27,48 @@ func MapSchema(data map[string]interface{}) map[string]interface{} {
 
 		// If the value is a nested structure, recursively map its schema
 		if reflect.TypeOf(value).Kind() == reflect.Map {
-			schema[key] = MapSchema(value.(map[string]interface{}))
+			schema[key] = mapNested(value.(map[string]interface{}))
 		}
 	}
 	return schema
 }
 
+// mapNested maps a nested structure. Objects keyed by IDs share a single
+// description of their values under schema.MapValuesKey, so that every new
+// key does not add a field.
+func mapNested(data map[string]interface{}) map[string]interface{} {
+	if schema.IsDynamicMap(data) {
+		return map[string]interface{}{schema.MapValuesKey: mapValuesSchema(data)}
+	}
+	return MapSchema(data)
+}
+
+// mapValuesSchema maps the values of an object with dynamic keys into a
+// single description, combining the keys of nested structures.
+func mapValuesSchema(data map[string]interface{}) interface{} {
+	var values interface{}
+	for _, value := range MapSchema(data) {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			if values == nil || values == "<nil>" {
+				values = value
+			}
+			continue
+		}
+		combined, ok := values.(map[string]interface{})
+		if !ok {
+			combined = make(map[string]interface{})
+			values = combined
+		}
+		for k, v := range nested {
+			if _, found := combined[k]; !found || combined[k] == "<nil>" {
+				combined[k] = v
+			}
+		}
+	}
+	return values
+}
+
 // XmlToMap xmlToMap dynamically converts XML into a map[string]interface{}
 func XmlToMap(reader io.Reader) (map[string]interface{}, error) {
 	decoder := xml.NewDecoder(reader)
@@ -49,7 +88,7 @@ func XmlToMap(reader io.Reader) (map[string]interface{}, error) {
 		case xml.StartElement:
 			element := make(map[string]interface{})
 			for _, attr := range tok.Attr {
-				element[attr.Name.Local] = attr.Value
+				element[schema.AttributePrefix+attr.Name.Local] = attr.Value
 			}
 
 			stack = append(stack, current)
@@ -82,7 +121,7 @@ func XmlToMap(reader io.Reader) (map[string]interface{}, error) {
 				if current == nil {
 					current = make(map[string]interface{})
 				}
-				current["#text"] = content
+				current[schema.TextField] = content
 			}
 		}
 	}
@@ -112,3 +151,83 @@ func JSONToMap(data []byte) (map[string]interface{}, error) {
 	}
 	return result, nil
 }
+
+// ParseDocument parses a message like ParseMessage, but describes XML
+// documents by the content of their root element. It also returns the name
+// of the root element, which is empty for JSON messages.
+func ParseDocument(data []byte) (map[string]interface{}, string, error) {
+	return DocumentOf(data, nil)
+}
+
+// DocumentOf returns what ParseDocument returns for a message that was
+// already parsed by ParseMessage, so that the handlers of the consumer do not
+// parse it again. The message is only parsed when parsed is nil.
+func DocumentOf(data []byte, parsed map[string]interface{}) (map[string]interface{}, string, error) {
+	if parsed == nil {
+		var err error
+		if parsed, err = ParseMessage(data); err != nil {
+			return nil, "", err
+		}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(data)), "<") {
+		return parsed, "", nil
+	}
+	for root, element := range parsed {
+		if fields, ok := element.(map[string]interface{}); ok {
+			return fields, root, nil
+		}
+	}
+	return parsed, "", nil
+}
+
+// InferSchema parses a message and infers its schema. XML documents are
+// described by their root element, which also names the schema when no name
+// is given.
+func InferSchema(name string, data []byte) (*schema.Schema, error) {
+	document, root, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return inferDocument(name, document, root, data), nil
+}
+
+// inferDocument infers the schema of a document returned by ParseDocument,
+// restoring the element order of XML documents from the raw message.
+func inferDocument(name string, document map[string]interface{}, root string, data []byte) *schema.Schema {
+	if root == "" {
+		return schema.Infer(name, document)
+	}
+	if name == "" {
+		name = root
+	}
+	inferred := schema.Infer(name, document)
+	inferred.Format = schema.FormatXML
+	inferred.OrderFields(xmlElementOrder(data))
+	return inferred
+}
+
+// xmlElementOrder lists the names of the child elements of every element in
+// document order, keyed by the dot separated path below the root element.
+func xmlElementOrder(data []byte) map[string][]string {
+	order := map[string][]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var path []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return order
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			if len(path) > 0 {
+				parent := strings.Join(path[1:], ".")
+				if !slices.Contains(order[parent], tok.Name.Local) {
+					order[parent] = append(order[parent], tok.Name.Local)
+				}
+			}
+			path = append(path, tok.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
 )
 
 func TestMapSchema(t *testing.T) {
@@ -50,6 +52,32 @@ func TestMapSchema(t *testing.T) {
 	})
 }
 
+func TestMapSchemaDynamicKeys(t *testing.T) {
+	t.Run("Given objects keyed by IDs, it should describe their values once", func(t *testing.T) {
+		input := map[string]interface{}{
+			"items": map[string]interface{}{
+				"sku-1": map[string]interface{}{"price": 1.5, "name": "Ink"},
+				"sku-2": map[string]interface{}{"price": 2.5, "name": "Pen"},
+				"sku-3": map[string]interface{}{"price": 3.5, "name": "Pad"},
+			},
+		}
+		expected := map[string]interface{}{
+			"items": map[string]interface{}{
+				"*": map[string]interface{}{"price": "float64", "name": "string"},
+			},
+		}
+		result := MapSchema(input)
+
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		converted := schema.FromMapSchema("Order", result)
+		if field := converted.Field("items"); field.Type != schema.TypeMap || field.Values.Type != schema.TypeRecord {
+			t.Errorf("Expected a map of records, got %+v", field)
+		}
+	})
+}
+
 func TestJSONToMap(t *testing.T) {
 	t.Run("Given valid JSON, it should parse into a map", func(t *testing.T) {
 		data := []byte(`{"name": "Alice", "age": 25}`)
@@ -147,3 +175,100 @@ func TestParseMessage(t *testing.T) {
 		}
 	})
 }
+
+func TestDocumentOf(t *testing.T) {
+	t.Run("Given a parsed XML message, it should return its root without parsing it again", func(t *testing.T) {
+		data := []byte(`<User><Name>Alice</Name></User>`)
+		parsed, err := ParseMessage(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		document, root, err := DocumentOf(data, parsed)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if root != "User" {
+			t.Errorf("Expected the root User, got %q", root)
+		}
+		document["Extra"] = true
+		if _, shared := parsed["User"].(map[string]interface{})["Extra"]; !shared {
+			t.Errorf("Expected the document to be taken from the parsed message")
+		}
+	})
+
+	t.Run("Given no parsed message, it should parse it like ParseDocument", func(t *testing.T) {
+		data := []byte(`{"name": "Alice"}`)
+		document, root, err := DocumentOf(data, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := map[string]interface{}{"name": "Alice"}
+		if root != "" || !reflect.DeepEqual(document, expected) {
+			t.Errorf("Expected %v without a root, got %v and %q", expected, document, root)
+		}
+	})
+}
+
+func TestInferSchema(t *testing.T) {
+	t.Run("Given an XML message, it should describe the root element", func(t *testing.T) {
+		data := []byte(`<User><Name>Alice</Name><Age>25</Age></User>`)
+		result, err := InferSchema("", data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if result.Name != "User" || result.Format != schema.FormatXML {
+			t.Errorf("Expected the XML schema User, got %s in format %q", result.Name, result.Format)
+		}
+		if field := result.Field("Age"); field == nil || field.Type != schema.TypeInteger {
+			t.Errorf("Expected Age to be an integer, got %+v", field)
+		}
+	})
+
+	t.Run("Given a JSON message, it should keep the given name", func(t *testing.T) {
+		result, err := InferSchema("users", []byte(`{"name": "Alice"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if result.Name != "users" || result.Format != "" || result.Field("name") == nil {
+			t.Errorf("Expected users schema with a name field, got %+v", result)
+		}
+	})
+}
+
+func TestXmlAttributes(t *testing.T) {
+	t.Run("Given XML attributes, it should keep them apart from elements", func(t *testing.T) {
+		data := []byte(`<Amount currency="USD">9.99</Amount>`)
+		expected := map[string]interface{}{
+			"Amount": map[string]interface{}{"@currency": "USD", "#text": "9.99"},
+		}
+		result, err := XmlToMap(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("Given an XML message, it should infer fields in document order", func(t *testing.T) {
+		data := []byte(`<T><Zeta>1</Zeta><Alpha a="x">2</Alpha><Mid>3</Mid></T>`)
+		result, err := InferSchema("", data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var names []string
+		for _, field := range result.Fields {
+			names = append(names, field.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"Zeta", "Alpha", "Mid"}) {
+			t.Errorf("Expected document order, got %v", names)
+		}
+		if field := result.Lookup("Alpha.a"); field == nil || !field.Attribute {
+			t.Errorf("Expected Alpha.a to be an attribute, got %+v", field)
+		}
+	})
+}
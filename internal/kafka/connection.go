@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// Connection is where the brokers of a cluster are and how to connect to
+// them. SecurityProtocol is PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL and
+// SASLMechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; SSLCaLocation names
+// a PEM file of the certificates trusted instead of the system ones.
+type Connection struct {
+	Brokers          []string
+	SecurityProtocol string
+	SASLMechanism    string
+	SASLUsername     string
+	SASLPassword     string
+	SSLCaLocation    string
+}
+
+// NewReader returns a reader of topic committing its offsets for groupID.
+func (c Connection) NewReader(topic, groupID string) (*kafka.Reader, error) {
+	mechanism, tlsConfig, err := c.security()
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers: c.Brokers,
+		Topic:   topic,
+		GroupID: groupID,
+		Dialer:  &kafka.Dialer{DualStack: true, TLS: tlsConfig, SASLMechanism: mechanism},
+	}), nil
+}
+
+// NewWriter returns a writer sending messages to their topic, partitioned by
+// key.
+func (c Connection) NewWriter() (*LocalKafkaWriter, error) {
+	mechanism, tlsConfig, err := c.security()
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKafkaWriter{
+		Writer: &kafka.Writer{
+			Addr:      kafka.TCP(c.Brokers...),
+			Balancer:  &kafka.Hash{},
+			Transport: &kafka.Transport{TLS: tlsConfig, SASL: mechanism},
+		},
+	}, nil
+}
+
+// security returns the SASL mechanism and TLS config of the connection, each
+// nil when it is not used.
+func (c Connection) security() (sasl.Mechanism, *tls.Config, error) {
+	var useSASL, useTLS bool
+	switch strings.ToUpper(c.SecurityProtocol) {
+	case "", "PLAINTEXT":
+	case "SSL":
+		useTLS = true
+	case "SASL_PLAINTEXT":
+		useSASL = true
+	case "SASL_SSL":
+		useSASL, useTLS = true, true
+	default:
+		return nil, nil, fmt.Errorf("unknown security protocol %q", c.SecurityProtocol)
+	}
+
+	var mechanism sasl.Mechanism
+	if useSASL || c.SASLMechanism != "" {
+		var err error
+		switch strings.ToUpper(c.SASLMechanism) {
+		case "PLAIN":
+			mechanism = plain.Mechanism{Username: c.SASLUsername, Password: c.SASLPassword}
+		case "SCRAM-SHA-256":
+			mechanism, err = scram.Mechanism(scram.SHA256, c.SASLUsername, c.SASLPassword)
+		case "SCRAM-SHA-512":
+			mechanism, err = scram.Mechanism(scram.SHA512, c.SASLUsername, c.SASLPassword)
+		default:
+			return nil, nil, fmt.Errorf("unknown SASL mechanism %q", c.SASLMechanism)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating SASL mechanism: %v", err)
+		}
+	}
+
+	if !useTLS {
+		return mechanism, nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.SSLCaLocation != "" {
+		data, err := os.ReadFile(c.SSLCaLocation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CA certificates: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, nil, fmt.Errorf("no CA certificates in %s", c.SSLCaLocation)
+		}
+	}
+	return mechanism, tlsConfig, nil
+}
@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConnectionSecurity(t *testing.T) {
+	t.Run("Given no security protocol, it should connect in plain text", func(t *testing.T) {
+		mechanism, tlsConfig, err := Connection{Brokers: []string{"localhost:9092"}}.security()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if mechanism != nil || tlsConfig != nil {
+			t.Errorf("Expected neither SASL nor TLS, got %v and %v", mechanism, tlsConfig)
+		}
+	})
+
+	t.Run("Given SASL_SSL with SCRAM, it should use both", func(t *testing.T) {
+		connection := Connection{SecurityProtocol: "SASL_SSL", SASLMechanism: "SCRAM-SHA-512", SASLUsername: "thoth", SASLPassword: "secret"}
+		mechanism, tlsConfig, err := connection.security()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if mechanism == nil || mechanism.Name() != "SCRAM-SHA-512" || tlsConfig == nil {
+			t.Errorf("Expected SCRAM-SHA-512 over TLS, got %v and %v", mechanism, tlsConfig)
+		}
+	})
+
+	t.Run("Given unknown settings, it should explain what is wrong", func(t *testing.T) {
+		invalid := map[string]Connection{
+			"security protocol":      {SecurityProtocol: "TLS"},
+			"SASL mechanism":         {SecurityProtocol: "SASL_PLAINTEXT"},
+			"CA certificates":        {SecurityProtocol: "SSL", SSLCaLocation: "/does/not/exist.pem"},
+			"unknown SASL mechanism": {SASLMechanism: "GSSAPI"},
+		}
+		for expected, connection := range invalid {
+			if _, _, err := connection.security(); err == nil || !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected %+v to fail with %q, got %v", connection, expected, err)
+			}
+		}
+	})
+}
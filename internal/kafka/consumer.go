@@ -5,41 +5,206 @@ import (
 	"fmt"
 	"github.com/segmentio/kafka-go"
 	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 )
 
-// StartKafkaConsumer reads messages and maps schema using the provided functions.
-func StartKafkaConsumer(
+// MessageHandler is called with every consumed message that parsed
+// successfully and the data parsed from it, which handlers pass to DocumentOf
+// rather than parsing the message again. Handlers must not change the data,
+// which every handler shares; it may be nil when a handler is called
+// directly.
+type MessageHandler func(message kafka.Message, data map[string]interface{})
+
+// DefaultConsumerGroup is the group the consumer commits its offsets for
+// unless configured otherwise.
+const DefaultConsumerGroup = "transaction-consumer-group"
+
+// ConnectReader returns a reader of topic committing its offsets for groupID
+// on the cluster of connection, as used by a running consumer.
+type ConnectReader func(topic, groupID string, connection Connection) (MessageReader, error)
+
+// Consumer reads the messages of a set of topics, records their mapped schema
+// in DefaultCatalog and passes each parsed message to its handlers in order.
+// Messages are committed once handled. The consumer only runs while it has
+// topics.
+type Consumer struct {
+	parse     func([]byte) (map[string]interface{}, error)
+	mapSchema func(map[string]interface{}) map[string]interface{}
+	handlers  []MessageHandler
+
+	mu         sync.Mutex
+	topics     []string
+	groupID    string
+	connection Connection
+	changed    chan struct{}
+}
+
+// NewConsumer returns a consumer parsing and mapping messages with the given
+// functions, without topics or connection until they are set.
+func NewConsumer(
 	parseMessageFunc func([]byte) (map[string]interface{}, error),
-	mapSchemaFunc func(map[string]interface{}) map[string]string,
-) {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        []string{"localhost:9092"},
-		Topic:          "transactions",
-		GroupID:        "transaction-consumer-group",
-		MinBytes:       10e3,        // 10KB
-		MaxBytes:       10e6,        // 10MB
-		CommitInterval: time.Second, // Commit offsets periodically
-	})
-	defer reader.Close()
-
-	fmt.Println("Kafka consumer started...")
+	mapSchemaFunc func(map[string]interface{}) map[string]interface{},
+	handlers ...MessageHandler,
+) *Consumer {
+	return &Consumer{
+		parse:     parseMessageFunc,
+		mapSchema: mapSchemaFunc,
+		handlers:  handlers,
+		groupID:   DefaultConsumerGroup,
+		changed:   make(chan struct{}),
+	}
+}
 
-	for {
-		message, err := reader.ReadMessage(context.Background())
-		if err != nil {
-			log.Fatalf("Error reading message from Kafka: %v", err)
+// SetTopics changes the topics messages are read from. Empty and repeated
+// topics are ignored.
+func (c *Consumer) SetTopics(topics ...string) {
+	var unique []string
+	for _, topic := range topics {
+		if topic != "" && !slices.Contains(unique, topic) {
+			unique = append(unique, topic)
 		}
+	}
+	sort.Strings(unique)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.Equal(unique, c.topics) {
+		return
+	}
+	c.topics = unique
+	c.notify()
+}
+
+// Topics returns the topics messages are read from, sorted.
+func (c *Consumer) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.topics...)
+}
 
-		data, err := parseMessageFunc(message.Value)
+// SetConnection changes the cluster messages are read from and the group
+// their offsets are committed for, DefaultConsumerGroup when groupID is
+// empty.
+func (c *Consumer) SetConnection(connection Connection, groupID string) {
+	if groupID == "" {
+		groupID = DefaultConsumerGroup
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if groupID == c.groupID && reflect.DeepEqual(connection, c.connection) {
+		return
+	}
+	c.connection, c.groupID = connection, groupID
+	c.notify()
+}
+
+// notify wakes Run up after a change; the caller holds the lock.
+func (c *Consumer) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Run reads every topic with a reader made by connect until ctx is done.
+// Whenever the topics or the connection change the current readers are
+// closed and new ones are made for the new topics.
+func (c *Consumer) Run(ctx context.Context, connect ConnectReader) {
+	for ctx.Err() == nil {
+		c.mu.Lock()
+		topics, groupID, connection, changed := c.topics, c.groupID, c.connection, c.changed
+		c.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for _, topic := range topics {
+			reader, err := connect(topic, groupID, connection)
+			if err != nil {
+				log.Printf("Failed to connect the consumer of %s: %v", topic, err)
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.consume(ctx, reader, changed)
+			}()
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		}
+		wg.Wait()
+	}
+}
+
+// consume handles the messages of reader until ctx is done or changed is
+// closed.
+func (c *Consumer) consume(ctx context.Context, reader MessageReader, changed <-chan struct{}) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-readCtx.Done():
+		case <-changed:
+			cancel()
+		}
+	}()
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Failed to close consumer reader: %v", err)
+		}
+	}()
+
+	for {
+		message, err := reader.FetchMessage(readCtx)
 		if err != nil {
-			log.Printf("Failed to parse message: %v", err)
+			if readCtx.Err() != nil {
+				return
+			}
+			log.Printf("Error reading message from Kafka: %v", err)
+			select {
+			case <-readCtx.Done():
+				return
+			case <-time.After(time.Second):
+			}
 			continue
 		}
+		handleMessage(message, c.parse, c.mapSchema, DefaultCatalog, c.handlers)
+		if err := reader.CommitMessages(readCtx, message); err != nil && readCtx.Err() == nil {
+			log.Printf("Failed to commit consumed message at %s[%d]@%d: %v", message.Topic, message.Partition, message.Offset, err)
+		}
+	}
+}
+
+// handleMessage parses a consumed message and records its mapped schema in the
+// catalog. The schema is only printed the first time its shape is seen.
+func handleMessage(
+	message kafka.Message,
+	parseMessageFunc func([]byte) (map[string]interface{}, error),
+	mapSchemaFunc func(map[string]interface{}) map[string]interface{},
+	catalog *SchemaCatalog,
+	handlers []MessageHandler,
+) {
+	data, err := parseMessageFunc(message.Value)
+	if err != nil {
+		log.Printf("Failed to parse message: %v", err)
+		return
+	}
 
-		schema := mapSchemaFunc(data)
+	schema := mapSchemaFunc(data)
+	for _, handle := range handlers {
+		handle(message, data)
+	}
 
-		fmt.Printf("Received Data: %+v\n", data)
-		fmt.Printf("Mapped Schema: %+v\n", schema)
+	fmt.Printf("Received Data: %+v\n", data)
+
+	fingerprint, isNew, err := catalog.Add(message.Topic, schema)
+	if err != nil {
+		log.Printf("Failed to fingerprint schema: %v", err)
+		return
+	}
+	if isNew {
+		fmt.Printf("Mapped Schema %s: %+v\n", fingerprint, schema)
 	}
 }
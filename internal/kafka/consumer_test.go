@@ -1,9 +1,14 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,3 +63,117 @@ func TestKafkaConsumer(t *testing.T) {
 	// Close the channel after tests are done
 	close(messageChan)
 }
+
+func TestHandleMessage(t *testing.T) {
+	mapSchema := func(data map[string]interface{}) map[string]interface{} {
+		schema := map[string]interface{}{}
+		for key := range data {
+			schema[key] = "string"
+		}
+		return schema
+	}
+
+	t.Run("Given messages of the same shape, it should store the schema once", func(t *testing.T) {
+		catalog := NewSchemaCatalog()
+		var handled int
+		handler := func(kafka.Message, map[string]interface{}) { handled++ }
+
+		for _, value := range []string{`{"a": "1"}`, `{"a": "2"}`, `{"b": "3"}`} {
+			message := kafka.Message{Topic: "t", Value: []byte(value)}
+			handleMessage(message, JSONToMap, mapSchema, catalog, []MessageHandler{handler})
+		}
+
+		entries := catalog.Entries()
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 distinct schemas, got %d", len(entries))
+		}
+		if entries[0].Count != 2 || entries[0].Topics["t"] != 2 {
+			t.Errorf("Expected the first shape to be counted twice, got %+v", entries[0])
+		}
+		if handled != 3 {
+			t.Errorf("Expected 3 handled messages, got %d", handled)
+		}
+	})
+
+	t.Run("Given MapSchema, it should tell XML and JSON shapes apart", func(t *testing.T) {
+		catalog := NewSchemaCatalog()
+		for _, value := range []string{`{"a": 1}`, `{"a": 2}`, `{"a": "x"}`, `<T><a>1</a></T>`} {
+			handleMessage(kafka.Message{Topic: "t", Value: []byte(value)}, ParseMessage, MapSchema, catalog, nil)
+		}
+		if entries := catalog.Entries(); len(entries) != 3 {
+			t.Errorf("Expected 3 distinct schemas, got %+v", entries)
+		}
+	})
+
+	t.Run("Given an unparseable message, it should skip the handlers", func(t *testing.T) {
+		catalog := NewSchemaCatalog()
+		handler := func(kafka.Message, map[string]interface{}) { t.Error("Unexpected handler call") }
+
+		handleMessage(kafka.Message{Value: []byte("error")}, MockParseMessage, mapSchema, catalog, []MessageHandler{handler})
+	})
+}
+
+func TestConsumer(t *testing.T) {
+	t.Run("Given changing topics and brokers, it should read the new topics from the new brokers", func(t *testing.T) {
+		var mu sync.Mutex
+		var handled []string
+		var connected []string
+		consumer := NewConsumer(ParseMessage, MapSchema, func(message kafka.Message, data map[string]interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, message.Topic)
+		})
+		readers := map[string]*queueReader{
+			"orders":   {messages: make(chan kafka.Message, 1)},
+			"payments": {messages: make(chan kafka.Message, 1)},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			consumer.Run(ctx, func(topic, groupID string, connection Connection) (MessageReader, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				connected = append(connected, topic+"@"+connection.Brokers[0]+"/"+groupID)
+				return readers[topic], nil
+			})
+		}()
+		count := func(n int) func() bool {
+			return func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(handled) >= n
+			}
+		}
+
+		consumer.SetConnection(Connection{Brokers: []string{"broker-1:9092"}}, "")
+		consumer.SetTopics("orders", "orders", "")
+		readers["orders"].messages <- kafka.Message{Topic: "orders", Value: []byte(`{"id": "a"}`)}
+		waitFor(t, "one handled message", count(1))
+
+		consumer.SetConnection(Connection{Brokers: []string{"broker-2:9092"}}, "thoth")
+		consumer.SetTopics("payments", "orders")
+		readers["payments"].messages <- kafka.Message{Topic: "payments", Value: []byte(`{"id": "b"}`)}
+		waitFor(t, "two handled messages", count(2))
+		cancel()
+		<-done
+
+		if topics := consumer.Topics(); !reflect.DeepEqual(topics, []string{"orders", "payments"}) {
+			t.Errorf("Expected the sorted topics, got %v", topics)
+		}
+		expected := []string{
+			"orders@broker-1:9092/" + DefaultConsumerGroup,
+			"orders@broker-2:9092/thoth",
+			"payments@broker-2:9092/thoth",
+		}
+		// Each change reconnects, so the readers of orders may be made again.
+		sort.Strings(connected)
+		connected = slices.Compact(connected)
+		if !reflect.DeepEqual(connected, expected) {
+			t.Errorf("Expected connections %v, got %v", expected, connected)
+		}
+		if len(readers["orders"].commits()) != 1 || len(readers["payments"].commits()) != 1 {
+			t.Errorf("Expected each message to be committed once handled")
+		}
+	})
+}
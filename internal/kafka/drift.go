@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// DefaultDriftTopic is the topic drift events are published to unless
+// configured otherwise.
+const DefaultDriftTopic = "schema-drift"
+
+// DriftEvent describes a change of a topic's schema first seen in a message.
+type DriftEvent struct {
+	Topic      string            `json:"topic"`
+	Partition  int               `json:"partition"`
+	Offset     int64             `json:"first_seen_offset"`
+	DetectedAt time.Time         `json:"detected_at"`
+	Change     schema.ChangeKind `json:"change"`
+	Path       string            `json:"path"`
+	OldType    schema.Type       `json:"old_type,omitempty"`
+	NewType    schema.Type       `json:"new_type,omitempty"`
+	Sample     string            `json:"sample"`
+}
+
+// DriftDetector compares the schema of every consumed message with the schema
+// known for its topic and publishes a DriftEvent for each field that was added,
+// removed or changed type. The first message of a topic establishes its known
+// schema, and every reported change is folded into it so that it is only
+// published once.
+type DriftDetector struct {
+	mu     sync.Mutex
+	writer KafkaWriter
+	topic  string
+	known  map[string]*schema.Schema
+}
+
+// NewDriftDetector returns a detector publishing to driftTopic with writer,
+// which may be nil until SetWriter is called.
+func NewDriftDetector(writer KafkaWriter, driftTopic string) *DriftDetector {
+	return &DriftDetector{writer: writer, topic: driftTopic, known: make(map[string]*schema.Schema)}
+}
+
+// Close closes the writer of the detector.
+func (d *DriftDetector) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.writer == nil {
+		return nil
+	}
+	return d.writer.Close()
+}
+
+// SetWriter makes the detector publish with writer, closing the previous
+// writer.
+func (d *DriftDetector) SetWriter(writer KafkaWriter) {
+	d.mu.Lock()
+	previous := d.writer
+	d.writer = writer
+	d.mu.Unlock()
+
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			log.Printf("Failed to close drift writer: %v", err)
+		}
+	}
+}
+
+// SetDriftTopic changes the topic drift events are published to.
+func (d *DriftDetector) SetDriftTopic(topic string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.topic = topic
+}
+
+// SetKnownSchema replaces the schema messages of a topic are compared with.
+func (d *DriftDetector) SetKnownSchema(topic string, s *schema.Schema) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.known[topic] = s.Clone()
+}
+
+// KnownSchema returns a copy of the schema known for a topic, or nil.
+func (d *DriftDetector) KnownSchema(topic string) *schema.Schema {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if known, ok := d.known[topic]; ok {
+		return known.Clone()
+	}
+	return nil
+}
+
+// Handle checks a consumed message for drift and publishes the resulting
+// events. It can be passed to NewConsumer.
+func (d *DriftDetector) Handle(message kafka.Message, data map[string]interface{}) {
+	events, err := d.check(message, data)
+	if err != nil {
+		log.Printf("Failed to check schema drift: %v", err)
+		return
+	}
+	if err := d.Publish(context.Background(), events); err != nil {
+		log.Printf("Failed to publish schema drift: %v", err)
+	}
+}
+
+// Check returns the drift events of a message and updates the known schema of
+// its topic.
+func (d *DriftDetector) Check(message kafka.Message) ([]DriftEvent, error) {
+	return d.check(message, nil)
+}
+
+// check is Check for a message that may already be parsed by ParseMessage.
+func (d *DriftDetector) check(message kafka.Message, parsed map[string]interface{}) ([]DriftEvent, error) {
+	document, root, err := DocumentOf(message.Value, parsed)
+	if err != nil {
+		return nil, err
+	}
+	observed := inferDocument("", document, root, message.Value)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	known, found := d.known[message.Topic]
+	if !found {
+		d.known[message.Topic] = observed
+		return nil, nil
+	}
+
+	merged := schema.Merge(known, observed)
+	var events []DriftEvent
+	for _, change := range schema.Diff(known, merged) {
+		if change.Kind == schema.RequiredChanged {
+			// A required field turns optional when the message lacks it.
+			if observed.Lookup(change.Path) != nil {
+				continue
+			}
+			change.Kind, change.NewType = schema.FieldRemoved, ""
+		}
+		events = append(events, DriftEvent{
+			Topic:      message.Topic,
+			Partition:  message.Partition,
+			Offset:     message.Offset,
+			DetectedAt: time.Now(),
+			Change:     change.Kind,
+			Path:       change.Path,
+			OldType:    change.OldType,
+			NewType:    change.NewType,
+			Sample:     string(message.Value),
+		})
+	}
+	d.known[message.Topic] = merged
+	return events, nil
+}
+
+// Publish writes the events to the drift topic, keyed by the topic they
+// concern.
+func (d *DriftDetector) Publish(ctx context.Context, events []DriftEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	d.mu.Lock()
+	topic, writer := d.topic, d.writer
+	d.mu.Unlock()
+	if writer == nil {
+		return fmt.Errorf("error publishing drift events: not connected")
+	}
+
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("error encoding drift event: %v", err)
+		}
+		messages = append(messages, kafka.Message{Topic: topic, Key: []byte(event.Topic), Value: value})
+	}
+	return writer.WriteMessages(ctx, messages...)
+}
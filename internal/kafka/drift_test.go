@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestDriftDetector(t *testing.T) {
+	t.Run("Given a changing producer, it should publish each change once", func(t *testing.T) {
+		writer := &MockKafkaWriter{}
+		detector := NewDriftDetector(writer, "drift")
+
+		messages := []kafka.Message{
+			{Topic: "orders", Offset: 1, Value: []byte(`{"id": "a", "amount": 10, "note": "x"}`)},
+			{Topic: "orders", Offset: 2, Value: []byte(`{"id": "b", "amount": 12, "note": "y"}`)},
+			{Topic: "orders", Offset: 3, Value: []byte(`{"id": "c", "amount": 12.5, "currency": "USD"}`)},
+			{Topic: "orders", Offset: 4, Value: []byte(`{"id": "d", "amount": 13.5, "currency": "EUR"}`)},
+		}
+		for _, message := range messages {
+			detector.Handle(message, nil)
+		}
+
+		expected := map[string]DriftEvent{
+			"amount":   {Change: schema.TypeChanged, OldType: schema.TypeInteger, NewType: schema.TypeNumber},
+			"currency": {Change: schema.FieldAdded, NewType: schema.TypeString},
+			"note":     {Change: schema.FieldRemoved, OldType: schema.TypeString},
+		}
+		if len(writer.Messages) != len(expected) {
+			t.Fatalf("Expected %d drift events, got %d", len(expected), len(writer.Messages))
+		}
+		for _, message := range writer.Messages {
+			var event DriftEvent
+			if err := json.Unmarshal(message.Value, &event); err != nil {
+				t.Fatalf("Failed to unmarshal drift event: %v", err)
+			}
+			want, ok := expected[event.Path]
+			if !ok {
+				t.Fatalf("Unexpected drift event: %+v", event)
+			}
+			if message.Topic != "drift" || string(message.Key) != "orders" {
+				t.Errorf("Expected the event on drift keyed by orders, got %s/%s", message.Topic, message.Key)
+			}
+			if event.Change != want.Change || event.OldType != want.OldType || event.NewType != want.NewType {
+				t.Errorf("Expected %+v, got %+v", want, event)
+			}
+			if event.Offset != 3 || event.Sample != string(messages[2].Value) {
+				t.Errorf("Expected first seen offset 3 with its sample, got %d %s", event.Offset, event.Sample)
+			}
+		}
+	})
+
+	t.Run("Given a known schema, it should compare the first message with it", func(t *testing.T) {
+		writer := &MockKafkaWriter{}
+		detector := NewDriftDetector(writer, "drift")
+		detector.SetKnownSchema("users", &schema.Schema{Fields: []*schema.Field{
+			{Name: "name", Type: schema.TypeString, Required: true},
+		}})
+		detector.SetDriftTopic("user-drift")
+
+		detector.Handle(kafka.Message{Topic: "users", Value: []byte(`{"name": "Alice", "age": 30}`)}, nil)
+
+		if len(writer.Messages) != 1 || writer.Messages[0].Topic != "user-drift" {
+			t.Fatalf("Expected one event on user-drift, got %+v", writer.Messages)
+		}
+		if known := detector.KnownSchema("users"); known.Field("age") == nil {
+			t.Errorf("Expected age to become part of the known schema")
+		}
+	})
+}
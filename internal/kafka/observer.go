@@ -0,0 +1,153 @@
+package kafka
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// SchemaObserver accumulates the schema inferred from every message consumed
+// from a topic, so optional and repeated fields are recognised across samples
+// and topics carrying several event types get one variant per type. It also
+// detects the string fields of each topic that look like enums and
+// samples example values of every field.
+type SchemaObserver struct {
+	mu            sync.RWMutex
+	unions        map[string]*schema.UnionBuilder
+	enums         map[string]*schema.EnumDetector
+	enumThreshold int
+	examples      map[string]*schema.ExampleSampler
+	masked        []string
+	random        *rand.Rand
+}
+
+// NewSchemaObserver returns an observer that has not seen any message yet.
+func NewSchemaObserver() *SchemaObserver {
+	return &SchemaObserver{
+		unions:        make(map[string]*schema.UnionBuilder),
+		enums:         make(map[string]*schema.EnumDetector),
+		enumThreshold: schema.DefaultEnumThreshold,
+		examples:      make(map[string]*schema.ExampleSampler),
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetMaskedFields changes the paths of the fields whose example values are
+// masked; schema.MaskAll masks every field.
+func (o *SchemaObserver) SetMaskedFields(paths []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.masked = paths
+	for _, sampler := range o.examples {
+		sampler.SetMasked(paths)
+	}
+}
+
+// SetEnumThreshold changes the largest number of distinct values of a field
+// proposed as an enum.
+func (o *SchemaObserver) SetEnumThreshold(threshold int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.enumThreshold = threshold
+	for _, detector := range o.enums {
+		detector.Threshold = threshold
+	}
+}
+
+// Observe merges the schema of a message value into the schema of its topic.
+func (o *SchemaObserver) Observe(topic string, value []byte) error {
+	return o.observe(topic, value, nil)
+}
+
+// observe is Observe for a value that may already be parsed by ParseMessage.
+func (o *SchemaObserver) observe(topic string, value []byte, parsed map[string]interface{}) error {
+	document, root, err := DocumentOf(value, parsed)
+	if err != nil {
+		return err
+	}
+	inferred := inferDocument("", document, root, value)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	union, ok := o.unions[topic]
+	if !ok {
+		union = schema.NewUnionBuilder()
+		o.unions[topic] = union
+	}
+	union.Add(document, inferred)
+	detector, ok := o.enums[topic]
+	if !ok {
+		detector = schema.NewEnumDetector()
+		detector.Threshold = o.enumThreshold
+		o.enums[topic] = detector
+	}
+	detector.Observe(document)
+	sampler, ok := o.examples[topic]
+	if !ok {
+		sampler = schema.NewExampleSampler(schema.DefaultExampleSize, o.random)
+		sampler.SetMasked(o.masked)
+		o.examples[topic] = sampler
+	}
+	sampler.Observe(document)
+	return nil
+}
+
+// Handle observes a consumed message. It can be passed to NewConsumer.
+func (o *SchemaObserver) Handle(message kafka.Message, data map[string]interface{}) {
+	_ = o.observe(message.Topic, message.Value, data)
+}
+
+// Schema returns a copy of the schema accumulated for a topic, with the enums
+// that were not rejected and the sampled examples applied, or nil when no
+// message of the topic has been observed.
+func (o *SchemaObserver) Schema(topic string) *schema.Schema {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	union, ok := o.unions[topic]
+	if !ok {
+		return nil
+	}
+	s := union.Schema()
+	o.enums[topic].Apply(s)
+	o.examples[topic].Apply(s)
+	return s
+}
+
+// EnumSuggestions lists the enums proposed for the fields of a topic, or nil
+// when no message of the topic has been observed.
+func (o *SchemaObserver) EnumSuggestions(topic string) []schema.EnumSuggestion {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if detector, ok := o.enums[topic]; ok {
+		return detector.Suggestions()
+	}
+	return nil
+}
+
+// DecideEnum confirms or rejects the enum proposed for a field of a topic.
+func (o *SchemaObserver) DecideEnum(topic, path string, status schema.EnumStatus) (schema.EnumSuggestion, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	detector, ok := o.enums[topic]
+	if !ok {
+		return schema.EnumSuggestion{}, fmt.Errorf("no messages observed on topic %q", topic)
+	}
+	return detector.Decide(path, status)
+}
+
+// Topics lists the observed topics in alphabetical order.
+func (o *SchemaObserver) Topics() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	topics := make([]string, 0, len(o.unions))
+	for topic := range o.unions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestSchemaObserver(t *testing.T) {
+	t.Run("Given several XML samples, it should accumulate presence and repetition", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		samples := []string{
+			`<Transaction><ID>1</ID><Items><Item><Price>1.50</Price></Item></Items><PromotionCode>P1</PromotionCode></Transaction>`,
+			`<Transaction><ID>2</ID><Items><Item><Price>2.00</Price></Item><Item><Price>3.25</Price></Item></Items></Transaction>`,
+		}
+		for _, sample := range samples {
+			observer.Handle(kafka.Message{Topic: "transactions", Value: []byte(sample)}, nil)
+		}
+
+		result := observer.Schema("transactions")
+		if result == nil {
+			t.Fatal("Expected a schema for the transactions topic")
+		}
+		if field := result.Field("PromotionCode"); field == nil || field.Required {
+			t.Errorf("Expected PromotionCode to be optional, got %+v", field)
+		}
+		if field := result.Lookup("Items.Item"); field == nil || field.Type != schema.TypeArray {
+			t.Errorf("Expected Items.Item to repeat, got %+v", field)
+		}
+		if field := result.Lookup("Items.Item.Price"); field == nil || field.Type != schema.TypeDecimal {
+			t.Errorf("Expected Items.Item.Price to be a decimal, got %+v", field)
+		}
+	})
+
+	t.Run("Given an unparseable message, it should return an error", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		if err := observer.Observe("t", []byte("plain text")); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+		if observer.Schema("t") != nil {
+			t.Error("Expected no schema for the topic")
+		}
+	})
+
+	t.Run("Given observed topics, it should list them", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		_ = observer.Observe("b", []byte(`{"x": 1}`))
+		_ = observer.Observe("a", []byte(`{"x": 1}`))
+
+		if !reflect.DeepEqual(observer.Topics(), []string{"a", "b"}) {
+			t.Errorf("Expected [a b], got %v", observer.Topics())
+		}
+	})
+
+	t.Run("Given a low-cardinality field, it should propose and apply an enum", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		statuses := []string{"PENDING", "SETTLED"}
+		for i := 0; i < schema.DefaultEnumMinSamples; i++ {
+			sample := `<Transaction><Status>` + statuses[i%2] + `</Status></Transaction>`
+			_ = observer.Observe("transactions", []byte(sample))
+		}
+
+		suggestions := observer.EnumSuggestions("transactions")
+		if len(suggestions) != 1 || suggestions[0].Path != "Status" {
+			t.Fatalf("Expected an enum for Status, got %+v", suggestions)
+		}
+		if enum := observer.Schema("transactions").Field("Status").Enum; !reflect.DeepEqual(enum, statuses) {
+			t.Errorf("Expected %v, got %v", statuses, enum)
+		}
+
+		if _, err := observer.DecideEnum("transactions", "Status", schema.EnumRejected); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if enum := observer.Schema("transactions").Field("Status").Enum; enum != nil {
+			t.Errorf("Expected no enum after rejection, got %v", enum)
+		}
+	})
+
+	t.Run("Given masked fields, it should return masked examples with the schema", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		observer.SetMaskedFields([]string{"Customer.Email"})
+		sample := `<Transaction><Customer><Email>ann@example.com</Email></Customer><Amount>12.50</Amount></Transaction>`
+		_ = observer.Observe("transactions", []byte(sample))
+
+		result := observer.Schema("transactions")
+		if examples := result.Lookup("Customer.Email").Examples; !reflect.DeepEqual(examples, []string{"xxx@xxxxxxx.xxx"}) {
+			t.Errorf("Expected a masked email, got %v", examples)
+		}
+		if examples := result.Field("Amount").Examples; !reflect.DeepEqual(examples, []string{"12.50"}) {
+			t.Errorf("Expected [12.50], got %v", examples)
+		}
+	})
+
+	t.Run("Given several event types, it should describe each as a variant", func(t *testing.T) {
+		observer := NewSchemaObserver()
+		_ = observer.Observe("orders", []byte(`{"eventType": "OrderCreated", "id": "1", "total": 10.5}`))
+		_ = observer.Observe("orders", []byte(`{"eventType": "OrderCancelled", "id": "2", "reason": "late"}`))
+
+		result := observer.Schema("orders")
+		if result.Discriminator != "eventType" || len(result.Variants) != 2 {
+			t.Fatalf("Expected two variants discriminated by eventType, got %+v", result)
+		}
+		if result.Field("reason") != nil {
+			t.Errorf("Expected variant specific fields to be left out of the common fields, got %+v", result.Fields)
+		}
+	})
+}
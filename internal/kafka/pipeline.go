@@ -0,0 +1,273 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/mapping"
+)
+
+// MessageReader fetches the messages of a topic in order, blocking until the
+// next one is written, and commits the ones that were handled so that they
+// are not fetched again.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Connect returns a reader of topic and a writer for the cluster of
+// connection, as used by a running mapping pipeline.
+type Connect func(topic string, connection Connection) (MessageReader, KafkaWriter, error)
+
+// writeError is returned by Map when a mapped message could not be written,
+// which unlike a message that cannot be mapped is worth retrying.
+type writeError struct {
+	err error
+}
+
+func (e writeError) Error() string {
+	return fmt.Sprintf("error writing mapped message: %v", e.err)
+}
+
+// PipelineStatus is the state of a mapping pipeline.
+type PipelineStatus struct {
+	SourceTopic string `json:"source_topic"`
+	TargetTopic string `json:"target_topic"`
+	Running     bool   `json:"running"`
+	Mapped      int    `json:"mapped"`
+	Failed      int    `json:"failed"`
+}
+
+// MappingPipeline maps the messages of a source topic with a mapping spec and
+// writes the mapped documents, encoded as JSON and keyed like the messages
+// they come from, to a target topic. Messages that cannot be parsed or mapped
+// are logged and skipped, while messages that cannot be written are retried
+// and only committed once written. The pipeline only runs while it has a spec
+// and both topics.
+type MappingPipeline struct {
+	mu         sync.Mutex
+	source     string
+	target     string
+	connection Connection
+	spec       *mapping.Spec
+	changed    chan struct{}
+	mapped     int
+	failed     int
+}
+
+// NewMappingPipeline returns a pipeline without a spec, topics or
+// connection until they are set.
+func NewMappingPipeline() *MappingPipeline {
+	return &MappingPipeline{changed: make(chan struct{})}
+}
+
+// SetTopics changes the topics messages are read from and written to.
+func (p *MappingPipeline) SetTopics(source, target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if source == p.source && target == p.target {
+		return
+	}
+	p.source, p.target = source, target
+	p.notify()
+}
+
+// SetConnection changes the cluster messages are read from and written to.
+func (p *MappingPipeline) SetConnection(connection Connection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if reflect.DeepEqual(connection, p.connection) {
+		return
+	}
+	p.connection = connection
+	p.notify()
+}
+
+// SetMapping replaces the spec messages are mapped with; a nil spec stops the
+// pipeline.
+func (p *MappingPipeline) SetMapping(spec *mapping.Spec) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spec = spec
+	p.notify()
+}
+
+// Mapping returns the spec messages are mapped with, or nil.
+func (p *MappingPipeline) Mapping() *mapping.Spec {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.spec
+}
+
+// Status returns the topics of the pipeline, whether it runs, and how many
+// messages it mapped and failed to map.
+func (p *MappingPipeline) Status() PipelineStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PipelineStatus{
+		SourceTopic: p.source,
+		TargetTopic: p.target,
+		Running:     p.ready(),
+		Mapped:      p.mapped,
+		Failed:      p.failed,
+	}
+}
+
+// notify wakes Run up after a change; the caller holds the lock.
+func (p *MappingPipeline) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// ready reports whether the pipeline has a spec and both topics; the caller
+// holds the lock.
+func (p *MappingPipeline) ready() bool {
+	return p.spec != nil && p.source != "" && p.target != ""
+}
+
+// Map maps a message with the current spec and writes the result to the
+// target topic with writer. It does nothing while the pipeline is not ready.
+// Messages that could not be written are not counted as failed, as Run writes
+// them again.
+func (p *MappingPipeline) Map(ctx context.Context, writer KafkaWriter, message kafka.Message) error {
+	p.mu.Lock()
+	spec, target, ready := p.spec, p.target, p.ready()
+	p.mu.Unlock()
+	if !ready {
+		return nil
+	}
+
+	value, err := mapMessage(spec, message)
+	if err == nil {
+		err = writer.WriteMessages(ctx, kafka.Message{Topic: target, Key: message.Key, Value: value})
+		if err != nil {
+			return writeError{err}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failed++
+		return err
+	}
+	p.mapped++
+	return nil
+}
+
+func mapMessage(spec *mapping.Spec, message kafka.Message) ([]byte, error) {
+	document, _, err := ParseDocument(message.Value)
+	if err != nil {
+		return nil, err
+	}
+	mapped, err := spec.Apply(document)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(mapped)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding mapped message: %v", err)
+	}
+	return value, nil
+}
+
+// Run fetches the source topic with readers made by connect and maps every
+// message until ctx is done, committing each message once it is mapped or
+// found unmappable. Whenever the topics, the connection or the spec change
+// the current reader and writer are closed and, if the pipeline is still
+// ready, new ones are made for the new source topic.
+func (p *MappingPipeline) Run(ctx context.Context, connect Connect) {
+	for ctx.Err() == nil {
+		p.mu.Lock()
+		source, connection, changed, ready := p.source, p.connection, p.changed, p.ready()
+		p.mu.Unlock()
+		if !ready {
+			select {
+			case <-ctx.Done():
+			case <-changed:
+			}
+			continue
+		}
+		reader, writer, err := connect(source, connection)
+		if err != nil {
+			log.Printf("Failed to connect the mapping pipeline: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-changed:
+			}
+			continue
+		}
+		p.consume(ctx, reader, writer, changed)
+	}
+}
+
+// consume maps the messages of reader until ctx is done or changed is closed.
+func (p *MappingPipeline) consume(ctx context.Context, reader MessageReader, writer KafkaWriter, changed <-chan struct{}) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-readCtx.Done():
+		case <-changed:
+			cancel()
+		}
+	}()
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("Failed to close mapping reader: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			log.Printf("Failed to close mapping writer: %v", err)
+		}
+	}()
+
+	for {
+		message, err := reader.FetchMessage(readCtx)
+		if err != nil {
+			if readCtx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to read message to map: %v", err)
+			select {
+			case <-readCtx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if !p.deliver(readCtx, writer, message) {
+			return
+		}
+		if err := reader.CommitMessages(readCtx, message); err != nil && readCtx.Err() == nil {
+			log.Printf("Failed to commit mapped message at %s[%d]@%d: %v", message.Topic, message.Partition, message.Offset, err)
+		}
+	}
+}
+
+// deliver maps a message, writing it again every second until it is written
+// or found unmappable. It returns false when ctx is done first, leaving the
+// message to the next reader.
+func (p *MappingPipeline) deliver(ctx context.Context, writer KafkaWriter, message kafka.Message) bool {
+	for {
+		err := p.Map(ctx, writer, message)
+		if err == nil {
+			return true
+		}
+		log.Printf("Failed to map message at %s[%d]@%d: %v", message.Topic, message.Partition, message.Offset, err)
+		if _, retry := err.(writeError); !retry {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/mapping"
+)
+
+// queueReader hands out queued messages and then blocks until it is closed or
+// its context is done. It records the messages committed.
+type queueReader struct {
+	messages chan kafka.Message
+
+	mu        sync.Mutex
+	committed []kafka.Message
+}
+
+func (r *queueReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case message, ok := <-r.messages:
+		if !ok {
+			return kafka.Message{}, errors.New("reader closed")
+		}
+		return message, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *queueReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *queueReader) commits() []kafka.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]kafka.Message(nil), r.committed...)
+}
+
+func (r *queueReader) Close() error {
+	return nil
+}
+
+// syncWriter captures messages like MockKafkaWriter but can be read while the
+// pipeline runs. It fails the given number of writes first.
+type syncWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	failures int
+}
+
+func (w *syncWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("broker unavailable")
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *syncWriter) Close() error {
+	return nil
+}
+
+func (w *syncWriter) written() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+func parseMapping(t *testing.T, data string) *mapping.Spec {
+	t.Helper()
+	spec, err := mapping.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return spec
+}
+
+// waitFor fails the test unless condition holds within two seconds.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMappingPipeline(t *testing.T) {
+	t.Run("Given XML messages, it should write the mapped JSON to the target topic", func(t *testing.T) {
+		writer := &MockKafkaWriter{}
+		pipeline := NewMappingPipeline()
+		pipeline.SetTopics("pre", "post")
+		pipeline.SetMapping(parseMapping(t, `fields: [{source: ID, target: id}, {source: Amount, target: amount, type: number}]`))
+
+		message := kafka.Message{Topic: "pre", Key: []byte("key-1"), Value: []byte(`<Transaction><ID>t-1</ID><Amount>12.5</Amount></Transaction>`)}
+		if err := pipeline.Map(context.Background(), writer, message); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(writer.Messages) != 1 {
+			t.Fatalf("Expected one mapped message, got %d", len(writer.Messages))
+		}
+		written := writer.Messages[0]
+		if written.Topic != "post" || string(written.Key) != "key-1" || string(written.Value) != `{"amount":12.5,"id":"t-1"}` {
+			t.Errorf("Unexpected mapped message %s/%s: %s", written.Topic, written.Key, written.Value)
+		}
+	})
+
+	t.Run("Given an unmappable message, it should count it as failed", func(t *testing.T) {
+		writer := &MockKafkaWriter{}
+		pipeline := NewMappingPipeline()
+		pipeline.SetTopics("pre", "post")
+		pipeline.SetMapping(parseMapping(t, `fields: [{source: id, target: id, required: true}]`))
+
+		if err := pipeline.Map(context.Background(), writer, kafka.Message{Value: []byte(`{"name": "x"}`)}); err == nil {
+			t.Errorf("Expected the missing id to fail")
+		}
+		if status := pipeline.Status(); status.Failed != 1 || status.Mapped != 0 || len(writer.Messages) != 0 {
+			t.Errorf("Expected one failure and nothing written, got %+v", status)
+		}
+	})
+
+	t.Run("Given no mapping, it should neither run nor map", func(t *testing.T) {
+		writer := &MockKafkaWriter{}
+		pipeline := NewMappingPipeline()
+		pipeline.SetTopics("pre", "post")
+
+		if err := pipeline.Map(context.Background(), writer, kafka.Message{Value: []byte(`{"id": "x"}`)}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if status := pipeline.Status(); status.Running || len(writer.Messages) != 0 {
+			t.Errorf("Expected an idle pipeline, got %+v", status)
+		}
+	})
+
+	t.Run("Given changing topics, it should read the new source topic", func(t *testing.T) {
+		writer := &syncWriter{}
+		pipeline := NewMappingPipeline()
+		readers := map[string]*queueReader{
+			"pre":   {messages: make(chan kafka.Message, 1)},
+			"other": {messages: make(chan kafka.Message, 1)},
+		}
+		var mu sync.Mutex
+		var connections []Connection
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			pipeline.Run(ctx, func(topic string, connection Connection) (MessageReader, KafkaWriter, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				connections = append(connections, connection)
+				return readers[topic], writer, nil
+			})
+		}()
+		written := func(count int) func() bool {
+			return func() bool { return len(writer.written()) >= count }
+		}
+
+		pipeline.SetConnection(Connection{Brokers: []string{"broker-1:9092"}})
+		pipeline.SetMapping(parseMapping(t, `fields: [{source: id, target: key}]`))
+		pipeline.SetTopics("pre", "post")
+		readers["pre"].messages <- kafka.Message{Value: []byte(`{"id": "a"}`)}
+		waitFor(t, "one mapped message", written(1))
+
+		pipeline.SetTopics("other", "post")
+		readers["other"].messages <- kafka.Message{Value: []byte(`{"id": "b"}`)}
+		waitFor(t, "two mapped messages", written(2))
+
+		cancel()
+		<-done
+		messages := writer.written()
+		if string(messages[0].Value) != `{"key":"a"}` || string(messages[1].Value) != `{"key":"b"}` {
+			t.Errorf("Unexpected mapped messages %s and %s", messages[0].Value, messages[1].Value)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if last := connections[len(connections)-1]; len(last.Brokers) != 1 || last.Brokers[0] != "broker-1:9092" {
+			t.Errorf("Expected the pipeline to connect to broker-1:9092, got %+v", last)
+		}
+	})
+
+	t.Run("Given a failed write, it should write the message again before committing it", func(t *testing.T) {
+		writer := &syncWriter{failures: 1}
+		reader := &queueReader{messages: make(chan kafka.Message, 1)}
+		pipeline := NewMappingPipeline()
+		pipeline.SetMapping(parseMapping(t, `fields: [{source: id, target: key}]`))
+		pipeline.SetTopics("pre", "post")
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			pipeline.Run(ctx, func(string, Connection) (MessageReader, KafkaWriter, error) {
+				return reader, writer, nil
+			})
+		}()
+
+		reader.messages <- kafka.Message{Offset: 7, Value: []byte(`{"id": "a"}`)}
+		waitFor(t, "a first failed write", func() bool {
+			writer.mu.Lock()
+			defer writer.mu.Unlock()
+			return writer.failures == 0
+		})
+		if len(reader.commits()) != 0 {
+			t.Errorf("Expected the unwritten message to stay uncommitted")
+		}
+		waitFor(t, "the message to be committed", func() bool { return len(reader.commits()) == 1 })
+
+		cancel()
+		<-done
+		if len(writer.written()) != 1 || reader.commits()[0].Offset != 7 {
+			t.Errorf("Expected the message to be written once and committed, got %d writes", len(writer.written()))
+		}
+		if status := pipeline.Status(); status.Mapped != 1 || status.Failed != 0 {
+			t.Errorf("Expected one mapped message and no failures, got %+v", status)
+		}
+	})
+}
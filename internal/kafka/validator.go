@@ -0,0 +1,245 @@
+package kafka
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// Binding ties a topic to the registered schema its messages must follow.
+type Binding struct {
+	Topic   string         `json:"topic"`
+	Subject string         `json:"subject"`
+	Version int            `json:"version"`
+	Schema  *schema.Schema `json:"-"`
+}
+
+// Deprecation is a version of a subject that is deprecated or retired, with
+// its State named as in the registry.
+type Deprecation struct {
+	Subject string         `json:"subject"`
+	Version int            `json:"version"`
+	State   string         `json:"state"`
+	Schema  *schema.Schema `json:"-"`
+}
+
+// ViolationCount is the number of messages breaking their schema in the same
+// way at the same field path.
+type ViolationCount struct {
+	Path  string               `json:"path"`
+	Kind  schema.ViolationKind `json:"kind"`
+	Count int                  `json:"count"`
+}
+
+// ValidationReport sums up the validation of the messages of a bound topic.
+type ValidationReport struct {
+	Binding
+	Messages   int              `json:"messages"`
+	Invalid    int              `json:"invalid"`
+	Deprecated int              `json:"deprecated"`
+	Violations []ViolationCount `json:"violations"`
+}
+
+type violationKey struct {
+	path string
+	kind schema.ViolationKind
+}
+
+type topicValidation struct {
+	binding    Binding
+	messages   int
+	invalid    int
+	deprecated int
+	counts     map[violationKey]int
+}
+
+// SchemaValidator checks the messages consumed from bound topics against
+// their registered schema and counts the violations by field path. Messages
+// still matching a deprecated or retired version of the bound subject are
+// counted too and logged as warnings.
+type SchemaValidator struct {
+	mu           sync.RWMutex
+	topics       map[string]*topicValidation
+	deprecations map[string][]Deprecation
+}
+
+// NewSchemaValidator returns a validator without bound topics.
+func NewSchemaValidator() *SchemaValidator {
+	return &SchemaValidator{
+		topics:       make(map[string]*topicValidation),
+		deprecations: make(map[string][]Deprecation),
+	}
+}
+
+// Deprecate replaces the deprecated and retired versions of a subject that
+// messages of the topics bound to it are matched against, the latest first.
+func (v *SchemaValidator) Deprecate(subject string, deprecations []Deprecation) {
+	copied := make([]Deprecation, 0, len(deprecations))
+	for _, deprecation := range deprecations {
+		if deprecation.Schema != nil {
+			deprecation.Subject = subject
+			deprecation.Schema = deprecation.Schema.Clone()
+			copied = append(copied, deprecation)
+		}
+	}
+	sort.Slice(copied, func(i, j int) bool { return copied[i].Version > copied[j].Version })
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(copied) == 0 {
+		delete(v.deprecations, subject)
+		return
+	}
+	v.deprecations[subject] = copied
+}
+
+// Bind makes the messages of a topic validated against the schema of the
+// binding, resetting the counts of the topic.
+func (v *SchemaValidator) Bind(binding Binding) error {
+	if binding.Topic == "" || binding.Schema == nil {
+		return fmt.Errorf("error binding schema: topic and schema are required")
+	}
+	binding.Schema = binding.Schema.Clone()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.topics[binding.Topic] = &topicValidation{binding: binding, counts: map[violationKey]int{}}
+	return nil
+}
+
+// Unbind stops validating the messages of a topic. It reports whether the
+// topic was bound.
+func (v *SchemaValidator) Unbind(topic string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, found := v.topics[topic]
+	delete(v.topics, topic)
+	return found
+}
+
+// Validate checks a message value against the schema bound to its topic and
+// counts its violations. It returns nil for topics without a binding.
+func (v *SchemaValidator) Validate(topic string, value []byte) ([]schema.Violation, error) {
+	violations, _, err := v.validate(topic, value, nil)
+	return violations, err
+}
+
+// validate is Validate also returning the deprecated or retired version of
+// the bound subject the message matches, if any.
+func (v *SchemaValidator) validate(topic string, value []byte, parsed map[string]interface{}) ([]schema.Violation, *Deprecation, error) {
+	v.mu.RLock()
+	validation, bound := v.topics[topic]
+	var deprecations []Deprecation
+	if bound {
+		deprecations = v.deprecations[validation.binding.Subject]
+	}
+	v.mu.RUnlock()
+	if !bound {
+		return nil, nil, nil
+	}
+
+	document, _, err := DocumentOf(value, parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+	violations := schema.Validate(validation.binding.Schema, document)
+	var matched *Deprecation
+	for i := range deprecations {
+		if len(schema.Validate(deprecations[i].Schema, document)) == 0 {
+			matched = &deprecations[i]
+			break
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.topics[topic] != validation {
+		// The topic was bound again meanwhile; do not count the message.
+		return violations, matched, nil
+	}
+	validation.messages++
+	if len(violations) > 0 {
+		validation.invalid++
+	}
+	if matched != nil {
+		validation.deprecated++
+	}
+	for _, violation := range violations {
+		validation.counts[violationKey{path: violation.Path, kind: violation.Kind}]++
+	}
+	return violations, matched, nil
+}
+
+// Handle validates a consumed message and logs its violations, warning when
+// it still matches a deprecated or retired version. It can be passed to
+// NewConsumer.
+func (v *SchemaValidator) Handle(message kafka.Message, data map[string]interface{}) {
+	violations, deprecation, err := v.validate(message.Topic, message.Value, data)
+	if err != nil {
+		log.Printf("Failed to validate message: %v", err)
+		return
+	}
+	if deprecation != nil {
+		log.Printf("Warning: message at %s[%d]@%d matches %s version %d of %s",
+			message.Topic, message.Partition, message.Offset, deprecation.State, deprecation.Version, deprecation.Subject)
+	}
+	for _, violation := range violations {
+		log.Printf("Message at %s[%d]@%d violates its schema: %s %s",
+			message.Topic, message.Partition, message.Offset, violation.Path, violation.Kind)
+	}
+}
+
+// Topics returns the bound topics, sorted.
+func (v *SchemaValidator) Topics() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	topics := make([]string, 0, len(v.topics))
+	for topic := range v.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Report returns the validation report of a bound topic.
+func (v *SchemaValidator) Report(topic string) (ValidationReport, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	validation, found := v.topics[topic]
+	if !found {
+		return ValidationReport{}, false
+	}
+	return validation.report(), true
+}
+
+// Reports returns the validation reports of every bound topic sorted by
+// topic.
+func (v *SchemaValidator) Reports() []ValidationReport {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	reports := make([]ValidationReport, 0, len(v.topics))
+	for _, validation := range v.topics {
+		reports = append(reports, validation.report())
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Topic < reports[j].Topic })
+	return reports
+}
+
+func (t *topicValidation) report() ValidationReport {
+	report := ValidationReport{Binding: t.binding, Messages: t.messages, Invalid: t.invalid, Deprecated: t.deprecated, Violations: []ViolationCount{}}
+	for key, count := range t.counts {
+		report.Violations = append(report.Violations, ViolationCount{Path: key.path, Kind: key.kind, Count: count})
+	}
+	sort.Slice(report.Violations, func(i, j int) bool {
+		a, b := report.Violations[i], report.Violations[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Kind < b.Kind
+	})
+	return report
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+func TestSchemaValidator(t *testing.T) {
+	bound := &schema.Schema{Fields: []*schema.Field{
+		{Name: "ID", Type: schema.TypeString, Required: true},
+		{Name: "Amount", Type: schema.TypeNumber, Required: true},
+	}}
+
+	t.Run("Given messages of a bound topic, it should count their violations by path", func(t *testing.T) {
+		validator := NewSchemaValidator()
+		if err := validator.Bind(Binding{Topic: "payments", Subject: "payments-value", Version: 2, Schema: bound}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, value := range []string{
+			`{"ID": "a", "Amount": 1.5}`,
+			`{"ID": "b", "Amount": "1.5"}`,
+			`{"Amount": "2", "Extra": true}`,
+		} {
+			validator.Handle(kafka.Message{Topic: "payments", Value: []byte(value)}, nil)
+		}
+
+		report, found := validator.Report("payments")
+		if !found || report.Messages != 3 || report.Invalid != 2 || report.Subject != "payments-value" || report.Version != 2 {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		expected := []ViolationCount{
+			{Path: "Amount", Kind: schema.WrongType, Count: 2},
+			{Path: "Extra", Kind: schema.UnexpectedField, Count: 1},
+			{Path: "ID", Kind: schema.MissingRequired, Count: 1},
+		}
+		if !reflect.DeepEqual(report.Violations, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, report.Violations)
+		}
+	})
+
+	t.Run("Given an XML message, it should validate the content of its root element", func(t *testing.T) {
+		validator := NewSchemaValidator()
+		_ = validator.Bind(Binding{Topic: "payments", Schema: bound})
+
+		violations, err := validator.Validate("payments", []byte(`<Payment><ID>a</ID><Amount>9.99</Amount></Payment>`))
+		if err != nil || len(violations) != 0 {
+			t.Errorf("Expected no violations, got %+v (%v)", violations, err)
+		}
+	})
+
+	t.Run("Given a topic without binding, it should not validate its messages", func(t *testing.T) {
+		validator := NewSchemaValidator()
+		violations, err := validator.Validate("orders", []byte(`not parsed`))
+		if violations != nil || err != nil {
+			t.Errorf("Expected nothing, got %+v (%v)", violations, err)
+		}
+		if _, found := validator.Report("orders"); found {
+			t.Error("Expected no report for an unbound topic")
+		}
+	})
+
+	t.Run("Given a message matching a deprecated version, it should count it", func(t *testing.T) {
+		validator := NewSchemaValidator()
+		_ = validator.Bind(Binding{Topic: "payments", Subject: "payments-value", Version: 2, Schema: bound})
+		validator.Deprecate("payments-value", []Deprecation{{Version: 1, State: "deprecated", Schema: &schema.Schema{Fields: []*schema.Field{
+			{Name: "ID", Type: schema.TypeString, Required: true},
+		}}}})
+
+		validator.Handle(kafka.Message{Topic: "payments", Value: []byte(`{"ID": "a"}`)}, nil)
+		validator.Handle(kafka.Message{Topic: "payments", Value: []byte(`{"ID": "b", "Amount": 1}`)}, nil)
+		if report, _ := validator.Report("payments"); report.Deprecated != 1 || report.Invalid != 1 {
+			t.Errorf("Expected one message to match version 1, got %+v", report)
+		}
+
+		validator.Deprecate("payments-value", nil)
+		validator.Handle(kafka.Message{Topic: "payments", Value: []byte(`{"ID": "c"}`)}, nil)
+		if report, _ := validator.Report("payments"); report.Deprecated != 1 {
+			t.Errorf("Expected no more matches, got %+v", report)
+		}
+	})
+
+	t.Run("Given an unbound topic, it should drop its report", func(t *testing.T) {
+		validator := NewSchemaValidator()
+		_ = validator.Bind(Binding{Topic: "payments", Schema: bound})
+		if !validator.Unbind("payments") || len(validator.Reports()) != 0 {
+			t.Errorf("Expected the binding to be removed, got %+v", validator.Reports())
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package mapping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule sets the field at the dot separated Target path of a mapped document,
+// either to the value at the Source path of the consumed document or to the
+// constant Value. Path segments that are numbers index arrays. The value is
+// converted to Type when it is string, integer, number or boolean. A missing
+// source takes the Default when there is one, fails the message when the
+// rule is Required, and leaves the target unset otherwise.
+type Rule struct {
+	Source   string      `json:"source,omitempty" yaml:"source,omitempty"`
+	Target   string      `json:"target" yaml:"target"`
+	Type     schema.Type `json:"type,omitempty" yaml:"type,omitempty"`
+	Required bool        `json:"required,omitempty" yaml:"required,omitempty"`
+	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Value    interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Spec describes how the fields of consumed documents become the fields of
+// the documents produced from them, which only hold the mapped fields. A spec
+// is not changed once parsed.
+type Spec struct {
+	Fields []Rule `json:"fields" yaml:"fields"`
+}
+
+// Parse decodes a mapping spec written in YAML or JSON and checks its rules.
+// Unknown keys are rejected so that misspelt options are not ignored.
+func Parse(data []byte) (*Spec, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var spec Spec
+	if err := decoder.Decode(&spec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("mapping is empty")
+		}
+		return nil, fmt.Errorf("error decoding mapping: %v", err)
+	}
+	if len(spec.Fields) == 0 {
+		return nil, fmt.Errorf("mapping has no fields")
+	}
+	targets := map[string]bool{}
+	for i, rule := range spec.Fields {
+		if rule.Target == "" {
+			return nil, fmt.Errorf("rule %d has no target", i)
+		}
+		if targets[rule.Target] {
+			return nil, fmt.Errorf("target %q is mapped twice", rule.Target)
+		}
+		targets[rule.Target] = true
+		if (rule.Source == "") == (rule.Value == nil) {
+			return nil, fmt.Errorf("rule for %q needs either a source or a value", rule.Target)
+		}
+		switch rule.Type {
+		case "", schema.TypeString, schema.TypeInteger, schema.TypeNumber, schema.TypeBoolean:
+		default:
+			return nil, fmt.Errorf("rule for %q cannot convert to type %q", rule.Target, rule.Type)
+		}
+	}
+	return &spec, nil
+}
+
+// Apply maps a consumed document, as parsed by kafka.ParseDocument, into a
+// new document.
+func (s *Spec) Apply(document map[string]interface{}) (map[string]interface{}, error) {
+	mapped := map[string]interface{}{}
+	for _, rule := range s.Fields {
+		value, found := rule.Value, true
+		if rule.Source != "" {
+			value, found = lookup(document, rule.Source)
+		}
+		if !found {
+			switch {
+			case rule.Default != nil:
+				value = rule.Default
+			case rule.Required:
+				return nil, fmt.Errorf("required source %q is missing", rule.Source)
+			default:
+				continue
+			}
+		}
+		converted, err := convert(value, rule.Type)
+		if err != nil {
+			return nil, fmt.Errorf("error mapping %q: %v", rule.Target, err)
+		}
+		if err := set(mapped, rule.Target, converted); err != nil {
+			return nil, err
+		}
+	}
+	return mapped, nil
+}
+
+// lookup returns the value at a path of a document. Null values count as
+// missing.
+func lookup(document map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = document
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// set stores a value at a path of a mapped document, creating the records on
+// the way.
+func set(document map[string]interface{}, path string, value interface{}) error {
+	segments := strings.Split(path, ".")
+	current := document
+	for _, segment := range segments[:len(segments)-1] {
+		next, exists := current[segment]
+		if !exists {
+			record := map[string]interface{}{}
+			current[segment] = record
+			current = record
+			continue
+		}
+		record, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("target %q conflicts with the value mapped at %q", path, segment)
+		}
+		current = record
+	}
+	last := segments[len(segments)-1]
+	if _, exists := current[last]; exists {
+		return fmt.Errorf("target %q conflicts with another mapped field", path)
+	}
+	current[last] = value
+	return nil
+}
+
+// convert returns a value as the given type. XML elements are mapped as their
+// text when they have no attributes and converted from it when typed.
+func convert(value interface{}, t schema.Type) (interface{}, error) {
+	if element, ok := value.(map[string]interface{}); ok {
+		if text, found := element[schema.TextField]; found && (len(element) == 1 || t != "") {
+			value = text
+		}
+	}
+	if t == "" {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case string:
+		return convertString(v, t)
+	case bool:
+		if t == schema.TypeBoolean {
+			return v, nil
+		}
+		if t == schema.TypeString {
+			return strconv.FormatBool(v), nil
+		}
+	case float64:
+		return convertNumber(v, t)
+	case int:
+		return convertNumber(float64(v), t)
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, t)
+}
+
+func convertString(s string, t schema.Type) (interface{}, error) {
+	trimmed := strings.TrimSpace(s)
+	var value interface{}
+	var err error
+	switch t {
+	case schema.TypeString:
+		return s, nil
+	case schema.TypeInteger:
+		value, err = strconv.ParseInt(trimmed, 10, 64)
+	case schema.TypeNumber:
+		value, err = strconv.ParseFloat(trimmed, 64)
+	case schema.TypeBoolean:
+		value, err = strconv.ParseBool(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q to %s", s, t)
+	}
+	return value, nil
+}
+
+func convertNumber(n float64, t schema.Type) (interface{}, error) {
+	switch t {
+	case schema.TypeString:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case schema.TypeInteger:
+		if n != float64(int64(n)) {
+			return nil, fmt.Errorf("cannot convert %v to integer", n)
+		}
+		return int64(n), nil
+	case schema.TypeNumber:
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", n, t)
+}
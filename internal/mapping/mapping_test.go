@@ -0,0 +1,142 @@
+package mapping
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+const ordersMapping = `
+fields:
+  - source: ID
+    target: order.id
+  - source: Amount
+    target: order.total
+    type: number
+  - source: Customer.Email
+    target: customer_email
+    required: true
+  - source: Items.0.Name
+    target: first_item
+  - source: Currency
+    target: currency
+    default: USD
+  - source: Note
+    target: note
+  - target: origin
+    value: legacy
+`
+
+func TestParse(t *testing.T) {
+	t.Run("Given a YAML mapping, it should decode its rules", func(t *testing.T) {
+		spec, err := Parse([]byte(ordersMapping))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := Rule{Source: "Amount", Target: "order.total", Type: schema.TypeNumber}
+		if len(spec.Fields) != 7 || !reflect.DeepEqual(spec.Fields[1], expected) {
+			t.Errorf("Expected 7 rules with %+v second, got %+v", expected, spec.Fields)
+		}
+	})
+
+	t.Run("Given a JSON mapping, it should decode its rules", func(t *testing.T) {
+		spec, err := Parse([]byte(`{"fields": [{"source": "id", "target": "key", "required": true}]}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []Rule{{Source: "id", Target: "key", Required: true}}
+		if !reflect.DeepEqual(spec.Fields, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, spec.Fields)
+		}
+	})
+
+	t.Run("Given invalid mappings, it should explain what is wrong", func(t *testing.T) {
+		invalid := map[string]string{
+			"":                      "empty",
+			"fields: []":            "no fields",
+			"fields: [{source: a}]": "no target",
+			"fields: [{source: a, target: b, typ: string}]":            "typ",
+			"fields: [{target: b}]":                                    "either a source or a value",
+			"fields: [{source: a, target: b, value: 1}]":               "either a source or a value",
+			"fields: [{source: a, target: b, type: record}]":           "cannot convert",
+			"fields: [{source: a, target: b}, {source: c, target: b}]": "mapped twice",
+		}
+		for data, expected := range invalid {
+			if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected %q to fail with %q, got %v", data, expected, err)
+			}
+		}
+	})
+}
+
+func TestApply(t *testing.T) {
+	spec, err := Parse([]byte(ordersMapping))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Given a document, it should build the mapped document", func(t *testing.T) {
+		document := map[string]interface{}{
+			"ID":       "o-1",
+			"Amount":   map[string]interface{}{"@currency": "EUR", schema.TextField: "10.50"},
+			"Customer": map[string]interface{}{"Email": "a@example.com"},
+			"Items":    []interface{}{map[string]interface{}{"Name": "Book"}},
+			"Note":     nil,
+		}
+		expected := map[string]interface{}{
+			"order":          map[string]interface{}{"id": "o-1", "total": 10.5},
+			"customer_email": "a@example.com",
+			"first_item":     "Book",
+			"currency":       "USD",
+			"origin":         "legacy",
+		}
+		mapped, err := spec.Apply(document)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(mapped, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, mapped)
+		}
+	})
+
+	t.Run("Given a document missing a required source, it should fail", func(t *testing.T) {
+		if _, err := spec.Apply(map[string]interface{}{"ID": "o-1"}); err == nil || !strings.Contains(err.Error(), "Customer.Email") {
+			t.Errorf("Expected the missing Customer.Email to be reported, got %v", err)
+		}
+	})
+
+	t.Run("Given a value of another type, it should convert it", func(t *testing.T) {
+		converting, err := Parse([]byte(`
+fields:
+  - {source: count, target: count, type: integer}
+  - {source: count, target: label, type: string}
+  - {source: active, target: active, type: boolean}
+`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		mapped, err := converting.Apply(map[string]interface{}{"count": float64(3), "active": "true"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := map[string]interface{}{"count": int64(3), "label": "3", "active": true}
+		if !reflect.DeepEqual(mapped, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, mapped)
+		}
+		if _, err := converting.Apply(map[string]interface{}{"count": "three"}); err == nil {
+			t.Errorf("Expected an unconvertible value to fail")
+		}
+	})
+
+	t.Run("Given targets inside one another, it should fail", func(t *testing.T) {
+		conflicting, err := Parse([]byte(`fields: [{source: a, target: b}, {source: c, target: b.c}]`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := conflicting.Apply(map[string]interface{}{"a": 1, "c": 2}); err == nil {
+			t.Errorf("Expected the conflicting targets to fail")
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package profile
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	t.Run("Given no values, it should estimate zero", func(t *testing.T) {
+		var h HyperLogLog
+		if estimate := h.Estimate(); estimate != 0 {
+			t.Errorf("Expected 0, got %d", estimate)
+		}
+	})
+
+	t.Run("Given repeated values, it should count them once", func(t *testing.T) {
+		var h HyperLogLog
+		for i := 0; i < 1000; i++ {
+			h.Add(strconv.Itoa(i % 10))
+		}
+		if estimate := h.Estimate(); estimate != 10 {
+			t.Errorf("Expected 10, got %d", estimate)
+		}
+	})
+
+	t.Run("Given many distinct values, it should estimate within a few percent", func(t *testing.T) {
+		var h HyperLogLog
+		for i := 0; i < 100000; i++ {
+			h.Add("value-" + strconv.Itoa(i))
+		}
+		estimate := float64(h.Estimate())
+		if estimate < 95000 || estimate > 105000 {
+			t.Errorf("Expected about 100000, got %.0f", estimate)
+		}
+	})
+}
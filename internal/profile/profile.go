@@ -0,0 +1,265 @@
+package profile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	localkafka "github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// lengthBuckets are the upper bounds of the string length histogram buckets.
+var lengthBuckets = []int{0, 4, 8, 16, 32, 64, 128, 256}
+
+// reportedQuantiles are the quantiles included in numeric profiles.
+var reportedQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// NumberProfile summarises the numeric values of a field.
+type NumberProfile struct {
+	Count     int                `json:"count"`
+	Min       float64            `json:"min"`
+	Max       float64            `json:"max"`
+	Mean      float64            `json:"mean"`
+	Quantiles map[string]float64 `json:"quantiles"`
+}
+
+// StringProfile summarises the lengths of the string values of a field.
+type StringProfile struct {
+	Count      int            `json:"count"`
+	MinLength  int            `json:"min_length"`
+	MaxLength  int            `json:"max_length"`
+	MeanLength float64        `json:"mean_length"`
+	Lengths    map[string]int `json:"length_distribution"`
+}
+
+// FieldProfile is the statistical profile of a field of a topic.
+type FieldProfile struct {
+	Path     string         `json:"path"`
+	Count    int            `json:"count"`
+	Nulls    int            `json:"nulls"`
+	NullRate float64        `json:"null_rate"`
+	Distinct uint64         `json:"distinct_estimate"`
+	Numbers  *NumberProfile `json:"numbers,omitempty"`
+	Strings  *StringProfile `json:"strings,omitempty"`
+}
+
+type fieldStats struct {
+	count, nulls int
+
+	distinct HyperLogLog
+
+	numbers        int
+	min, max, sum  float64
+	digest         *Digest
+	strings        int
+	minLen, maxLen int
+	lengthSum      int
+	lengths        []int
+}
+
+// Profiler builds per-field statistics of the messages consumed from each
+// topic.
+type Profiler struct {
+	mu     sync.Mutex
+	topics map[string]map[string]*fieldStats
+}
+
+// NewProfiler returns a profiler that has not seen any message yet.
+func NewProfiler() *Profiler {
+	return &Profiler{topics: make(map[string]map[string]*fieldStats)}
+}
+
+// Handle profiles a consumed message. It can be passed to NewConsumer.
+func (p *Profiler) Handle(message kafka.Message, data map[string]interface{}) {
+	document, _, err := localkafka.DocumentOf(message.Value, data)
+	if err != nil {
+		return
+	}
+	p.Observe(message.Topic, document)
+}
+
+// Observe adds the values of a parsed document to the profiles of a topic.
+// Array elements and the values of maps with dynamic keys are profiled under
+// the path of the field holding them.
+func (p *Profiler) Observe(topic string, document map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fields, ok := p.topics[topic]
+	if !ok {
+		fields = make(map[string]*fieldStats)
+		p.topics[topic] = fields
+	}
+	p.observeMap(fields, "", document)
+}
+
+func (p *Profiler) observeMap(fields map[string]*fieldStats, prefix string, values map[string]interface{}) {
+	for key, value := range values {
+		if key == schema.TextField {
+			continue
+		}
+		path := strings.TrimPrefix(key, schema.AttributePrefix)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		p.observeValue(fields, path, value, strings.HasPrefix(key, schema.AttributePrefix))
+	}
+}
+
+// observeValue records a value. XML character data and attributes are
+// profiled as numbers when they parse as one.
+func (p *Profiler) observeValue(fields map[string]*fieldStats, path string, value interface{}, xml bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			p.observeValue(fields, path, item, xml)
+		}
+		return
+	case map[string]interface{}:
+		if text, ok := v[schema.TextField].(string); ok && len(v) == 1 {
+			p.observeValue(fields, path, text, true)
+			return
+		}
+		if schema.IsDynamicMap(v) {
+			for _, item := range v {
+				p.observeValue(fields, path, item, xml)
+			}
+			return
+		}
+		p.observeMap(fields, path, v)
+		return
+	}
+
+	stats, ok := fields[path]
+	if !ok {
+		stats = &fieldStats{digest: NewDigest(), lengths: make([]int, len(lengthBuckets)+1)}
+		fields[path] = stats
+	}
+	stats.count++
+	switch v := value.(type) {
+	case nil:
+		stats.nulls++
+	case float64:
+		stats.distinct.Add(strconv.FormatFloat(v, 'g', -1, 64))
+		stats.addNumber(v)
+	case string:
+		stats.distinct.Add(v)
+		if number, err := strconv.ParseFloat(strings.TrimSpace(v), 64); xml && err == nil {
+			stats.addNumber(number)
+			return
+		}
+		stats.addString(v)
+	default:
+		stats.distinct.Add(fmt.Sprint(v))
+	}
+}
+
+func (s *fieldStats) addNumber(value float64) {
+	if s.numbers == 0 || value < s.min {
+		s.min = value
+	}
+	if s.numbers == 0 || value > s.max {
+		s.max = value
+	}
+	s.numbers++
+	s.sum += value
+	s.digest.Add(value)
+}
+
+func (s *fieldStats) addString(value string) {
+	length := len([]rune(value))
+	if s.strings == 0 || length < s.minLen {
+		s.minLen = length
+	}
+	if s.strings == 0 || length > s.maxLen {
+		s.maxLen = length
+	}
+	s.strings++
+	s.lengthSum += length
+	s.lengths[sort.SearchInts(lengthBuckets, length)]++
+}
+
+// Profiles returns the field profiles of a topic sorted by path, or nil when
+// the topic has not been observed.
+func (p *Profiler) Profiles(topic string) []FieldProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fields, ok := p.topics[topic]
+	if !ok {
+		return nil
+	}
+	profiles := make([]FieldProfile, 0, len(fields))
+	for path, stats := range fields {
+		profiles = append(profiles, stats.profile(path))
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Path < profiles[j].Path })
+	return profiles
+}
+
+// Topics lists the profiled topics in alphabetical order.
+func (p *Profiler) Topics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topics := make([]string, 0, len(p.topics))
+	for topic := range p.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (s *fieldStats) profile(path string) FieldProfile {
+	profile := FieldProfile{
+		Path:     path,
+		Count:    s.count,
+		Nulls:    s.nulls,
+		NullRate: float64(s.nulls) / float64(s.count),
+		Distinct: s.distinct.Estimate(),
+	}
+	if s.numbers > 0 {
+		profile.Numbers = &NumberProfile{
+			Count:     s.numbers,
+			Min:       s.min,
+			Max:       s.max,
+			Mean:      s.sum / float64(s.numbers),
+			Quantiles: map[string]float64{},
+		}
+		for _, q := range reportedQuantiles {
+			value := s.digest.Quantile(q)
+			if !math.IsNaN(value) {
+				profile.Numbers.Quantiles["p"+strconv.Itoa(int(math.Round(q*100)))] = value
+			}
+		}
+	}
+	if s.strings > 0 {
+		profile.Strings = &StringProfile{
+			Count:      s.strings,
+			MinLength:  s.minLen,
+			MaxLength:  s.maxLen,
+			MeanLength: float64(s.lengthSum) / float64(s.strings),
+			Lengths:    map[string]int{},
+		}
+		for i, count := range s.lengths {
+			if count > 0 {
+				profile.Strings.Lengths[bucketLabel(i)] = count
+			}
+		}
+	}
+	return profile
+}
+
+// bucketLabel names a length histogram bucket, e.g. "5-8" or "257+".
+func bucketLabel(i int) string {
+	switch {
+	case i == 0:
+		return "0"
+	case i == len(lengthBuckets):
+		return strconv.Itoa(lengthBuckets[i-1]+1) + "+"
+	default:
+		return strconv.Itoa(lengthBuckets[i-1]+1) + "-" + strconv.Itoa(lengthBuckets[i])
+	}
+}
@@ -0,0 +1,85 @@
+package profile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func findProfile(profiles []FieldProfile, path string) *FieldProfile {
+	for i := range profiles {
+		if profiles[i].Path == path {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+func TestProfiler(t *testing.T) {
+	t.Run("Given XML transactions, it should profile amounts and item prices", func(t *testing.T) {
+		p := NewProfiler()
+		samples := []string{
+			`<Transaction><ID>1</ID><Amount>10.50</Amount><Items><Item><Price>1.50</Price></Item><Item><Price>9.00</Price></Item></Items></Transaction>`,
+			`<Transaction><ID>2</ID><Amount>20.00</Amount><Items><Item><Price>20.00</Price></Item></Items></Transaction>`,
+		}
+		for _, sample := range samples {
+			p.Handle(kafka.Message{Topic: "transactions", Value: []byte(sample)}, nil)
+		}
+
+		profiles := p.Profiles("transactions")
+		amount := findProfile(profiles, "Amount")
+		if amount == nil || amount.Numbers == nil {
+			t.Fatalf("Expected a numeric profile for Amount, got %+v", amount)
+		}
+		if amount.Numbers.Min != 10.5 || amount.Numbers.Max != 20 || amount.Numbers.Mean != 15.25 {
+			t.Errorf("Unexpected Amount statistics: %+v", amount.Numbers)
+		}
+		price := findProfile(profiles, "Items.Item.Price")
+		if price == nil || price.Numbers == nil {
+			t.Fatalf("Expected a numeric profile for Items.Item.Price, got %+v", price)
+		}
+		if price.Count != 3 || price.Distinct != 3 || price.Numbers.Quantiles["p50"] != 9 {
+			t.Errorf("Unexpected Items.Item.Price profile: %+v %+v", price, price.Numbers)
+		}
+	})
+
+	t.Run("Given JSON messages, it should profile nulls and string lengths", func(t *testing.T) {
+		p := NewProfiler()
+		p.Observe("users", map[string]interface{}{"name": "Ann", "email": nil})
+		p.Observe("users", map[string]interface{}{"name": "Bartholomew", "email": "b@example.com"})
+
+		profiles := p.Profiles("users")
+		email := findProfile(profiles, "email")
+		if email == nil || email.Nulls != 1 || email.NullRate != 0.5 {
+			t.Errorf("Expected half of the emails to be null, got %+v", email)
+		}
+		name := findProfile(profiles, "name")
+		if name == nil || name.Strings == nil {
+			t.Fatalf("Expected a string profile for name, got %+v", name)
+		}
+		expected := &StringProfile{Count: 2, MinLength: 3, MaxLength: 11, MeanLength: 7,
+			Lengths: map[string]int{"1-4": 1, "9-16": 1}}
+		if !reflect.DeepEqual(name.Strings, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, name.Strings)
+		}
+		if name.Numbers != nil {
+			t.Errorf("Expected no numeric profile for JSON strings, got %+v", name.Numbers)
+		}
+	})
+
+	t.Run("Given an unknown topic, it should return no profiles", func(t *testing.T) {
+		if profiles := NewProfiler().Profiles("missing"); profiles != nil {
+			t.Errorf("Expected nil, got %v", profiles)
+		}
+	})
+
+	t.Run("Given profiled topics, it should list them", func(t *testing.T) {
+		p := NewProfiler()
+		p.Observe("b", map[string]interface{}{"x": 1.0})
+		p.Observe("a", map[string]interface{}{"x": 1.0})
+		if !reflect.DeepEqual(p.Topics(), []string{"a", "b"}) {
+			t.Errorf("Expected [a b], got %v", p.Topics())
+		}
+	})
+}
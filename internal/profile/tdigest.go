@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"math"
+	"sort"
+)
+
+// defaultCompression bounds the number of centroids a Digest keeps.
+const defaultCompression = 100
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// Digest is a merging t-digest estimating quantiles of a stream of numbers in
+// bounded memory, with the best accuracy near the extremes.
+type Digest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []float64
+	count       float64
+}
+
+// NewDigest returns an empty digest.
+func NewDigest() *Digest {
+	return &Digest{compression: defaultCompression}
+}
+
+// Add records a value.
+func (d *Digest) Add(value float64) {
+	d.buffer = append(d.buffer, value)
+	d.count++
+	if len(d.buffer) >= 5*int(d.compression) {
+		d.compress()
+	}
+}
+
+// Quantile returns the estimated value below which the fraction q of the
+// recorded values fall, or NaN when nothing was recorded.
+func (d *Digest) Quantile(q float64) float64 {
+	d.compress()
+	if len(d.centroids) == 0 {
+		return math.NaN()
+	}
+	if len(d.centroids) == 1 || q <= 0 {
+		return d.centroids[0].mean
+	}
+	last := d.centroids[len(d.centroids)-1]
+	if q >= 1 {
+		return last.mean
+	}
+
+	target := q * d.count
+	cumulative := 0.0
+	for i, c := range d.centroids {
+		center := cumulative + c.weight/2
+		if target < center {
+			if i == 0 {
+				return c.mean
+			}
+			previous := d.centroids[i-1]
+			previousCenter := cumulative - previous.weight/2
+			fraction := (target - previousCenter) / (center - previousCenter)
+			return previous.mean + fraction*(c.mean-previous.mean)
+		}
+		cumulative += c.weight
+	}
+	return last.mean
+}
+
+// compress merges the buffered values into the centroids, keeping centroids
+// near the median larger than those near the tails.
+func (d *Digest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := make([]centroid, 0, len(d.centroids)+len(d.buffer))
+	all = append(all, d.centroids...)
+	for _, value := range d.buffer {
+		all = append(all, centroid{mean: value, weight: 1})
+	}
+	d.buffer = d.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := []centroid{all[0]}
+	cumulative := 0.0
+	for _, c := range all[1:] {
+		current := &merged[len(merged)-1]
+		weight := current.weight + c.weight
+		q := (cumulative + weight/2) / d.count
+		if weight <= 4*d.count*q*(1-q)/d.compression {
+			current.mean += (c.mean - current.mean) * c.weight / weight
+			current.weight = weight
+			continue
+		}
+		cumulative += current.weight
+		merged = append(merged, c)
+	}
+	d.centroids = merged
+}
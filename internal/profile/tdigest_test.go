@@ -0,0 +1,45 @@
+package profile
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	t.Run("Given no values, it should return NaN", func(t *testing.T) {
+		if q := NewDigest().Quantile(0.5); !math.IsNaN(q) {
+			t.Errorf("Expected NaN, got %v", q)
+		}
+	})
+
+	t.Run("Given a single value, it should return it for every quantile", func(t *testing.T) {
+		d := NewDigest()
+		d.Add(42)
+		for _, q := range []float64{0, 0.5, 0.99, 1} {
+			if got := d.Quantile(q); got != 42 {
+				t.Errorf("Expected 42 for q=%v, got %v", q, got)
+			}
+		}
+	})
+
+	t.Run("Given a uniform stream, it should estimate its quantiles", func(t *testing.T) {
+		d := NewDigest()
+		r := rand.New(rand.NewSource(1))
+		for _, i := range r.Perm(10000) {
+			d.Add(float64(i))
+		}
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			expected := q * 10000
+			if got := d.Quantile(q); math.Abs(got-expected) > 100 {
+				t.Errorf("Expected about %v for q=%v, got %v", expected, q, got)
+			}
+		}
+		if got := d.Quantile(0); got != 0 {
+			t.Errorf("Expected the minimum 0, got %v", got)
+		}
+		if got := d.Quantile(1); got != 9999 {
+			t.Errorf("Expected the maximum 9999, got %v", got)
+		}
+	})
+}
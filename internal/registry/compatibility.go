@@ -0,0 +1,263 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// Compatibility is the rule a new version of a subject must satisfy against
+// the versions registered before it. It uses the names of Confluent Schema
+// Registry.
+type Compatibility string
+
+const (
+	CompatibilityNone               Compatibility = "NONE"
+	CompatibilityBackward           Compatibility = "BACKWARD"
+	CompatibilityBackwardTransitive Compatibility = "BACKWARD_TRANSITIVE"
+	CompatibilityForward            Compatibility = "FORWARD"
+	CompatibilityForwardTransitive  Compatibility = "FORWARD_TRANSITIVE"
+	CompatibilityFull               Compatibility = "FULL"
+	CompatibilityFullTransitive     Compatibility = "FULL_TRANSITIVE"
+)
+
+// DefaultCompatibility is the level of a new registry, as in Confluent Schema
+// Registry.
+const DefaultCompatibility = CompatibilityBackward
+
+// ParseCompatibility returns the level with the given name, ignoring case.
+func ParseCompatibility(name string) (Compatibility, error) {
+	level := Compatibility(strings.ToUpper(strings.TrimSpace(name)))
+	switch level {
+	case CompatibilityNone, CompatibilityBackward, CompatibilityBackwardTransitive,
+		CompatibilityForward, CompatibilityForwardTransitive, CompatibilityFull, CompatibilityFullTransitive:
+		return level, nil
+	default:
+		return "", fmt.Errorf("unknown compatibility level %q", name)
+	}
+}
+
+// Backward reports whether the level requires readers of a new version to
+// read data written with older ones.
+func (c Compatibility) Backward() bool {
+	return c == CompatibilityBackward || c == CompatibilityBackwardTransitive || c == CompatibilityFull || c == CompatibilityFullTransitive
+}
+
+// Forward reports whether the level requires readers of older versions to
+// read data written with a new one.
+func (c Compatibility) Forward() bool {
+	return c == CompatibilityForward || c == CompatibilityForwardTransitive || c == CompatibilityFull || c == CompatibilityFullTransitive
+}
+
+// Allows reports whether the diff of an older version against a new one
+// satisfies the level.
+func (c Compatibility) Allows(report schema.DiffReport) bool {
+	return !(c.Backward() && !report.BackwardCompatible) && !(c.Forward() && !report.ForwardCompatible)
+}
+
+// breaks reports whether a change breaks the level.
+func (c Compatibility) breaks(change schema.ClassifiedChange) bool {
+	return c.Backward() && change.BreaksBackward || c.Forward() && change.BreaksForward
+}
+
+// Transitive reports whether new versions are checked against every version
+// of the subject rather than only the latest one.
+func (c Compatibility) Transitive() bool {
+	return strings.HasSuffix(string(c), "_TRANSITIVE")
+}
+
+// Conflict lists the changes from a registered version that break the
+// compatibility level of a subject.
+type Conflict struct {
+	Version int                       `json:"version"`
+	Changes []schema.ClassifiedChange `json:"changes"`
+}
+
+// IncompatibleError is returned when registering a schema that breaks the
+// compatibility level of its subject with one or more of its versions.
+type IncompatibleError struct {
+	Subject   string
+	Level     Compatibility
+	Conflicts []Conflict
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schema is incompatible with subject %q under %s compatibility: %s",
+		e.Subject, e.Level, strings.Join(e.Messages(), "; "))
+}
+
+// Messages explains every breaking change, naming the version it breaks.
+func (e *IncompatibleError) Messages() []string {
+	var messages []string
+	for _, conflict := range e.Conflicts {
+		for _, change := range conflict.Changes {
+			messages = append(messages, fmt.Sprintf("version %d: %s", conflict.Version, Explain(change)))
+		}
+	}
+	return messages
+}
+
+// Explain describes a change and the compatibility direction it breaks.
+func Explain(change schema.ClassifiedChange) string {
+	var what string
+	switch change.Kind {
+	case schema.FieldAdded:
+		what = fmt.Sprintf("field %q was added as required", change.Path)
+		if !change.NewRequired {
+			what = fmt.Sprintf("optional field %q was added", change.Path)
+		}
+	case schema.FieldRemoved:
+		what = fmt.Sprintf("required field %q was removed", change.Path)
+		if !change.OldRequired {
+			what = fmt.Sprintf("optional field %q was removed", change.Path)
+		}
+	case schema.RequiredChanged:
+		what = fmt.Sprintf("field %q became optional", change.Path)
+		if change.NewRequired {
+			what = fmt.Sprintf("field %q became required", change.Path)
+		}
+	case schema.TypeChanged:
+		what = fmt.Sprintf("field %q changed type from %s to %s", change.Path, change.OldType, change.NewType)
+	default:
+		what = fmt.Sprintf("field %q changed (%s)", change.Path, change.Kind)
+	}
+	switch {
+	case change.BreaksBackward && change.BreaksForward:
+		return what + ", so neither schema can read data written with the other"
+	case change.BreaksBackward:
+		return what + ", so the new schema cannot read data written with the old one"
+	case change.BreaksForward:
+		return what + ", so the old schema cannot read data written with the new one"
+	default:
+		return what
+	}
+}
+
+// Check returns an *IncompatibleError when a schema breaks the compatibility
+// level of a subject with its latest version or, for transitive levels, with
+// any of its versions that is not a draft.
+func (r *Registry) Check(subject string, s *schema.Schema) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.check(subject, s)
+}
+
+// CheckVersion returns an *IncompatibleError when a schema breaks the
+// compatibility level of a subject with one of its versions.
+func (r *Registry) CheckVersion(subject string, version int, s *schema.Schema) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found := r.find(subject, version)
+	if found == nil {
+		return fmt.Errorf("version %d of subject %q not found", version, subject)
+	}
+	return r.conflicts(subject, []*Version{found}, s)
+}
+
+// check is Check for callers holding the lock. Drafts and soft deleted
+// versions are left out.
+func (r *Registry) check(subject string, s *schema.Schema) error {
+	var versions []*Version
+	for _, version := range r.live(subject) {
+		if version.State != StateDraft {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	if !r.level(subject).Transitive() {
+		versions = versions[len(versions)-1:]
+	}
+	return r.conflicts(subject, versions, s)
+}
+
+func (r *Registry) conflicts(subject string, versions []*Version, s *schema.Schema) error {
+	level := r.level(subject)
+	if level == CompatibilityNone {
+		return nil
+	}
+	s, err := r.resolve(s)
+	if err != nil {
+		return err
+	}
+	incompatible := &IncompatibleError{Subject: subject, Level: level}
+	for _, version := range versions {
+		previous, err := r.resolve(version.Schema)
+		if err != nil {
+			return err
+		}
+		conflict := Conflict{Version: version.Version}
+		for _, change := range schema.Compare(previous, s).Changes {
+			if level.breaks(change) {
+				conflict.Changes = append(conflict.Changes, change)
+			}
+		}
+		if len(conflict.Changes) > 0 {
+			incompatible.Conflicts = append(incompatible.Conflicts, conflict)
+		}
+	}
+	if len(incompatible.Conflicts) == 0 {
+		return nil
+	}
+	return incompatible
+}
+
+// level returns the level applying to a subject; the caller holds the lock.
+func (r *Registry) level(subject string) Compatibility {
+	if level, ok := r.levels[subject]; ok {
+		return level
+	}
+	return r.compatibility
+}
+
+// Compatibility returns the level applying to a subject: its own, or else the
+// level of the registry.
+func (r *Registry) Compatibility(subject string) Compatibility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.level(subject)
+}
+
+// SubjectCompatibility returns the level a subject configured for itself.
+func (r *Registry) SubjectCompatibility(subject string) (Compatibility, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	level, ok := r.levels[subject]
+	return level, ok
+}
+
+// SetCompatibility changes the level of a subject, or of the registry when
+// subject is empty.
+func (r *Registry) SetCompatibility(subject string, level Compatibility) error {
+	if _, err := ParseCompatibility(string(level)); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.saveLevel(subject, level); err != nil {
+		return err
+	}
+	if subject == "" {
+		r.compatibility = level
+	} else {
+		r.levels[subject] = level
+	}
+	return nil
+}
+
+// ClearCompatibility removes the level a subject configured, returning it.
+func (r *Registry) ClearCompatibility(subject string) (Compatibility, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	level, ok := r.levels[subject]
+	if !ok {
+		return "", false, nil
+	}
+	if err := r.saveLevel(subject, ""); err != nil {
+		return "", false, err
+	}
+	delete(r.levels, subject)
+	return level, true, nil
+}
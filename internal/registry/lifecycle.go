@@ -0,0 +1,271 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// State is the lifecycle state of a registered version. Drafts are proposed
+// versions that are not yet served as the latest one; active versions are in
+// use; deprecated versions are still accepted but should be moved away from;
+// retired versions must no longer be used, and new schemas cannot reference
+// them nor can topics be bound to them.
+type State string
+
+const (
+	StateDraft      State = "draft"
+	StateActive     State = "active"
+	StateDeprecated State = "deprecated"
+	StateRetired    State = "retired"
+)
+
+// transitions lists the states each state can move to. Retiring is final.
+var transitions = map[State][]State{
+	StateDraft:      {StateActive, StateRetired},
+	StateActive:     {StateDeprecated, StateRetired},
+	StateDeprecated: {StateActive, StateRetired},
+}
+
+// ParseState returns the state with the given name, ignoring case.
+func ParseState(name string) (State, error) {
+	state := State(strings.ToLower(strings.TrimSpace(name)))
+	switch state {
+	case StateDraft, StateActive, StateDeprecated, StateRetired:
+		return state, nil
+	default:
+		return "", fmt.Errorf("unknown state %q", name)
+	}
+}
+
+// CanBecome reports whether a version in the state can be moved to next.
+func (s State) CanBecome(next State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when moving a version to a state its current
+// state cannot move to.
+type TransitionError struct {
+	Subject string
+	Version int
+	From    State
+	To      State
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("version %d of subject %q cannot move from %s to %s", e.Version, e.Subject, e.From, e.To)
+}
+
+// NotSoftDeletedError is returned when permanently deleting a version, or a
+// subject when Version is zero, that was not soft deleted first.
+type NotSoftDeletedError struct {
+	Subject string
+	Version int
+}
+
+func (e *NotSoftDeletedError) Error() string {
+	if e.Version == 0 {
+		return fmt.Sprintf("subject %q was not soft deleted before being permanently deleted", e.Subject)
+	}
+	return fmt.Sprintf("version %d of subject %q was not soft deleted before being permanently deleted", e.Version, e.Subject)
+}
+
+// Watch makes the registry call fn with the subject of every change to its
+// versions, whether made through this registry or by another instance
+// sharing its store. fn is called without the lock held and may read the
+// registry; it may also be called for a subject that did not change.
+func (r *Registry) Watch(fn func(subject string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watchers = append(r.watchers, fn)
+}
+
+// notify calls the watchers with a changed subject.
+func (r *Registry) notify(subject string) {
+	r.mu.RLock()
+	watchers := r.watchers
+	r.mu.RUnlock()
+	for _, fn := range watchers {
+		fn(subject)
+	}
+}
+
+// SetState moves a version of a subject to a state, returning the changed
+// version. It reports whether the version exists; a move its current state
+// does not allow returns a *TransitionError.
+func (r *Registry) SetState(subject string, version int, state State) (Version, bool, error) {
+	changed, found, err := r.setState(subject, version, state)
+	if found && err == nil {
+		r.notify(subject)
+	}
+	return changed, found, err
+}
+
+func (r *Registry) setState(subject string, version int, state State) (Version, bool, error) {
+	if _, err := ParseState(string(state)); err != nil {
+		return Version{}, false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := r.find(subject, version)
+	if found == nil {
+		return Version{}, false, nil
+	}
+	if found.State == state {
+		return r.copy(found), true, nil
+	}
+	if !found.State.CanBecome(state) {
+		return Version{}, true, &TransitionError{Subject: subject, Version: version, From: found.State, To: state}
+	}
+	changed := *found
+	changed.State = state
+	if err := r.replaceVersion(subject, &changed); err != nil {
+		return Version{}, true, err
+	}
+	return r.copy(&changed), true, nil
+}
+
+// DeleteVersion deletes a version of a subject, reporting whether it existed.
+// A soft deletion hides the version but keeps it so that it can be deleted
+// for good later; a permanent deletion removes a soft deleted version, and
+// returns a *NotSoftDeletedError for other versions. Versions that other
+// versions reference are not deleted and a *ReferencedError is returned.
+// IDs of deleted versions keep serving their schemas.
+func (r *Registry) DeleteVersion(subject string, version int, permanent bool) (bool, error) {
+	found, err := r.deleteVersion(subject, version, permanent)
+	if found && err == nil {
+		r.notify(subject)
+	}
+	return found, err
+}
+
+func (r *Registry) deleteVersion(subject string, version int, permanent bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !permanent {
+		found := r.find(subject, version)
+		if found == nil {
+			return false, nil
+		}
+		if err := r.deletable(subject, version, false, false); err != nil {
+			return false, err
+		}
+		deleted := *found
+		deleted.Deleted = true
+		return true, r.replaceVersion(subject, &deleted)
+	}
+
+	found := r.findDeleted(subject, version)
+	if found == nil {
+		return false, nil
+	}
+	if !found.Deleted {
+		return false, &NotSoftDeletedError{Subject: subject, Version: version}
+	}
+	if err := r.deletable(subject, version, false, true); err != nil {
+		return false, err
+	}
+	var remaining []*Version
+	for _, candidate := range r.subjects[subject] {
+		if candidate.Version != version {
+			remaining = append(remaining, candidate)
+		}
+	}
+	return true, r.replaceSubject(subject, remaining)
+}
+
+// DeleteSubject deletes every version of a subject, returning their numbers,
+// soft deleting the versions that are not or permanently deleting a subject
+// whose versions all are. It reports whether the subject had versions to
+// delete; when another subject references one of them nothing is deleted and
+// a *ReferencedError is returned.
+func (r *Registry) DeleteSubject(subject string, permanent bool) ([]int, bool, error) {
+	deleted, found, err := r.deleteSubject(subject, permanent)
+	if found && err == nil {
+		r.notify(subject)
+	}
+	return deleted, found, err
+}
+
+func (r *Registry) deleteSubject(subject string, permanent bool) ([]int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.live(subject)
+	if permanent {
+		if len(versions) > 0 {
+			return nil, false, &NotSoftDeletedError{Subject: subject}
+		}
+		versions = r.subjects[subject]
+	}
+	if len(versions) == 0 {
+		return nil, false, nil
+	}
+	numbers := make([]int, len(versions))
+	for i, version := range versions {
+		if err := r.deletable(subject, version.Version, true, permanent); err != nil {
+			return nil, false, err
+		}
+		numbers[i] = version.Version
+	}
+	if permanent {
+		return numbers, true, r.replaceSubject(subject, nil)
+	}
+	remaining := make([]*Version, len(r.subjects[subject]))
+	for i, version := range r.subjects[subject] {
+		deleted := *version
+		deleted.Deleted = true
+		remaining[i] = &deleted
+	}
+	return numbers, true, r.replaceSubject(subject, remaining)
+}
+
+// deletable returns a *ReferencedError when other versions reference a
+// version, ignoring the versions of its own subject when the whole subject is
+// deleted and soft deleted versions unless the deletion is permanent; the
+// caller holds the lock.
+func (r *Registry) deletable(subject string, version int, wholeSubject, permanent bool) error {
+	var by []Version
+	for _, referencing := range r.referencedBy(subject, version, permanent) {
+		if !wholeSubject || referencing.Subject != subject {
+			by = append(by, r.copy(referencing))
+		}
+	}
+	if len(by) > 0 {
+		return &ReferencedError{Subject: subject, Version: version, By: by}
+	}
+	return nil
+}
+
+// replaceVersion stores a changed version of a subject in place of the
+// version with its number; the caller holds the lock.
+func (r *Registry) replaceVersion(subject string, changed *Version) error {
+	versions := make([]*Version, len(r.subjects[subject]))
+	for i, version := range r.subjects[subject] {
+		versions[i] = version
+		if version.Version == changed.Version {
+			versions[i] = changed
+		}
+	}
+	return r.replaceSubject(subject, versions)
+}
+
+// replaceSubject stores the remaining versions of a subject, removing the
+// subject when none remain; the caller holds the lock.
+func (r *Registry) replaceSubject(subject string, versions []*Version) error {
+	if len(versions) == 0 {
+		if err := r.store.Delete(subjectsBucket, subject); err != nil {
+			return err
+		}
+		delete(r.subjects, subject)
+		return nil
+	}
+	if err := r.saveSubject(subject, versions); err != nil {
+		return err
+	}
+	r.subjects[subject] = versions
+	return nil
+}
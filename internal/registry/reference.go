@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// ReferencedError is returned when deleting versions that other versions
+// still reference.
+type ReferencedError struct {
+	Subject string
+	Version int
+	By      []Version
+}
+
+func (e *ReferencedError) Error() string {
+	by := make([]string, len(e.By))
+	for i, version := range e.By {
+		by[i] = fmt.Sprintf("%s version %d", version.Subject, version.Version)
+	}
+	return fmt.Sprintf("version %d of subject %q is referenced by %s", e.Version, e.Subject, strings.Join(by, ", "))
+}
+
+// pin returns a copy of a schema whose references to the latest version of a
+// subject name that version, checking that every reference resolves to a
+// version that is not retired; the caller holds the lock.
+func (r *Registry) pin(s *schema.Schema) (*schema.Schema, error) {
+	pinned := s.Clone()
+	var err error
+	pinned.EachReference(func(ref *schema.Reference) {
+		version := r.latestOr(ref.Subject, ref.Version)
+		switch {
+		case err != nil:
+		case version == nil:
+			err = fmt.Errorf("referenced %s not found", ref)
+		case version.State == StateRetired:
+			err = fmt.Errorf("referenced %s is retired", ref)
+		default:
+			ref.Version = version.Version
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.resolve(pinned); err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// Resolve returns a copy of a schema in which referencing fields carry the
+// fields of the versions they reference.
+func (r *Registry) Resolve(s *schema.Schema) (*schema.Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resolve(s)
+}
+
+// resolve is Resolve for callers holding the lock.
+func (r *Registry) resolve(s *schema.Schema) (*schema.Schema, error) {
+	return schema.Resolve(s, func(ref schema.Reference) (*schema.Schema, error) {
+		version := r.latestOr(ref.Subject, ref.Version)
+		if version == nil {
+			return nil, fmt.Errorf("referenced %s not found", ref)
+		}
+		return version.Schema, nil
+	})
+}
+
+// latestOr returns a version of a subject, or its latest version when
+// version is zero; the caller holds the lock.
+func (r *Registry) latestOr(subject string, version int) *Version {
+	if version != 0 {
+		return r.find(subject, version)
+	}
+	return r.latest(subject)
+}
+
+// ReferencedBy returns the versions referencing a version of a subject,
+// leaving out deleted ones.
+func (r *Registry) ReferencedBy(subject string, version int) []Version {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var by []Version
+	for _, referencing := range r.referencedBy(subject, version, false) {
+		by = append(by, r.copy(referencing))
+	}
+	return by
+}
+
+// referencedBy is ReferencedBy for callers holding the lock, listing the
+// versions in subject and version order, soft deleted ones included when
+// deleted is set.
+func (r *Registry) referencedBy(subject string, version int, deleted bool) []*Version {
+	var by []*Version
+	for _, name := range r.subjectNames(true) {
+		for _, candidate := range r.subjects[name] {
+			if candidate.Deleted && !deleted {
+				continue
+			}
+			for _, ref := range candidate.Schema.References() {
+				if ref.Subject == subject && ref.Version == version {
+					by = append(by, candidate)
+					break
+				}
+			}
+		}
+	}
+	return by
+}
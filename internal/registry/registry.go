@@ -0,0 +1,329 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"github.com/wolfchristopher/thoth/internal/storage"
+)
+
+// Version is a schema registered under a subject. Versions of a subject are
+// numbered from 1; IDs are global and shared by every subject registering the
+// same schema. Text holds the schema document as registered through the
+// Confluent compatible API, which serializers expect back verbatim. Soft
+// deleted versions are kept, marked Deleted, until they are deleted for good.
+type Version struct {
+	Subject    string         `json:"subject"`
+	Version    int            `json:"version"`
+	ID         int            `json:"id"`
+	Schema     *schema.Schema `json:"schema"`
+	Text       string         `json:"text,omitempty"`
+	State      State          `json:"state"`
+	Deleted    bool           `json:"deleted,omitempty"`
+	Registered time.Time      `json:"registered"`
+}
+
+// registered is a distinct schema known by its ID.
+type registered struct {
+	schema *schema.Schema
+	text   string
+}
+
+// Registry stores schemas under subjects, giving each new schema of a subject
+// the next version number and each distinct schema a unique ID. It also keeps
+// the compatibility level of the registry and of each subject configuring
+// its own. Every change is written to its store before it takes effect.
+type Registry struct {
+	mu            sync.RWMutex
+	store         storage.Store
+	subjects      map[string][]*Version
+	ids           map[int]*registered
+	digests       map[string]int
+	nextID        int
+	compatibility Compatibility
+	levels        map[string]Compatibility
+	watchers      []func(subject string)
+}
+
+// New returns an empty registry using DefaultCompatibility, kept in memory
+// only.
+func New() *Registry {
+	return newRegistry(storage.NewMemory())
+}
+
+func newRegistry(store storage.Store) *Registry {
+	return &Registry{
+		store:         store,
+		subjects:      map[string][]*Version{},
+		ids:           map[int]*registered{},
+		digests:       map[string]int{},
+		nextID:        1,
+		compatibility: DefaultCompatibility,
+		levels:        map[string]Compatibility{},
+	}
+}
+
+// digest identifies a schema by its full JSON form and registered text, so
+// schemas differing only in names, documentation or Avro union order are
+// registered separately.
+func digest(s *schema.Schema, text string) string {
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(append(data, text...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Register stores a schema under a subject. Registering a schema the subject
+// already holds returns the existing version instead of creating a new one;
+// a new version must satisfy the compatibility level of the subject or an
+// *IncompatibleError is returned.
+func (r *Registry) Register(subject string, s *schema.Schema) (Version, error) {
+	return r.RegisterText(subject, s, "")
+}
+
+// RegisterText stores a schema under a subject together with the document it
+// was parsed from. References of the schema must point at registered
+// versions; references to the latest version are pinned to its number.
+func (r *Registry) RegisterText(subject string, s *schema.Schema, text string) (Version, error) {
+	return r.register(subject, s, text, StateActive)
+}
+
+// RegisterDraft stores a schema under a subject as RegisterText does, in the
+// draft state. The draft is checked against the compatibility level of the
+// subject but is not its latest version until it is made active.
+func (r *Registry) RegisterDraft(subject string, s *schema.Schema, text string) (Version, error) {
+	return r.register(subject, s, text, StateDraft)
+}
+
+func (r *Registry) register(subject string, s *schema.Schema, text string, state State) (Version, error) {
+	version, err := r.add(subject, s, text, state)
+	if err == nil {
+		r.notify(subject)
+	}
+	return version, err
+}
+
+func (r *Registry) add(subject string, s *schema.Schema, text string, state State) (Version, error) {
+	if strings.TrimSpace(subject) == "" {
+		return Version{}, fmt.Errorf("error registering schema: subject is empty")
+	}
+	if s == nil {
+		return Version{}, fmt.Errorf("error registering schema: schema is nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.pin(s)
+	if err != nil {
+		return Version{}, fmt.Errorf("error registering schema: %v", err)
+	}
+	key := digest(s, text)
+	id, known := r.digests[key]
+	if known {
+		for _, version := range r.subjects[subject] {
+			if version.ID == id && !version.Deleted {
+				return r.copy(version), nil
+			}
+		}
+	}
+	if err := r.check(subject, s); err != nil {
+		return Version{}, err
+	}
+	nextID := r.nextID
+	if !known {
+		id = nextID
+		nextID++
+		// The ID is taken before the version is stored, so a failure in
+		// between skips it rather than issuing it twice.
+		if err := r.saveNextID(nextID); err != nil {
+			return Version{}, err
+		}
+	}
+
+	number := 1
+	if versions := r.subjects[subject]; len(versions) > 0 {
+		number = versions[len(versions)-1].Version + 1
+	}
+	version := &Version{
+		Subject:    subject,
+		Version:    number,
+		ID:         id,
+		Schema:     s,
+		Text:       text,
+		State:      state,
+		Registered: time.Now().UTC(),
+	}
+	if known {
+		version.Schema = r.ids[id].schema
+	}
+	versions := append(append([]*Version(nil), r.subjects[subject]...), version)
+	if err := r.saveSubject(subject, versions); err != nil {
+		return Version{}, err
+	}
+	r.subjects[subject] = versions
+	if !known {
+		r.nextID = nextID
+		r.digests[key] = id
+		r.ids[id] = &registered{schema: version.Schema, text: text}
+	}
+	return r.copy(version), nil
+}
+
+// Lookup returns the version of a subject holding the given schema and text.
+func (r *Registry) Lookup(subject string, s *schema.Schema, text string) (Version, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, err := r.pin(s)
+	if err != nil {
+		return Version{}, false
+	}
+	id, known := r.digests[digest(s, text)]
+	if !known {
+		return Version{}, false
+	}
+	for _, version := range r.subjects[subject] {
+		if version.ID == id && !version.Deleted {
+			return r.copy(version), true
+		}
+	}
+	return Version{}, false
+}
+
+// copy returns a version whose schema the caller may modify.
+func (r *Registry) copy(version *Version) Version {
+	c := *version
+	c.Schema = version.Schema.Clone()
+	return c
+}
+
+// Subjects lists the registered subjects in alphabetical order, leaving out
+// subjects whose versions are all soft deleted.
+func (r *Registry) Subjects() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.subjectNames(false)
+}
+
+// subjectNames is Subjects for callers holding the lock, listing every
+// subject when deleted is set.
+func (r *Registry) subjectNames(deleted bool) []string {
+	subjects := make([]string, 0, len(r.subjects))
+	for subject := range r.subjects {
+		if deleted || len(r.live(subject)) > 0 {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// Versions lists the version numbers of a subject in ascending order, leaving
+// out soft deleted versions.
+func (r *Registry) Versions(subject string) ([]int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.live(subject)
+	if len(versions) == 0 {
+		return nil, false
+	}
+	numbers := make([]int, len(versions))
+	for i, version := range versions {
+		numbers[i] = version.Version
+	}
+	return numbers, true
+}
+
+// live returns the versions of a subject that are not soft deleted; the
+// caller holds the lock.
+func (r *Registry) live(subject string) []*Version {
+	var versions []*Version
+	for _, version := range r.subjects[subject] {
+		if !version.Deleted {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// Version returns a version of a subject.
+func (r *Registry) Version(subject string, version int) (Version, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found := r.find(subject, version)
+	if found == nil {
+		return Version{}, false
+	}
+	return r.copy(found), true
+}
+
+// find returns a version of a subject that is not soft deleted, or nil; the
+// caller holds the lock.
+func (r *Registry) find(subject string, version int) *Version {
+	if found := r.findDeleted(subject, version); found != nil && !found.Deleted {
+		return found
+	}
+	return nil
+}
+
+// findDeleted is find including soft deleted versions.
+func (r *Registry) findDeleted(subject string, version int) *Version {
+	for _, candidate := range r.subjects[subject] {
+		if candidate.Version == version {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// Latest returns the most recent version of a subject that is neither a
+// draft nor soft deleted.
+func (r *Registry) Latest(subject string) (Version, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	latest := r.latest(subject)
+	if latest == nil {
+		return Version{}, false
+	}
+	return r.copy(latest), true
+}
+
+// latest is Latest for callers holding the lock, returning nil when the
+// subject has no such version.
+func (r *Registry) latest(subject string) *Version {
+	versions := r.live(subject)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].State != StateDraft {
+			return versions[i]
+		}
+	}
+	return nil
+}
+
+// ByID returns the schema registered with an ID.
+func (r *Registry) ByID(id int) (*schema.Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found, ok := r.ids[id]
+	if !ok {
+		return nil, false
+	}
+	return found.schema.Clone(), true
+}
+
+// TextByID returns the document the schema with an ID was registered from,
+// empty for schemas registered in thoth's own format.
+func (r *Registry) TextByID(id int) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found, ok := r.ids[id]
+	if !ok {
+		return "", false
+	}
+	return found.text, true
+}
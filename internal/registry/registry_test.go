@@ -0,0 +1,569 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"github.com/wolfchristopher/thoth/internal/storage"
+)
+
+func userSchema(fields ...string) *schema.Schema {
+	s := &schema.Schema{Name: "User"}
+	for _, name := range fields {
+		s.Fields = append(s.Fields, &schema.Field{Name: name, Type: schema.TypeString, Required: true})
+	}
+	return s
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Given new schemas for a subject, it should number their versions and IDs", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("", CompatibilityNone)
+		first, err := r.Register("users", userSchema("id"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		second, _ := r.Register("users", userSchema("id", "email"))
+		if first.Version != 1 || second.Version != 2 || first.ID == second.ID {
+			t.Errorf("Unexpected versions: %+v and %+v", first, second)
+		}
+
+		versions, found := r.Versions("users")
+		if !found || len(versions) != 2 || versions[1] != 2 {
+			t.Errorf("Expected versions [1 2], got %v", versions)
+		}
+		latest, _ := r.Latest("users")
+		if latest.Version != 2 || latest.Schema.Field("email") == nil {
+			t.Errorf("Expected the latest version to be 2, got %+v", latest)
+		}
+	})
+
+	t.Run("Given an already registered schema, it should return its version", func(t *testing.T) {
+		r := New()
+		first, _ := r.Register("users", userSchema("id"))
+		again, _ := r.Register("users", userSchema("id"))
+		if again.Version != first.Version || again.ID != first.ID {
+			t.Errorf("Expected version %+v, got %+v", first, again)
+		}
+		if versions, _ := r.Versions("users"); len(versions) != 1 {
+			t.Errorf("Expected a single version, got %v", versions)
+		}
+	})
+
+	t.Run("Given the same schema under two subjects, it should share its ID", func(t *testing.T) {
+		r := New()
+		users, _ := r.Register("users", userSchema("id"))
+		admins, _ := r.Register("admins", userSchema("id"))
+		if users.ID != admins.ID || admins.Version != 1 {
+			t.Errorf("Expected a shared ID, got %+v and %+v", users, admins)
+		}
+		if subjects := r.Subjects(); len(subjects) != 2 || subjects[0] != "admins" {
+			t.Errorf("Expected sorted subjects, got %v", subjects)
+		}
+		s, found := r.ByID(users.ID)
+		if !found || s.Field("id") == nil {
+			t.Errorf("Expected the schema of ID %d, got %+v", users.ID, s)
+		}
+	})
+
+	t.Run("Given unknown subjects, versions and IDs, it should report them as not found", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("users", userSchema("id"))
+		if _, found := r.Version("users", 2); found {
+			t.Error("Expected version 2 not to be found")
+		}
+		if _, found := r.Latest("orders"); found {
+			t.Error("Expected subject orders not to be found")
+		}
+		if _, found := r.ByID(42); found {
+			t.Error("Expected ID 42 not to be found")
+		}
+	})
+
+	t.Run("Given a returned version, it should not let callers modify the registry", func(t *testing.T) {
+		r := New()
+		version, _ := r.Register("users", userSchema("id"))
+		version.Schema.Fields[0].Name = "changed"
+		stored, _ := r.Version("users", 1)
+		if stored.Schema.Field("id") == nil {
+			t.Errorf("Expected the stored schema to be unchanged, got %+v", stored.Schema.Fields[0])
+		}
+	})
+
+	t.Run("Given an empty subject, it should return an error", func(t *testing.T) {
+		if _, err := New().Register(" ", userSchema("id")); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestCompatibility(t *testing.T) {
+	t.Run("Given a level in lower case, it should parse it", func(t *testing.T) {
+		level, err := ParseCompatibility("full_transitive")
+		if err != nil || level != CompatibilityFullTransitive {
+			t.Errorf("Expected FULL_TRANSITIVE, got %q (%v)", level, err)
+		}
+		if _, err := ParseCompatibility("SIDEWAYS"); err == nil {
+			t.Error("Expected an error for an unknown level")
+		}
+	})
+
+	t.Run("Given a subject level, it should override the registry level", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("", CompatibilityNone)
+		_ = r.SetCompatibility("users", CompatibilityForward)
+		if r.Compatibility("users") != CompatibilityForward || r.Compatibility("orders") != CompatibilityNone {
+			t.Errorf("Unexpected levels: %s and %s", r.Compatibility("users"), r.Compatibility("orders"))
+		}
+		if level, ok, err := r.ClearCompatibility("users"); !ok || err != nil || level != CompatibilityForward {
+			t.Errorf("Expected to clear FORWARD, got %s", level)
+		}
+		if r.Compatibility("users") != CompatibilityNone {
+			t.Errorf("Expected the registry level, got %s", r.Compatibility("users"))
+		}
+	})
+
+	t.Run("Given a diff breaking backward compatibility, it should be allowed only by forward levels", func(t *testing.T) {
+		report := schema.Compare(userSchema("id"), userSchema("id", "email"))
+		for level, allowed := range map[Compatibility]bool{
+			CompatibilityNone:     true,
+			CompatibilityForward:  true,
+			CompatibilityBackward: false,
+			CompatibilityFull:     false,
+		} {
+			if level.Allows(report) != allowed {
+				t.Errorf("Expected %s to allow the change: %v", level, allowed)
+			}
+		}
+	})
+}
+
+func TestRegisterText(t *testing.T) {
+	t.Run("Given equal schemas registered from different documents, it should give them different IDs", func(t *testing.T) {
+		r := New()
+		a, _ := r.RegisterText("users", userSchema("id"), `{"a": 1}`)
+		b, _ := r.RegisterText("users", userSchema("id"), `{"b": 1}`)
+		if a.ID == b.ID {
+			t.Errorf("Expected different IDs, got %d", a.ID)
+		}
+		if text, _ := r.TextByID(b.ID); text != `{"b": 1}` {
+			t.Errorf("Expected the registered document, got %q", text)
+		}
+		if found, ok := r.Lookup("users", userSchema("id"), `{"a": 1}`); !ok || found.Version != 1 {
+			t.Errorf("Expected to find version 1, got %+v", found)
+		}
+	})
+}
+
+func TestRegisterCompatibility(t *testing.T) {
+	optional := func(s *schema.Schema, name string) *schema.Schema {
+		s.Fields = append(s.Fields, &schema.Field{Name: name, Type: schema.TypeString})
+		return s
+	}
+
+	t.Run("Given a backward incompatible schema, it should reject it explaining the broken field", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("users", userSchema("id"))
+		_, err := r.Register("users", userSchema("id", "email"))
+
+		incompatible, ok := err.(*IncompatibleError)
+		if !ok {
+			t.Fatalf("Expected an *IncompatibleError, got %v", err)
+		}
+		if incompatible.Level != CompatibilityBackward || len(incompatible.Conflicts) != 1 || incompatible.Conflicts[0].Version != 1 {
+			t.Errorf("Unexpected conflicts: %+v", incompatible)
+		}
+		expected := `version 1: field "email" was added as required, so the new schema cannot read data written with the old one`
+		if messages := incompatible.Messages(); len(messages) != 1 || messages[0] != expected {
+			t.Errorf("Expected %q, got %q", expected, messages)
+		}
+		if versions, _ := r.Versions("users"); len(versions) != 1 {
+			t.Errorf("Expected the schema not to be registered, got versions %v", versions)
+		}
+	})
+
+	t.Run("Given a forward level, it should reject removing a required field", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("users", CompatibilityForward)
+		_, _ = r.Register("users", userSchema("id", "email"))
+		if _, err := r.Register("users", userSchema("id", "email", "name")); err != nil {
+			t.Fatalf("Expected adding a field to be forward compatible, got %v", err)
+		}
+		if _, err := r.Register("users", userSchema("id", "name")); err == nil {
+			t.Error("Expected removing a required field to be rejected")
+		}
+	})
+
+	t.Run("Given a transitive level, it should check every version", func(t *testing.T) {
+		for level, rejected := range map[Compatibility]bool{
+			CompatibilityFull:           false,
+			CompatibilityFullTransitive: true,
+		} {
+			r := New()
+			_ = r.SetCompatibility("", level)
+			_, _ = r.Register("users", userSchema("id"))
+			_, _ = r.Register("users", optional(userSchema("id"), "email"))
+			third := userSchema("id")
+			third.Metadata.Description = "without email"
+			_, _ = r.Register("users", third)
+			// Version 3 dropped the optional email; making it a number only
+			// breaks version 2, which FULL does not check against.
+			s := userSchema("id")
+			s.Fields = append(s.Fields, &schema.Field{Name: "email", Type: schema.TypeInteger})
+			_, err := r.Register("users", s)
+			if (err != nil) != rejected {
+				t.Errorf("Expected %s to reject the schema: %v, got %v", level, rejected, err)
+			}
+		}
+	})
+
+	t.Run("Given the level NONE, it should accept any schema", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("users", CompatibilityNone)
+		_, _ = r.Register("users", userSchema("id"))
+		if _, err := r.Register("users", userSchema("name")); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Given a registered version, it should check a schema against it", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("users", userSchema("id"))
+		if err := r.CheckVersion("users", 1, optional(userSchema("id"), "email")); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if err := r.CheckVersion("users", 1, userSchema("name")); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("Given a store written by another registry, it should load its subjects and levels", func(t *testing.T) {
+		store := storage.NewMemory()
+		first, _ := Open(store)
+		_ = first.SetCompatibility("", CompatibilityNone)
+		_ = first.SetCompatibility("orders", CompatibilityFull)
+		_, _ = first.Register("users", userSchema("id"))
+		_, _ = first.Register("users", userSchema("id", "email"))
+		_, _ = first.Register("accounts", userSchema("id"))
+
+		r, err := Open(store)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if versions, _ := r.Versions("users"); len(versions) != 2 {
+			t.Errorf("Expected 2 versions, got %v", versions)
+		}
+		if account, _ := r.Latest("accounts"); account.ID != 1 {
+			t.Errorf("Expected the shared ID 1, got %d", account.ID)
+		}
+		if r.Compatibility("users") != CompatibilityNone || r.Compatibility("orders") != CompatibilityFull {
+			t.Errorf("Expected the stored levels, got %s and %s", r.Compatibility("users"), r.Compatibility("orders"))
+		}
+		next, _ := r.Register("users", userSchema("id", "name"))
+		if next.Version != 3 || next.ID != 3 {
+			t.Errorf("Expected version 3 with ID 3, got %+v", next)
+		}
+	})
+
+	t.Run("Given the highest ID permanently deleted, it should not issue it again", func(t *testing.T) {
+		store := storage.NewMemory()
+		first, _ := Open(store)
+		_ = first.SetCompatibility("", CompatibilityNone)
+		_, _ = first.Register("users", userSchema("id"))
+		_, _ = first.Register("users", userSchema("id", "email"))
+		_, _ = first.DeleteVersion("users", 2, false)
+		_, _ = first.DeleteVersion("users", 2, true)
+
+		r, err := Open(store)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		next, err := r.Register("users", userSchema("id", "name"))
+		if err != nil || next.ID != 3 {
+			t.Errorf("Expected the new ID 3, got %d (%v)", next.ID, err)
+		}
+	})
+
+	t.Run("Given a cleared level, it should not load it again", func(t *testing.T) {
+		store := storage.NewMemory()
+		first, _ := Open(store)
+		_ = first.SetCompatibility("users", CompatibilityFull)
+		_, _, _ = first.ClearCompatibility("users")
+
+		r, _ := Open(store)
+		if _, ok := r.SubjectCompatibility("users"); ok {
+			t.Error("Expected no level for users")
+		}
+	})
+
+	t.Run("Given a corrupted subject, it should return an error", func(t *testing.T) {
+		store := storage.NewMemory()
+		_ = store.Put("subjects", "users", []byte(`{"version": 1}`))
+		if _, err := Open(store); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+// sharedStore is a store whose changes made by other instances are notified
+// by calling changed.
+type sharedStore struct {
+	*storage.Memory
+	changed func(bucket, key string)
+}
+
+func (s *sharedStore) Watch(fn func(bucket, key string)) {
+	s.changed = fn
+}
+
+func TestFollow(t *testing.T) {
+	t.Run("Given subjects and levels changed by another instance, it should apply them", func(t *testing.T) {
+		store := &sharedStore{Memory: storage.NewMemory()}
+		r, _ := Open(store)
+		other, _ := Open(store.Memory)
+		_ = other.SetCompatibility("", CompatibilityNone)
+		_, _ = other.Register("users", userSchema("id"))
+		_, _ = other.Register("users", userSchema("id", "email"))
+		store.changed("config", "compatibility")
+		store.changed("subjects", "users")
+
+		if versions, _ := r.Versions("users"); len(versions) != 2 {
+			t.Errorf("Expected 2 versions, got %v", versions)
+		}
+		if s, found := r.ByID(2); !found || len(s.Fields) != 2 {
+			t.Errorf("Expected schema 2 to be known, got %+v", s)
+		}
+		if r.Compatibility("users") != CompatibilityNone {
+			t.Errorf("Expected NONE, got %s", r.Compatibility("users"))
+		}
+		if next, _ := r.Register("orders", userSchema("id", "name")); next.ID != 3 {
+			t.Errorf("Expected the next ID to be 3, got %d", next.ID)
+		}
+	})
+
+	t.Run("Given a level removed by another instance, it should fall back to the registry level", func(t *testing.T) {
+		store := &sharedStore{Memory: storage.NewMemory()}
+		r, _ := Open(store)
+		_ = r.SetCompatibility("users", CompatibilityFull)
+		_ = store.Memory.Delete("compatibility", "users")
+		store.changed("compatibility", "users")
+
+		if _, ok := r.SubjectCompatibility("users"); ok {
+			t.Error("Expected no level for users")
+		}
+	})
+}
+
+func TestReferences(t *testing.T) {
+	order := func(version int) *schema.Schema {
+		return &schema.Schema{Name: "Order", Fields: []*schema.Field{
+			{Name: "ID", Type: schema.TypeString, Required: true},
+			{Name: "Buyer", Type: schema.TypeRecord, Required: true, Ref: &schema.Reference{Subject: "customer", Version: version}},
+		}}
+	}
+
+	t.Run("Given a reference to the latest version, it should pin it and resolve its fields", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("customer", userSchema("email"))
+		registered, err := r.Register("orders", order(0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ref := registered.Schema.Field("Buyer").Ref; ref.Version != 1 {
+			t.Errorf("Expected the reference to be pinned to version 1, got %d", ref.Version)
+		}
+		resolved, err := r.Resolve(registered.Schema)
+		if err != nil || resolved.Lookup("Buyer.email") == nil {
+			t.Errorf("Expected Buyer.email to be resolved, got %+v (%v)", resolved, err)
+		}
+	})
+
+	t.Run("Given a reference to a missing version, it should reject the schema", func(t *testing.T) {
+		r := New()
+		if _, err := r.Register("orders", order(3)); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a referenced version breaking compatibility, it should reject the referencing schema", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("customer", CompatibilityNone)
+		_, _ = r.Register("customer", userSchema("email"))
+		_, _ = r.Register("customer", userSchema("email", "phone"))
+		_, _ = r.Register("orders", order(1))
+
+		_, err := r.Register("orders", order(2))
+		incompatible, ok := err.(*IncompatibleError)
+		if !ok || len(incompatible.Conflicts) != 1 || incompatible.Conflicts[0].Changes[0].Path != "Buyer.phone" {
+			t.Errorf("Expected Buyer.phone to break compatibility, got %v", err)
+		}
+	})
+
+	t.Run("Given a referenced version, it should refuse to delete it", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("customer", userSchema("email"))
+		_, _ = r.Register("orders", order(1))
+
+		_, err := r.DeleteVersion("customer", 1, false)
+		referenced, ok := err.(*ReferencedError)
+		if !ok || len(referenced.By) != 1 || referenced.By[0].Subject != "orders" {
+			t.Fatalf("Expected a ReferencedError naming orders, got %v", err)
+		}
+		if _, _, err := r.DeleteSubject("customer", false); err == nil {
+			t.Error("Expected deleting the subject to be refused too")
+		}
+		if deleted, found, err := r.DeleteSubject("orders", false); !found || err != nil || len(deleted) != 1 {
+			t.Fatalf("Expected orders to be deleted, got %v %v (%v)", deleted, found, err)
+		}
+		if found, err := r.DeleteVersion("customer", 1, false); !found || err != nil {
+			t.Errorf("Expected customer version 1 to be deleted, got %v (%v)", found, err)
+		}
+		if subjects := r.Subjects(); len(subjects) != 0 {
+			t.Errorf("Expected no subjects left, got %v", subjects)
+		}
+	})
+
+	t.Run("Given a soft deleted referencing version, it should refuse to delete the referenced one permanently", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("customer", userSchema("email"))
+		_, _ = r.Register("orders", order(1))
+		_, _, _ = r.DeleteSubject("orders", false)
+		_, _ = r.DeleteVersion("customer", 1, false)
+
+		if _, err := r.DeleteVersion("customer", 1, true); err == nil {
+			t.Fatal("Expected the soft deleted orders to keep customer")
+		}
+		if _, _, err := r.DeleteSubject("orders", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if found, err := r.DeleteVersion("customer", 1, true); !found || err != nil {
+			t.Errorf("Expected customer version 1 to be deleted, got %v (%v)", found, err)
+		}
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Run("Given a draft, it should check it but not serve it as the latest version", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("users", userSchema("id"))
+		if _, err := r.RegisterDraft("users", userSchema("id", "email"), ""); err == nil {
+			t.Fatal("Expected an incompatible draft to be rejected")
+		}
+		optional := userSchema("id")
+		optional.Fields = append(optional.Fields, &schema.Field{Name: "nickname", Type: schema.TypeString})
+		draft, err := r.RegisterDraft("users", optional, "")
+		if err != nil || draft.State != StateDraft || draft.Version != 2 {
+			t.Fatalf("Expected draft version 2, got %+v (%v)", draft, err)
+		}
+		if latest, _ := r.Latest("users"); latest.Version != 1 {
+			t.Errorf("Expected version 1 to stay the latest, got %d", latest.Version)
+		}
+
+		if _, _, err := r.SetState("users", 2, StateActive); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if latest, _ := r.Latest("users"); latest.Version != 2 || latest.State != StateActive {
+			t.Errorf("Expected active version 2 to be the latest, got %+v", latest)
+		}
+	})
+
+	t.Run("Given a retired version, it should refuse to move it or reference it", func(t *testing.T) {
+		r := New()
+		_, _ = r.Register("customer", userSchema("email"))
+		var watched []string
+		r.Watch(func(subject string) { watched = append(watched, subject) })
+
+		if version, found, err := r.SetState("customer", 1, StateDeprecated); !found || err != nil || version.State != StateDeprecated {
+			t.Fatalf("Expected customer to be deprecated, got %+v (%v)", version, err)
+		}
+		_, _, _ = r.SetState("customer", 1, StateRetired)
+		_, _, err := r.SetState("customer", 1, StateActive)
+		if transition, ok := err.(*TransitionError); !ok || transition.From != StateRetired {
+			t.Errorf("Expected a TransitionError from retired, got %v", err)
+		}
+		order := &schema.Schema{Fields: []*schema.Field{
+			{Name: "Buyer", Type: schema.TypeRecord, Ref: &schema.Reference{Subject: "customer", Version: 1}},
+		}}
+		if _, err := r.Register("orders", order); err == nil {
+			t.Error("Expected the reference to a retired version to be refused")
+		}
+		if len(watched) != 2 {
+			t.Errorf("Expected two changes to be watched, got %v", watched)
+		}
+	})
+
+	t.Run("Given a soft deleted version, it should hide it until it is deleted permanently", func(t *testing.T) {
+		r := New()
+		_ = r.SetCompatibility("", CompatibilityNone)
+		_, _ = r.Register("users", userSchema("id"))
+		_, _ = r.Register("users", userSchema("id", "email"))
+		if _, err := r.DeleteVersion("users", 2, true); err == nil {
+			t.Fatal("Expected a version to be soft deleted before being deleted permanently")
+		}
+		_, _ = r.DeleteVersion("users", 2, false)
+		if versions, _ := r.Versions("users"); len(versions) != 1 {
+			t.Errorf("Expected version 2 to be hidden, got %v", versions)
+		}
+		if latest, _ := r.Latest("users"); latest.Version != 1 {
+			t.Errorf("Expected version 1 to be the latest, got %d", latest.Version)
+		}
+		third, _ := r.Register("users", userSchema("id", "email"))
+		if third.Version != 3 {
+			t.Errorf("Expected the schema to be registered again as version 3, got %d", third.Version)
+		}
+		if _, ok := r.ByID(2); !ok {
+			t.Error("Expected the ID of the deleted version to keep serving its schema")
+		}
+
+		if found, err := r.DeleteVersion("users", 2, true); !found || err != nil {
+			t.Fatalf("Expected version 2 to be deleted permanently, got %v (%v)", found, err)
+		}
+		if _, _, err := r.DeleteSubject("users", true); err == nil {
+			t.Error("Expected the subject to be soft deleted before being deleted permanently")
+		}
+		_, _, _ = r.DeleteSubject("users", false)
+		if subjects := r.Subjects(); len(subjects) != 0 {
+			t.Errorf("Expected no subjects listed, got %v", subjects)
+		}
+		deleted, found, err := r.DeleteSubject("users", true)
+		if !found || err != nil || len(deleted) != 2 {
+			t.Fatalf("Expected versions 1 and 3 to be deleted, got %v (%v)", deleted, err)
+		}
+		if recreated, _ := r.Register("users", userSchema("name")); recreated.Version != 1 {
+			t.Errorf("Expected the recreated subject to start over, got %d", recreated.Version)
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	r := New()
+	_ = r.SetCompatibility("", CompatibilityNone)
+	_, _ = r.Register("customer", userSchema("email"))
+	_, _ = r.Register("users", userSchema("id"))
+	_, _ = r.Register("users", userSchema("id", "email"))
+	_, _ = r.Register("orders", &schema.Schema{Fields: []*schema.Field{
+		{Name: "Buyer", Type: schema.TypeRecord, Ref: &schema.Reference{Subject: "customer"}},
+	}})
+
+	t.Run("Given a field name, it should find it in every version and referenced schema", func(t *testing.T) {
+		matches := r.Search(schema.FieldQuery{Name: "email"}, false)
+		var found []string
+		for _, match := range matches {
+			found = append(found, fmt.Sprintf("%s/%d/%s", match.Subject, match.Version, match.Path))
+		}
+		expected := []string{"customer/1/email", "orders/1/Buyer.email", "users/2/email"}
+		if !reflect.DeepEqual(found, expected) {
+			t.Errorf("Expected %v, got %v", expected, found)
+		}
+	})
+
+	t.Run("Given latest, it should only search the latest versions", func(t *testing.T) {
+		if matches := r.Search(schema.FieldQuery{Name: "id"}, true); len(matches) != 1 || matches[0].Version != 2 {
+			t.Errorf("Expected version 2 of users, got %+v", matches)
+		}
+	})
+}
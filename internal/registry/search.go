@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"log"
+
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// Match is a field of a registered version found by Search.
+type Match struct {
+	Subject string      `json:"subject"`
+	Version int         `json:"version"`
+	State   State       `json:"state"`
+	Path    string      `json:"path"`
+	Type    schema.Type `json:"type"`
+}
+
+// Search returns the fields matching a query in every version of every
+// subject, or in the latest version of each subject when latest is set, in
+// subject, version and schema order. Referenced schemas are resolved, so
+// their fields are found under the referencing field; soft deleted versions
+// are left out.
+func (r *Registry) Search(query schema.FieldQuery, latest bool) []Match {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matches []Match
+	for _, subject := range r.subjectNames(false) {
+		versions := r.live(subject)
+		if latest {
+			versions = nil
+			if version := r.latest(subject); version != nil {
+				versions = []*Version{version}
+			}
+		}
+		for _, version := range versions {
+			resolved, err := r.resolve(version.Schema)
+			if err != nil {
+				log.Printf("Failed to resolve version %d of %s: %v", version.Version, subject, err)
+				continue
+			}
+			for _, field := range resolved.Find(query) {
+				matches = append(matches, Match{
+					Subject: subject,
+					Version: version.Version,
+					State:   version.State,
+					Path:    field.Path,
+					Type:    field.Type,
+				})
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,211 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/wolfchristopher/thoth/internal/storage"
+)
+
+// Buckets of the store holding the registry state. Each subject is stored
+// with all its versions and compatibility levels are stored by subject, the
+// level of the registry and the next schema ID being kept apart in the config
+// bucket.
+const (
+	subjectsBucket      = "subjects"
+	compatibilityBucket = "compatibility"
+	configBucket        = "config"
+	globalLevel         = "compatibility"
+	nextIDKey           = "next_id"
+)
+
+// Open returns a registry kept in store, loading the subjects and
+// compatibility levels it already holds. When the store is shared with other
+// instances, the registry follows the changes they make.
+func Open(store storage.Store) (*Registry, error) {
+	r := newRegistry(store)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Changes made while loading wait for the lock and are applied after.
+	if watcher, ok := store.(storage.Watcher); ok {
+		watcher.Watch(r.reload)
+	}
+
+	subjects, err := store.List(subjectsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("error loading registry: %v", err)
+	}
+	for subject, data := range subjects {
+		if err := r.loadSubject(subject, data); err != nil {
+			return nil, err
+		}
+	}
+
+	levels, err := store.List(compatibilityBucket)
+	if err != nil {
+		return nil, fmt.Errorf("error loading registry: %v", err)
+	}
+	for subject, data := range levels {
+		if r.levels[subject], err = decodeLevel(subject, data); err != nil {
+			return nil, err
+		}
+	}
+	data, found, err := store.Get(configBucket, globalLevel)
+	if err != nil {
+		return nil, fmt.Errorf("error loading registry: %v", err)
+	}
+	if found {
+		if r.compatibility, err = decodeLevel("the registry", data); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.loadNextID(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadNextID raises the next schema ID to the stored one, so the IDs of
+// permanently deleted versions are never issued again. Stores written before
+// the ID was stored rely on the IDs of the versions they hold.
+func (r *Registry) loadNextID() error {
+	data, found, err := r.store.Get(configBucket, nextIDKey)
+	if err != nil || !found {
+		return err
+	}
+	var id int
+	if err := json.Unmarshal(data, &id); err != nil {
+		return fmt.Errorf("error loading next schema ID: %v", err)
+	}
+	if id > r.nextID {
+		r.nextID = id
+	}
+	return nil
+}
+
+// loadSubject replaces the versions of a subject with the stored ones,
+// indexing the schemas they register. Versions stored without a state were
+// registered before states existed and are active.
+func (r *Registry) loadSubject(subject string, data []byte) error {
+	var versions []*Version
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return fmt.Errorf("error loading subject %s: %v", subject, err)
+	}
+	for _, version := range versions {
+		if version.State == "" {
+			version.State = StateActive
+		}
+		key := digest(version.Schema, version.Text)
+		if id, known := r.digests[key]; known && id == version.ID {
+			version.Schema = r.ids[id].schema
+		} else {
+			r.digests[key] = version.ID
+			r.ids[version.ID] = &registered{schema: version.Schema, text: version.Text}
+		}
+		if version.ID >= r.nextID {
+			r.nextID = version.ID + 1
+		}
+	}
+	r.subjects[subject] = versions
+	return nil
+}
+
+// reload applies a change another instance made to the store. The current
+// value is read again, so changes notified late never undo newer ones.
+func (r *Registry) reload(bucket, key string) {
+	if r.apply(bucket, key) {
+		r.notify(key)
+	}
+}
+
+// apply is reload holding the lock. It reports whether the versions of the
+// subject named by key were reloaded.
+func (r *Registry) apply(bucket, key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case bucket == subjectsBucket:
+		data, found, err := r.store.Get(bucket, key)
+		if err == nil && found {
+			err = r.loadSubject(key, data)
+		} else if err == nil {
+			delete(r.subjects, key)
+		}
+		if err != nil {
+			log.Printf("Failed to reload subject %s: %v", key, err)
+			return false
+		}
+		return true
+	case bucket == configBucket && key == nextIDKey:
+		if err := r.loadNextID(); err != nil {
+			log.Printf("Failed to reload next schema ID: %v", err)
+		}
+	case bucket == compatibilityBucket, bucket == configBucket && key == globalLevel:
+		data, found, err := r.store.Get(bucket, key)
+		level := DefaultCompatibility
+		if err == nil && found {
+			level, err = decodeLevel(key, data)
+		}
+		if err != nil {
+			log.Printf("Failed to reload compatibility of %s: %v", key, err)
+			return false
+		}
+		switch {
+		case bucket == configBucket:
+			r.compatibility = level
+		case found:
+			r.levels[key] = level
+		default:
+			delete(r.levels, key)
+		}
+	}
+	return false
+}
+
+func decodeLevel(owner string, data []byte) (Compatibility, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return "", fmt.Errorf("error loading compatibility of %s: %v", owner, err)
+	}
+	level, err := ParseCompatibility(name)
+	if err != nil {
+		return "", fmt.Errorf("error loading compatibility of %s: %v", owner, err)
+	}
+	return level, nil
+}
+
+// saveSubject stores every version of a subject.
+func (r *Registry) saveSubject(subject string, versions []*Version) error {
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return fmt.Errorf("error encoding subject %s: %v", subject, err)
+	}
+	return r.store.Put(subjectsBucket, subject, data)
+}
+
+// saveNextID stores the next schema ID.
+func (r *Registry) saveNextID(id int) error {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Errorf("error encoding next schema ID: %v", err)
+	}
+	return r.store.Put(configBucket, nextIDKey, data)
+}
+
+// saveLevel stores the level of a subject, or of the registry when subject
+// is empty. An empty level removes the level of the subject.
+func (r *Registry) saveLevel(subject string, level Compatibility) error {
+	bucket, key := compatibilityBucket, subject
+	if subject == "" {
+		bucket, key = configBucket, globalLevel
+	}
+	if level == "" {
+		return r.store.Delete(bucket, key)
+	}
+	data, err := json.Marshal(level)
+	if err != nil {
+		return fmt.Errorf("error encoding compatibility of %s: %v", key, err)
+	}
+	return r.store.Put(bucket, key, data)
+}
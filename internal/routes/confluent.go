@@ -0,0 +1,503 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/codegen"
+	"github.com/wolfchristopher/thoth/internal/registry"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// The handlers of this file implement the subset of the Confluent Schema
+// Registry REST API that serializers use, backed by schemaRegistry. Only
+// AVRO schemas are accepted; their references name the Avro records defined
+// by other subjects.
+
+// confluentContentType is the media type of Confluent Schema Registry
+// responses.
+const confluentContentType = "application/vnd.schemaregistry.v1+json"
+
+// Error codes of Confluent Schema Registry.
+const (
+	errorSubjectNotFound      = 40401
+	errorVersionNotFound      = 40402
+	errorSchemaNotFound       = 40403
+	errorSubjectNotDeleted    = 40405
+	errorVersionNotDeleted    = 40407
+	errorSubjectLevelNotFound = 40408
+	errorIncompatibleSchema   = 409
+	errorInvalidSchema        = 42201
+	errorInvalidVersion       = 42202
+	errorInvalidCompatibility = 42203
+	errorReferenceExists      = 42206
+)
+
+// confluentSchema is the body posted to register, look up or test a schema.
+type confluentSchema struct {
+	Schema     string             `json:"schema"`
+	SchemaType string             `json:"schemaType,omitempty"`
+	References []schema.Reference `json:"references,omitempty"`
+}
+
+// confluentVersion is a registered version as returned by the API.
+type confluentVersion struct {
+	Subject    string             `json:"subject"`
+	ID         int                `json:"id"`
+	Version    int                `json:"version"`
+	Schema     string             `json:"schema"`
+	SchemaType string             `json:"schemaType,omitempty"`
+	References []schema.Reference `json:"references,omitempty"`
+}
+
+// confluentSchemaByID is a schema returned by its ID.
+type confluentSchemaByID struct {
+	Schema     string             `json:"schema"`
+	References []schema.Reference `json:"references,omitempty"`
+}
+
+type confluentError struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func writeConfluent(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", confluentContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+func writeConfluentError(w http.ResponseWriter, status, code int, message string) {
+	writeConfluent(w, status, confluentError{ErrorCode: code, Message: message})
+}
+
+// pathSegments splits the path below prefix into unescaped segments, so
+// subjects may contain escaped slashes.
+func pathSegments(r *http.Request, prefix string) ([]string, error) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+// schemaText returns the Avro document of a registered schema: the one it was
+// registered with, or else the Avro export of the resolved schema.
+func schemaText(s *schema.Schema, text string) (string, error) {
+	if text != "" {
+		return text, nil
+	}
+	resolved, err := schemaRegistry.Resolve(s)
+	if err != nil {
+		return "", err
+	}
+	avro, err := codegen.Avro(resolved)
+	return string(avro), err
+}
+
+// decodeConfluentSchema reads and parses the schema posted in a request.
+func decodeConfluentSchema(r *http.Request) (confluentSchema, *schema.Schema, error) {
+	var posted confluentSchema
+	if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+		return posted, nil, fmt.Errorf("invalid request body: %v", err)
+	}
+	if posted.SchemaType != "" && !strings.EqualFold(posted.SchemaType, "AVRO") {
+		return posted, nil, fmt.Errorf("schema type %s is not supported", posted.SchemaType)
+	}
+	for i, ref := range posted.References {
+		if ref.Name == "" || ref.Subject == "" {
+			return posted, nil, fmt.Errorf("reference %d needs a name and a subject", i)
+		}
+		if ref.Version == -1 {
+			posted.References[i].Version = 0
+		}
+	}
+	parsed, err := schema.ParseAvroReferences([]byte(posted.Schema), posted.References)
+	return posted, parsed, err
+}
+
+// resolveConfluentVersion returns the version of a subject named by a path
+// segment: a number, "latest" or -1. It writes the error response when the
+// version does not exist.
+func resolveConfluentVersion(w http.ResponseWriter, subject, segment string) (registry.Version, bool) {
+	if _, found := schemaRegistry.Versions(subject); !found {
+		writeConfluentError(w, http.StatusNotFound, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", subject))
+		return registry.Version{}, false
+	}
+	if segment == "latest" || segment == "-1" {
+		version, found := schemaRegistry.Latest(subject)
+		if !found {
+			writeConfluentError(w, http.StatusNotFound, errorVersionNotFound, "Version latest not found.")
+		}
+		return version, found
+	}
+	number, err := strconv.Atoi(segment)
+	if err != nil || number < 1 {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidVersion,
+			fmt.Sprintf("The specified version '%s' is not a valid version id. Allowed values are between [1, 2^31-1] and the string \"latest\"", segment))
+		return registry.Version{}, false
+	}
+	version, found := schemaRegistry.Version(subject, number)
+	if !found {
+		writeConfluentError(w, http.StatusNotFound, errorVersionNotFound, fmt.Sprintf("Version %d not found.", number))
+		return registry.Version{}, false
+	}
+	return version, true
+}
+
+func confluentVersionOf(version registry.Version) (confluentVersion, error) {
+	text, err := schemaText(version.Schema, version.Text)
+	return confluentVersion{
+		Subject:    version.Subject,
+		ID:         version.ID,
+		Version:    version.Version,
+		Schema:     text,
+		References: version.Schema.References(),
+	}, err
+}
+
+// ConfluentSubjectsHandler serves "/subjects" and the paths below it:
+// listing subjects and versions, registering a schema, fetching a version,
+// its bare schema or the IDs of the schemas referencing it, looking up the
+// version of a schema, and deleting subjects and versions no other schema
+// references, softly unless permanent=true.
+func ConfluentSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r, "/subjects")
+	if err != nil {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		writeConfluent(w, http.StatusOK, schemaRegistry.Subjects())
+	case len(segments) == 1 && r.Method == http.MethodPost:
+		lookupConfluentSchema(w, r, segments[0])
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		deleted, found, err := schemaRegistry.DeleteSubject(segments[0], r.URL.Query().Get("permanent") == "true")
+		if !found && err == nil {
+			writeConfluentError(w, http.StatusNotFound, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", segments[0]))
+			return
+		}
+		writeConfluentDeletion(w, r, deleted, err)
+	case len(segments) == 2 && segments[1] == "versions" && r.Method == http.MethodGet:
+		versions, found := schemaRegistry.Versions(segments[0])
+		if !found {
+			writeConfluentError(w, http.StatusNotFound, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", segments[0]))
+			return
+		}
+		writeConfluent(w, http.StatusOK, versions)
+	case len(segments) == 2 && segments[1] == "versions" && r.Method == http.MethodPost:
+		registerConfluentSchema(w, r, segments[0])
+	case len(segments) == 3 && segments[1] == "versions" && r.Method == http.MethodGet:
+		version, ok := resolveConfluentVersion(w, segments[0], segments[2])
+		if !ok {
+			return
+		}
+		response, err := confluentVersionOf(version)
+		if err != nil {
+			http.Error(w, "Failed to export schema", http.StatusInternalServerError)
+			return
+		}
+		writeConfluent(w, http.StatusOK, response)
+	case len(segments) == 3 && segments[1] == "versions" && r.Method == http.MethodDelete:
+		deleteConfluentVersion(w, r, segments[0], segments[2])
+	case len(segments) == 4 && segments[1] == "versions" && segments[3] == "referencedby" && r.Method == http.MethodGet:
+		version, ok := resolveConfluentVersion(w, segments[0], segments[2])
+		if !ok {
+			return
+		}
+		ids := []int{}
+		for _, referencing := range schemaRegistry.ReferencedBy(version.Subject, version.Version) {
+			ids = append(ids, referencing.ID)
+		}
+		writeConfluent(w, http.StatusOK, ids)
+	case len(segments) == 4 && segments[1] == "versions" && segments[3] == "schema" && r.Method == http.MethodGet:
+		version, ok := resolveConfluentVersion(w, segments[0], segments[2])
+		if !ok {
+			return
+		}
+		text, err := schemaText(version.Schema, version.Text)
+		if err != nil {
+			http.Error(w, "Failed to export schema", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", confluentContentType)
+		if _, err := w.Write([]byte(text)); err != nil {
+			log.Printf("Failed to write schema response: %v", err)
+		}
+	case len(segments) <= 4:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func registerConfluentSchema(w http.ResponseWriter, r *http.Request, subject string) {
+	posted, parsed, err := decodeConfluentSchema(r)
+	if err != nil {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidSchema, fmt.Sprintf("Invalid schema: %v", err))
+		return
+	}
+	version, err := schemaRegistry.RegisterText(subject, parsed, posted.Schema)
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if incompatible, ok := err.(*registry.IncompatibleError); ok {
+		writeConfluentError(w, http.StatusConflict, errorIncompatibleSchema, fmt.Sprintf(
+			"Schema being registered is incompatible with an earlier schema for subject \"%s\", details: [%s]",
+			subject, strings.Join(incompatible.Messages(), ", ")))
+		return
+	}
+	if err != nil {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidSchema, err.Error())
+		return
+	}
+	log.Printf("Registered schema %s version %d with id %d\n", version.Subject, version.Version, version.ID)
+	writeConfluent(w, http.StatusOK, map[string]int{"id": version.ID})
+}
+
+// deleteConfluentVersion deletes a version of a subject named by a path
+// segment. Soft deleted versions are only found by their number, to be
+// deleted permanently.
+func deleteConfluentVersion(w http.ResponseWriter, r *http.Request, subject, segment string) {
+	if r.URL.Query().Get("permanent") != "true" {
+		version, ok := resolveConfluentVersion(w, subject, segment)
+		if !ok {
+			return
+		}
+		_, err := schemaRegistry.DeleteVersion(version.Subject, version.Version, false)
+		writeConfluentDeletion(w, r, version.Version, err)
+		return
+	}
+	number, err := strconv.Atoi(segment)
+	if err != nil || number < 1 {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidVersion,
+			fmt.Sprintf("The specified version '%s' is not a valid version id. Allowed values are between [1, 2^31-1]", segment))
+		return
+	}
+	found, err := schemaRegistry.DeleteVersion(subject, number, true)
+	if !found && err == nil {
+		writeConfluentError(w, http.StatusNotFound, errorVersionNotFound, fmt.Sprintf("Version %d not found.", number))
+		return
+	}
+	writeConfluentDeletion(w, r, number, err)
+}
+
+// writeConfluentDeletion answers a deletion with what was deleted, or with
+// the error preventing it.
+func writeConfluentDeletion(w http.ResponseWriter, r *http.Request, deleted interface{}, err error) {
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if referenced, ok := err.(*registry.ReferencedError); ok {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorReferenceExists,
+			fmt.Sprintf("One or more references exist to the schema {%s}", referenced.Error()))
+		return
+	}
+	if notDeleted, ok := err.(*registry.NotSoftDeletedError); ok {
+		code := errorVersionNotDeleted
+		if notDeleted.Version == 0 {
+			code = errorSubjectNotDeleted
+		}
+		writeConfluentError(w, http.StatusNotFound, code, notDeleted.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete schema: %v", err)
+		http.Error(w, "Failed to delete schema", http.StatusInternalServerError)
+		return
+	}
+	writeConfluent(w, http.StatusOK, deleted)
+}
+
+func lookupConfluentSchema(w http.ResponseWriter, r *http.Request, subject string) {
+	if _, found := schemaRegistry.Versions(subject); !found {
+		writeConfluentError(w, http.StatusNotFound, errorSubjectNotFound, fmt.Sprintf("Subject '%s' not found.", subject))
+		return
+	}
+	posted, parsed, err := decodeConfluentSchema(r)
+	if err != nil {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidSchema, fmt.Sprintf("Invalid schema: %v", err))
+		return
+	}
+	version, found := schemaRegistry.Lookup(subject, parsed, posted.Schema)
+	if !found {
+		writeConfluentError(w, http.StatusNotFound, errorSchemaNotFound, "Schema not found")
+		return
+	}
+	response, err := confluentVersionOf(version)
+	if err != nil {
+		http.Error(w, "Failed to export schema", http.StatusInternalServerError)
+		return
+	}
+	writeConfluent(w, http.StatusOK, response)
+}
+
+// ConfluentSchemaIDsHandler serves "/schemas/ids/{id}", returning the schema
+// registered with an ID.
+func ConfluentSchemaIDsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	segments, err := pathSegments(r, "/schemas/ids")
+	if err != nil || len(segments) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(segments[0])
+	if err != nil {
+		writeConfluentError(w, http.StatusNotFound, errorSchemaNotFound, "Schema not found")
+		return
+	}
+	found, ok := schemaRegistry.ByID(id)
+	if !ok {
+		writeConfluentError(w, http.StatusNotFound, errorSchemaNotFound, "Schema not found")
+		return
+	}
+	text, _ := schemaRegistry.TextByID(id)
+	if text, err = schemaText(found, text); err != nil {
+		http.Error(w, "Failed to export schema", http.StatusInternalServerError)
+		return
+	}
+	writeConfluent(w, http.StatusOK, confluentSchemaByID{Schema: text, References: found.References()})
+}
+
+// confluentConfig is the body of "/config" requests and responses. Updates
+// send Compatibility, reads return CompatibilityLevel.
+type confluentConfig struct {
+	Compatibility      registry.Compatibility `json:"compatibility,omitempty"`
+	CompatibilityLevel registry.Compatibility `json:"compatibilityLevel,omitempty"`
+}
+
+// ConfluentConfigHandler serves "/config" and "/config/{subject}", reading
+// and changing the compatibility level of the registry or of a subject.
+func ConfluentConfigHandler(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r, "/config")
+	if err != nil || len(segments) > 1 {
+		http.NotFound(w, r)
+		return
+	}
+	subject := ""
+	if len(segments) == 1 {
+		subject = segments[0]
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		level, ok := schemaRegistry.SubjectCompatibility(subject)
+		if subject == "" || !ok && r.URL.Query().Get("defaultToGlobal") == "true" {
+			level, ok = schemaRegistry.Compatibility(subject), true
+		}
+		if !ok {
+			writeConfluentError(w, http.StatusNotFound, errorSubjectLevelNotFound,
+				fmt.Sprintf("Subject '%s' does not have subject-level compatibility configured", subject))
+			return
+		}
+		writeConfluent(w, http.StatusOK, confluentConfig{CompatibilityLevel: level})
+	case http.MethodPut:
+		var config confluentConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidCompatibility, "Invalid compatibility level")
+			return
+		}
+		level, err := registry.ParseCompatibility(string(config.Compatibility))
+		if err == nil {
+			err = schemaRegistry.SetCompatibility(subject, level)
+		}
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidCompatibility, fmt.Sprintf("Invalid compatibility level: %v", err))
+			return
+		}
+		writeConfluent(w, http.StatusOK, confluentConfig{Compatibility: level})
+	case http.MethodDelete:
+		if subject == "" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		level, ok, err := schemaRegistry.ClearCompatibility(subject)
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to clear compatibility level: %v", err)
+			http.Error(w, "Failed to store compatibility level", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			writeConfluentError(w, http.StatusNotFound, errorSubjectLevelNotFound,
+				fmt.Sprintf("Subject '%s' does not have subject-level compatibility configured", subject))
+			return
+		}
+		writeConfluent(w, http.StatusOK, confluentConfig{Compatibility: level})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// compatibilityResult is the response of "/compatibility" requests; Messages
+// explains the breaking changes when "verbose" is true.
+type compatibilityResult struct {
+	IsCompatible bool     `json:"is_compatible"`
+	Messages     []string `json:"messages,omitempty"`
+}
+
+// ConfluentCompatibilityHandler serves
+// "/compatibility/subjects/{subject}/versions/{version}", testing whether a
+// schema may follow a registered version under the subject's compatibility
+// level, and "/compatibility/subjects/{subject}/versions", testing it against
+// the versions the level checks on registration.
+func ConfluentCompatibilityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	segments, err := pathSegments(r, "/compatibility")
+	if err != nil || len(segments) < 3 || len(segments) > 4 || segments[0] != "subjects" || segments[2] != "versions" {
+		http.NotFound(w, r)
+		return
+	}
+	subject := segments[1]
+	var version registry.Version
+	if len(segments) == 4 {
+		var ok bool
+		if version, ok = resolveConfluentVersion(w, subject, segments[3]); !ok {
+			return
+		}
+	}
+	_, parsed, err := decodeConfluentSchema(r)
+	if err != nil {
+		writeConfluentError(w, http.StatusUnprocessableEntity, errorInvalidSchema, fmt.Sprintf("Invalid schema: %v", err))
+		return
+	}
+
+	if len(segments) == 4 {
+		err = schemaRegistry.CheckVersion(subject, version.Version, parsed)
+	} else {
+		err = schemaRegistry.Check(subject, parsed)
+	}
+	result := compatibilityResult{IsCompatible: err == nil}
+	if incompatible, ok := err.(*registry.IncompatibleError); ok && r.URL.Query().Get("verbose") == "true" {
+		result.Messages = incompatible.Messages()
+	}
+	writeConfluent(w, http.StatusOK, result)
+}
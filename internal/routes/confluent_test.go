@@ -0,0 +1,363 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/registry"
+	"github.com/wolfchristopher/thoth/internal/schema"
+)
+
+// avroRequest returns the body of a Confluent register request for an Avro
+// record with the given fields.
+func avroRequest(fields string) *bytes.Buffer {
+	document := fmt.Sprintf(`{"type": "record", "name": "User", "fields": [%s]}`, fields)
+	body, _ := json.Marshal(map[string]string{"schema": document})
+	return bytes.NewBuffer(body)
+}
+
+func TestConfluentSubjectsHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	const idField = `{"name": "id", "type": "string"}`
+	const emailField = `{"name": "email", "type": ["null", "string"], "default": null}`
+
+	var registered map[string]int
+	t.Run("RegisterSchema", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", avroRequest(idField)))
+
+		if w.Result().StatusCode != http.StatusOK || w.Header().Get("Content-Type") != confluentContentType {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &registered); err != nil || registered["id"] == 0 {
+			t.Fatalf("Expected an id, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("RegisterSameSchemaAgain", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", avroRequest(idField)))
+
+		if strings.TrimSpace(w.Body.String()) != fmt.Sprintf(`{"id":%d}`, registered["id"]) {
+			t.Errorf("Expected the same id, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("ListSubjectsAndVersions", func(t *testing.T) {
+		ConfluentSubjectsHandler(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", avroRequest(idField+","+emailField)))
+
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects", nil))
+		if strings.TrimSpace(w.Body.String()) != `["users-value"]` {
+			t.Errorf("Unexpected subjects: %s", w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/users-value/versions", nil))
+		if strings.TrimSpace(w.Body.String()) != `[1,2]` {
+			t.Errorf("Unexpected versions: %s", w.Body.String())
+		}
+	})
+
+	t.Run("GetVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/users-value/versions/1", nil))
+
+		var version confluentVersion
+		if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if version.ID != registered["id"] || version.Version != 1 || !strings.Contains(version.Schema, `"name": "User"`) {
+			t.Errorf("Unexpected version: %+v", version)
+		}
+	})
+
+	t.Run("GetLatestSchema", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/users-value/versions/latest/schema", nil))
+
+		if !strings.Contains(w.Body.String(), `"email"`) {
+			t.Errorf("Expected the latest schema text, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("LookupSchema", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value", avroRequest(idField)))
+
+		if !strings.Contains(w.Body.String(), `"version":1`) {
+			t.Errorf("Expected version 1, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("UnknownSubject", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/orders/versions/1", nil))
+
+		if w.Result().StatusCode != http.StatusNotFound || !strings.Contains(w.Body.String(), `"error_code":40401`) {
+			t.Errorf("Expected error 40401, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/users-value/versions/9", nil))
+
+		if w.Result().StatusCode != http.StatusNotFound || !strings.Contains(w.Body.String(), `"error_code":40402`) {
+			t.Errorf("Expected error 40402, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"schema": "{\"type\": \"record\", \"name\": \"R\", \"fields\": [{\"name\": \"a\", \"type\": \"Missing\"}]}"}`)
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", body))
+
+		if w.Result().StatusCode != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"error_code":42201`) {
+			t.Errorf("Expected error 42201, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("UnsupportedSchemaType", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"schema": "syntax = \"proto3\";", "schemaType": "PROTOBUF"}`)
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", body))
+
+		if w.Result().StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestConfluentSchemaIDsHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	native, _ := schemaRegistry.Register("users", &schema.Schema{
+		Name:   "User",
+		Fields: []*schema.Field{{Name: "id", Type: schema.TypeString, Required: true}},
+	})
+
+	t.Run("SchemaRegisteredNatively", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSchemaIDsHandler(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", native.ID), nil))
+
+		var response map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if !strings.Contains(response["schema"], `"type": "record"`) {
+			t.Errorf("Expected an Avro export, got %s", response["schema"])
+		}
+	})
+
+	t.Run("UnknownID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSchemaIDsHandler(w, httptest.NewRequest(http.MethodGet, "/schemas/ids/99", nil))
+
+		if w.Result().StatusCode != http.StatusNotFound || !strings.Contains(w.Body.String(), `"error_code":40403`) {
+			t.Errorf("Expected error 40403, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+}
+
+func TestConfluentConfigHandler(t *testing.T) {
+	SetRegistry(registry.New())
+
+	t.Run("GlobalDefault", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentConfigHandler(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+		if strings.TrimSpace(w.Body.String()) != `{"compatibilityLevel":"BACKWARD"}` {
+			t.Errorf("Unexpected config: %s", w.Body.String())
+		}
+	})
+
+	t.Run("SetSubjectLevel", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentConfigHandler(w, httptest.NewRequest(http.MethodPut, "/config/users-value", bytes.NewBufferString(`{"compatibility": "FULL"}`)))
+
+		if strings.TrimSpace(w.Body.String()) != `{"compatibility":"FULL"}` {
+			t.Errorf("Unexpected response: %s", w.Body.String())
+		}
+		if level := schemaRegistry.Compatibility("users-value"); level != registry.CompatibilityFull {
+			t.Errorf("Expected FULL, got %s", level)
+		}
+	})
+
+	t.Run("SubjectWithoutLevel", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentConfigHandler(w, httptest.NewRequest(http.MethodGet, "/config/orders-value", nil))
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentConfigHandler(w, httptest.NewRequest(http.MethodGet, "/config/orders-value?defaultToGlobal=true", nil))
+		if !strings.Contains(w.Body.String(), "BACKWARD") {
+			t.Errorf("Expected the global level, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("InvalidLevel", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentConfigHandler(w, httptest.NewRequest(http.MethodPut, "/config", bytes.NewBufferString(`{"compatibility": "SIDEWAYS"}`)))
+
+		if w.Result().StatusCode != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"error_code":42203`) {
+			t.Errorf("Expected error 42203, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+}
+
+func TestConfluentCompatibilityHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	ConfluentSubjectsHandler(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", avroRequest(`{"name": "id", "type": "string"}`)))
+
+	t.Run("CompatibleSchema", func(t *testing.T) {
+		body := avroRequest(`{"name": "id", "type": "string"}, {"name": "email", "type": ["null", "string"], "default": null}`)
+		w := httptest.NewRecorder()
+		ConfluentCompatibilityHandler(w, httptest.NewRequest(http.MethodPost, "/compatibility/subjects/users-value/versions/latest", body))
+
+		if strings.TrimSpace(w.Body.String()) != `{"is_compatible":true}` {
+			t.Errorf("Expected a compatible schema, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("FieldWithDefault", func(t *testing.T) {
+		body := avroRequest(`{"name": "id", "type": "string"}, {"name": "currency", "type": "string", "default": "USD"}`)
+		w := httptest.NewRecorder()
+		ConfluentCompatibilityHandler(w, httptest.NewRequest(http.MethodPost, "/compatibility/subjects/users-value/versions/latest", body))
+
+		if strings.TrimSpace(w.Body.String()) != `{"is_compatible":true}` {
+			t.Errorf("Expected a field with a default to be compatible, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("AgainstAllCheckedVersions", func(t *testing.T) {
+		body := avroRequest(`{"name": "id", "type": "long"}`)
+		w := httptest.NewRecorder()
+		ConfluentCompatibilityHandler(w, httptest.NewRequest(http.MethodPost, "/compatibility/subjects/users-value/versions?verbose=true", body))
+
+		if !strings.Contains(w.Body.String(), `"is_compatible":false`) || !strings.Contains(w.Body.String(), "changed type from string to integer") {
+			t.Errorf("Expected the type change to be reported, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("RegisterIncompatibleSchema", func(t *testing.T) {
+		body := avroRequest(`{"name": "id", "type": "string"}, {"name": "email", "type": "string"}`)
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/users-value/versions", body))
+
+		if w.Result().StatusCode != http.StatusConflict || !strings.Contains(w.Body.String(), `"error_code":409`) ||
+			!strings.Contains(w.Body.String(), `field \"email\" was added as required`) {
+			t.Errorf("Expected error 409, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("IncompatibleSchema", func(t *testing.T) {
+		body := avroRequest(`{"name": "id", "type": "string"}, {"name": "email", "type": "string"}`)
+		w := httptest.NewRecorder()
+		ConfluentCompatibilityHandler(w, httptest.NewRequest(http.MethodPost, "/compatibility/subjects/users-value/versions/1?verbose=true", body))
+
+		var result compatibilityResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if result.IsCompatible || len(result.Messages) != 1 || !strings.Contains(result.Messages[0], "email") {
+			t.Errorf("Expected email to break compatibility, got %+v", result)
+		}
+	})
+}
+
+func TestConfluentReferences(t *testing.T) {
+	SetRegistry(registry.New())
+	customer, _ := json.Marshal(map[string]string{
+		"schema": `{"type": "record", "name": "Customer", "namespace": "com.example", "fields": [{"name": "email", "type": "string"}]}`,
+	})
+	ConfluentSubjectsHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/subjects/customer/versions", bytes.NewBuffer(customer)))
+	order, _ := json.Marshal(map[string]interface{}{
+		"schema":     `{"type": "record", "name": "Order", "namespace": "com.example", "fields": [{"name": "buyer", "type": "Customer"}]}`,
+		"references": []map[string]interface{}{{"name": "com.example.Customer", "subject": "customer", "version": 1}},
+	})
+
+	t.Run("RegisterReferencingSchema", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/orders-value/versions", bytes.NewBuffer(order)))
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/orders-value/versions/1", nil))
+		var version confluentVersion
+		if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(version.References) != 1 || version.References[0].Name != "com.example.Customer" || version.References[0].Version != 1 {
+			t.Errorf("Expected the reference to be returned, got %+v", version.References)
+		}
+	})
+
+	t.Run("ReferencedBy", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/subjects/customer/versions/1/referencedby", nil))
+		if strings.TrimSpace(w.Body.String()) != `[2]` {
+			t.Errorf("Expected the id of orders-value, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("DeleteReferencedVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/customer/versions/1", nil))
+		if w.Result().StatusCode != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"error_code":42206`) {
+			t.Errorf("Expected error 42206, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("DeleteSubjects", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/orders-value", nil))
+		if strings.TrimSpace(w.Body.String()) != `[1]` {
+			t.Errorf("Expected version 1 to be deleted, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/customer/versions/latest", nil))
+		if strings.TrimSpace(w.Body.String()) != `1` {
+			t.Errorf("Expected version 1 to be deleted, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("DeletePermanently", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/customer/versions/1?permanent=true", nil))
+		if w.Result().StatusCode != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"error_code":42206`) {
+			t.Errorf("Expected the soft deleted orders-value to keep customer, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/orders-value?permanent=true", nil))
+		if strings.TrimSpace(w.Body.String()) != `[1]` {
+			t.Errorf("Expected version 1 to be deleted, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodDelete, "/subjects/customer/versions/1?permanent=true", nil))
+		if strings.TrimSpace(w.Body.String()) != `1` {
+			t.Errorf("Expected version 1 to be deleted, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("UnknownReference", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ConfluentSubjectsHandler(w, httptest.NewRequest(http.MethodPost, "/subjects/orders-value/versions", bytes.NewBuffer(order)))
+		if w.Result().StatusCode != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+}
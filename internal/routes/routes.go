@@ -3,35 +3,144 @@ package routes
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/wolfchristopher/thoth/internal/codegen"
 	"github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/mapping"
+	"github.com/wolfchristopher/thoth/internal/profile"
+	"github.com/wolfchristopher/thoth/internal/registry"
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type KafkaConfig struct {
-	Brokers          string `json:"brokers"`
-	GroupID          string `json:"group_id"`
-	PreServiceTopic  string `json:"pre_service_topic"`
-	PostServiceTopic string `json:"post_service_topic"`
-	SecurityProtocol string `json:"security_protocol,omitempty"`
-	SASLMechanism    string `json:"sasl_mechanism,omitempty"`
-	SASLUsername     string `json:"sasl_username,omitempty"`
-	SASLPassword     string `json:"sasl_password,omitempty"`
-	SSLCaLocation    string `json:"ssl_ca_location,omitempty"`
-	AutoOffsetReset  string `json:"auto_offset_reset,omitempty"`
+	Brokers          string   `json:"brokers"`
+	GroupID          string   `json:"group_id"`
+	Topics           []string `json:"topics,omitempty"`
+	PreServiceTopic  string   `json:"pre_service_topic"`
+	PostServiceTopic string   `json:"post_service_topic"`
+	SecurityProtocol string   `json:"security_protocol,omitempty"`
+	SASLMechanism    string   `json:"sasl_mechanism,omitempty"`
+	SASLUsername     string   `json:"sasl_username,omitempty"`
+	SASLPassword     string   `json:"sasl_password,omitempty"`
+	SSLCaLocation    string   `json:"ssl_ca_location,omitempty"`
+	AutoOffsetReset  string   `json:"auto_offset_reset,omitempty"`
+	DriftTopic       string   `json:"drift_topic,omitempty"`
+	EnumThreshold    int      `json:"enum_threshold,omitempty"`
+	MaskedFields     []string `json:"masked_fields,omitempty"`
 }
 
-var currentConfig KafkaConfig
+// currentConfig is the Kafka config in effect. configMu guards it and
+// serializes applying it, as it changes on HTTP requests and on changes made
+// by other instances to the state store.
+var (
+	configMu      sync.Mutex
+	currentConfig KafkaConfig
+)
+
+// driftDetector receives the drift topic and brokers of updated Kafka
+// configs. driftConnection is the connection of its writer, made by
+// connectDriftWriter.
+var (
+	driftDetector      *kafka.DriftDetector
+	driftConnection    *kafka.Connection
+	connectDriftWriter = func(connection kafka.Connection) (kafka.KafkaWriter, error) {
+		return connection.NewWriter()
+	}
+)
+
+// SetDriftDetector makes config updates apply their drift topic and brokers
+// to detector, and connects it to the brokers of the current config.
+func SetDriftDetector(detector *kafka.DriftDetector) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	driftDetector, driftConnection = detector, nil
+	if detector != nil {
+		connectDriftDetector()
+	}
+}
+
+// connectDriftDetector gives the drift detector a writer for the brokers of
+// the current config when they changed; the caller holds configMu.
+func connectDriftDetector() {
+	connection := kafkaConnection(currentConfig)
+	if driftConnection != nil && reflect.DeepEqual(connection, *driftConnection) {
+		return
+	}
+	writer, err := connectDriftWriter(connection)
+	if err != nil {
+		log.Printf("Failed to connect drift detector: %v", err)
+		return
+	}
+	driftDetector.SetWriter(writer)
+	driftConnection = &connection
+}
+
+// consumer reads the topics of the Kafka config and the topics bound for
+// validation.
+var consumer *kafka.Consumer
+
+// SetConsumer makes config updates apply their topics, group and brokers to
+// c, and bindings their topic, and applies those of the current config.
+func SetConsumer(c *kafka.Consumer) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	consumer = c
+	consumer.SetTopics(consumedTopics(currentConfig)...)
+	consumer.SetConnection(kafkaConnection(currentConfig), currentConfig.GroupID)
+}
+
+// defaultTopics are consumed while the config names no topics.
+var defaultTopics = []string{"transactions"}
+
+// consumedTopics returns the topics of config, or defaultTopics, along with
+// the bound topics.
+func consumedTopics(config KafkaConfig) []string {
+	topics := config.Topics
+	if len(topics) == 0 {
+		topics = defaultTopics
+	}
+	return append(append([]string(nil), topics...), schemaValidator.Topics()...)
+}
+
+// followBindings makes the consumer read the topics bound after a binding
+// changed.
+func followBindings() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if consumer != nil {
+		consumer.SetTopics(consumedTopics(currentConfig)...)
+	}
+}
 
 func UpdateKafkaConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
+		configMu.Lock()
+		defer configMu.Unlock()
+		config := currentConfig
 		decoder := json.NewDecoder(r.Body)
-		err := decoder.Decode(&currentConfig)
+		err := decoder.Decode(&config)
 		if err != nil {
 			http.Error(w, "Invalid config format", http.StatusBadRequest)
 			return
 		}
-		_, err = fmt.Fprintf(w, "Kafka config updated: %+v\n", currentConfig)
+		err = saveState(serviceBucket, kafkaConfigKey, withoutSecrets(config))
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to store Kafka config: %v", err)
+			http.Error(w, "Failed to store config", http.StatusInternalServerError)
+			return
+		}
+		currentConfig = config
+		applyKafkaConfig()
+		_, err = fmt.Fprintf(w, "Kafka config updated: %+v\n", withoutSecrets(currentConfig))
 		if err != nil {
 			return
 		}
@@ -40,27 +149,127 @@ func UpdateKafkaConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// applyKafkaConfig passes the current config on to the consumer, the drift
+// detector, the schema observer and the mapping pipeline; the caller holds
+// configMu.
+func applyKafkaConfig() {
+	if consumer != nil {
+		consumer.SetTopics(consumedTopics(currentConfig)...)
+		consumer.SetConnection(kafkaConnection(currentConfig), currentConfig.GroupID)
+	}
+	if driftDetector != nil {
+		if currentConfig.DriftTopic != "" {
+			driftDetector.SetDriftTopic(currentConfig.DriftTopic)
+		}
+		connectDriftDetector()
+	}
+	if mappingPipeline != nil {
+		mappingPipeline.SetTopics(currentConfig.PreServiceTopic, currentConfig.PostServiceTopic)
+		mappingPipeline.SetConnection(kafkaConnection(currentConfig))
+	}
+	if currentConfig.EnumThreshold > 0 {
+		schemaObserver.SetEnumThreshold(currentConfig.EnumThreshold)
+	}
+	schemaObserver.SetMaskedFields(currentConfig.MaskedFields)
+}
+
+// defaultBroker is connected to while the config names no brokers.
+const defaultBroker = "localhost:9092"
+
+// kafkaConnection returns the comma separated brokers of config and how to
+// connect to them.
+func kafkaConnection(config KafkaConfig) kafka.Connection {
+	var brokers []string
+	for _, broker := range strings.Split(config.Brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		brokers = []string{defaultBroker}
+	}
+	return kafka.Connection{
+		Brokers:          brokers,
+		SecurityProtocol: config.SecurityProtocol,
+		SASLMechanism:    config.SASLMechanism,
+		SASLUsername:     config.SASLUsername,
+		SASLPassword:     config.SASLPassword,
+		SSLCaLocation:    config.SSLCaLocation,
+	}
+}
+
+// schemaRegistry stores the schemas posted to "/schema".
+var schemaRegistry = registry.New()
+
+// SetRegistry makes the routes register and serve schemas with the given
+// registry, and warn about messages matching its deprecated versions.
+func SetRegistry(r *registry.Registry) {
+	schemaRegistry = r
+	r.Watch(refreshDeprecations)
+	for _, subject := range r.Subjects() {
+		refreshDeprecations(subject)
+	}
+}
+
+// defaultSubject holds the schemas posted to "/schema" without a subject or
+// a name.
+const defaultSubject = "default"
+
+// ReceiveSchemaHandler registers the posted schema document under the subject
+// given in the query, or else under the schema's qualified name or
+// defaultSubject. With state=draft the version is registered as a draft.
+// Schemas breaking the compatibility level of the subject are rejected with a
+// conflict listing the broken fields.
 func ReceiveSchemaHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var schema map[string]interface{}
-
-	err := json.NewDecoder(r.Body).Decode(&schema)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("Failed to decode JSON response: %v", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	received, err := schema.Parse(body)
+	if err != nil {
+		log.Printf("Failed to decode schema: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Received schema: %+v\n", schema)
-
-	schema = kafka.MapSchema(schema)
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		subject = qualifiedName(received)
+	}
+	if subject == "" {
+		subject = defaultSubject
+	}
+	register := schemaRegistry.RegisterText
+	switch r.URL.Query().Get("state") {
+	case "", string(registry.StateActive):
+	case string(registry.StateDraft):
+		register = schemaRegistry.RegisterDraft
+	default:
+		http.Error(w, "Schemas are registered as draft or active", http.StatusBadRequest)
+		return
+	}
+	version, err := register(subject, received, "")
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if incompatible, ok := err.(*registry.IncompatibleError); ok {
+		http.Error(w, incompatible.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	log.Printf("Schema Mapped: %+v\n", schema)
+	log.Printf("Registered schema %s version %d with id %d\n", version.Subject, version.Version, version.ID)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(map[string]string{"message": "Schema received successfully"})
 	if err != nil {
@@ -69,3 +278,907 @@ func ReceiveSchemaHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// qualifiedName returns the name of a schema prefixed with its namespace.
+func qualifiedName(s *schema.Schema) string {
+	if s.Namespace != "" && s.Name != "" {
+		return s.Namespace + "." + s.Name
+	}
+	return s.Name
+}
+
+// SubjectsHandler lists the subjects of the registry.
+func SubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schemaRegistry.Subjects()); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// lookupVersion returns the version of a subject given as a number or as
+// "latest".
+func lookupVersion(subject, version string) (registry.Version, bool) {
+	if version == "latest" {
+		return schemaRegistry.Latest(subject)
+	}
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		return registry.Version{}, false
+	}
+	return schemaRegistry.Version(subject, number)
+}
+
+// VersionsHandler lists the versions of the subject given in the query, or
+// returns a single version when "version" is a number or "latest". DELETE
+// soft deletes the version, or the whole subject without "version", unless
+// other schemas reference it; with permanent=true it removes soft deleted
+// versions for good.
+func VersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		deleteVersions(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var response interface{}
+	if query.Get("version") == "" {
+		versions, found := schemaRegistry.Versions(query.Get("subject"))
+		if !found {
+			http.Error(w, "Subject not found", http.StatusNotFound)
+			return
+		}
+		response = versions
+	} else {
+		version, found := lookupVersion(query.Get("subject"), query.Get("version"))
+		if !found {
+			http.Error(w, "Version not found", http.StatusNotFound)
+			return
+		}
+		response = version
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// deleteVersions deletes the version or subject given in the query and
+// returns the numbers of the deleted versions.
+func deleteVersions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subject := query.Get("subject")
+	permanent := query.Get("permanent") == "true"
+	var deleted []int
+	var found bool
+	var err error
+	if query.Get("version") == "" {
+		deleted, found, err = schemaRegistry.DeleteSubject(subject, permanent)
+	} else {
+		version, parseErr := strconv.Atoi(query.Get("version"))
+		if parseErr != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		found, err = schemaRegistry.DeleteVersion(subject, version, permanent)
+		deleted = []int{version}
+	}
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if referenced, ok := err.(*registry.ReferencedError); ok {
+		http.Error(w, referenced.Error(), http.StatusConflict)
+		return
+	}
+	if notDeleted, ok := err.(*registry.NotSoftDeletedError); ok {
+		http.Error(w, notDeleted.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete schema: %v", err)
+		http.Error(w, "Failed to delete schema", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deleted); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// SchemaByIDHandler returns the schema registered with the ID given in the
+// query.
+func SchemaByIDHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid schema id", http.StatusBadRequest)
+		return
+	}
+	found, ok := schemaRegistry.ByID(id)
+	if !ok {
+		http.Error(w, "Schema not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(found); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// metadataResponse is the governance metadata of a registered version.
+type metadataResponse struct {
+	Subject  string                 `json:"subject"`
+	Version  int                    `json:"version"`
+	Metadata schema.Metadata        `json:"metadata"`
+	Fields   []schema.FieldMetadata `json:"fields"`
+}
+
+// MetadataHandler returns the owners, tags and description of the version of
+// the subject given in the query (the latest unless "version" is a number),
+// with the doc, tags and classification of its fields, those of referenced
+// schemas included. With "classification" only the fields classified so are
+// listed.
+func MetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	number := query.Get("version")
+	if number == "" {
+		number = "latest"
+	}
+	var classification schema.Classification
+	if query.Get("classification") != "" {
+		var err error
+		if classification, err = schema.ParseClassification(query.Get("classification")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	version, found := lookupVersion(query.Get("subject"), number)
+	if !found {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+	resolved, err := schemaRegistry.Resolve(version.Schema)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := metadataResponse{Subject: version.Subject, Version: version.Version, Metadata: resolved.Metadata, Fields: []schema.FieldMetadata{}}
+	for _, field := range resolved.FieldMetadata() {
+		if classification == "" || field.Classification == classification {
+			response.Fields = append(response.Fields, field)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// searchResult is a field found by SearchHandler, either in a version of a
+// registered subject or in the schema inferred for an observed topic.
+type searchResult struct {
+	Source  string         `json:"source"`
+	Subject string         `json:"subject,omitempty"`
+	Version int            `json:"version,omitempty"`
+	State   registry.State `json:"state,omitempty"`
+	Topic   string         `json:"topic,omitempty"`
+	Path    string         `json:"path"`
+	Type    schema.Type    `json:"type"`
+}
+
+// SearchHandler finds the fields matching the "name", "type" and "tag" given
+// in the query in every registered version (only the latest of each subject
+// with latest=true) and in the schemas inferred from observed topics. With
+// source=registry or source=observed only one of them is searched.
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	fieldQuery := schema.FieldQuery{Name: query.Get("name"), Tag: query.Get("tag")}
+	if query.Get("type") != "" {
+		t, err := schema.ParseType(query.Get("type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fieldQuery.Type = t
+	}
+	if fieldQuery == (schema.FieldQuery{}) {
+		http.Error(w, "Search needs a name, type or tag", http.StatusBadRequest)
+		return
+	}
+	source := query.Get("source")
+	if source != "" && source != "registry" && source != "observed" {
+		http.Error(w, "Source must be registry or observed", http.StatusBadRequest)
+		return
+	}
+
+	results := []searchResult{}
+	if source != "observed" {
+		for _, match := range schemaRegistry.Search(fieldQuery, query.Get("latest") == "true") {
+			results = append(results, searchResult{
+				Source:  "registry",
+				Subject: match.Subject,
+				Version: match.Version,
+				State:   match.State,
+				Path:    match.Path,
+				Type:    match.Type,
+			})
+		}
+	}
+	if source != "registry" {
+		for _, topic := range schemaObserver.Topics() {
+			observed := schemaObserver.Schema(topic)
+			if observed == nil {
+				continue
+			}
+			for _, match := range observed.Find(fieldQuery) {
+				results = append(results, searchResult{Source: "observed", Topic: topic, Path: match.Path, Type: match.Type})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// exporters renders a schema in each format supported by "/schema/export".
+var exporters = map[string]func(*schema.Schema) ([]byte, error){
+	"avro": codegen.Avro,
+	"proto": func(s *schema.Schema) ([]byte, error) {
+		return exportProto(qualifiedName(s), s)
+	},
+	"go": codegen.GoStructs,
+	"sqlite": func(s *schema.Schema) ([]byte, error) {
+		return codegen.SQL(s, codegen.DialectSQLite)
+	},
+	"postgres": func(s *schema.Schema) ([]byte, error) {
+		return codegen.SQL(s, codegen.DialectPostgres)
+	},
+	"typescript": codegen.TypeScript,
+	"openapi":    codegen.OpenAPI,
+	"xsd":        codegen.XSD,
+	"jsonschema": codegen.JSONSchema,
+}
+
+// schemaObserver holds the schemas accumulated from consumed messages.
+var schemaObserver = kafka.NewSchemaObserver()
+
+// SetSchemaObserver makes the routes serve the schemas accumulated by the
+// given observer.
+func SetSchemaObserver(observer *kafka.SchemaObserver) {
+	schemaObserver = observer
+}
+
+// ObservedSchemaHandler returns the schema accumulated from the messages of
+// the topic given in the query, with example values sampled for each field.
+func ObservedSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	observed := schemaObserver.Schema(r.URL.Query().Get("topic"))
+	if observed == nil {
+		http.Error(w, "No messages observed on topic", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(observed); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// enumDecision confirms or rejects the enum proposed for a field of a topic.
+type enumDecision struct {
+	Topic  string            `json:"topic"`
+	Path   string            `json:"path"`
+	Status schema.EnumStatus `json:"status"`
+}
+
+// EnumSuggestionsHandler lists the enums proposed for the string fields of the
+// topic given in the query on GET, and records a confirmation or rejection
+// posted as an enumDecision on POST.
+func EnumSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	switch r.Method {
+	case http.MethodGet:
+		suggestions := schemaObserver.EnumSuggestions(r.URL.Query().Get("topic"))
+		if suggestions == nil {
+			http.Error(w, "No messages observed on topic", http.StatusNotFound)
+			return
+		}
+		response = suggestions
+	case http.MethodPost:
+		var decision enumDecision
+		if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+			http.Error(w, "Invalid enum decision", http.StatusBadRequest)
+			return
+		}
+		suggestion, err := schemaObserver.DecideEnum(decision.Topic, decision.Path, decision.Status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = suggestion
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// protoNumbers keeps the protobuf field numbers handed out per subject so
+// that regenerating a .proto file for the same subject stays wire compatible.
+var (
+	protoNumbers   = map[string]*codegen.FieldNumbers{}
+	protoNumbersMu sync.Mutex
+)
+
+// exportProto renders a schema as a .proto file numbered with the field
+// numbers kept under key, the registry subject or the qualified name of the
+// schema. Numbers are only stored, and kept, when the export hands out new
+// ones, so exporting an unchanged schema writes nothing; schemas without a
+// key are numbered from scratch.
+func exportProto(key string, s *schema.Schema) ([]byte, error) {
+	protoNumbersMu.Lock()
+	defer protoNumbersMu.Unlock()
+	known, ok := protoNumbers[key]
+	numbers := codegen.NewFieldNumbers()
+	if ok {
+		numbers = known.Clone()
+	}
+	data, err := codegen.Proto(s, numbers)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" || ok && reflect.DeepEqual(known, numbers) {
+		return data, nil
+	}
+	if err := saveState(protoNumbersBucket, key, numbers); err != nil {
+		return nil, err
+	}
+	protoNumbers[key] = numbers
+	return data, nil
+}
+
+// ExportSchemaHandler renders a schema in the format given by the "format"
+// query parameter. The body is a sample message whose schema is inferred, or
+// a schema document as accepted by "/schema" when "source" is "schema". With
+// "source" set to "topic" the schema accumulated from the messages consumed
+// from the "topic" query parameter is exported instead, and with "subject"
+// the "version" (or latest version) of the "subject" query parameter.
+// References to registered schemas are resolved before exporting.
+func ExportSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	source := query.Get("source")
+	if r.Method != http.MethodPost && !(r.Method == http.MethodGet && (source == "topic" || source == "subject")) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	export, ok := exporters[query.Get("format")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unsupported export format %q", query.Get("format")), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var exported *schema.Schema
+	var subject string
+	switch source {
+	case "", "sample":
+		exported, err = kafka.InferSchema(query.Get("name"), body)
+	case "schema":
+		exported, err = schema.Parse(body)
+	case "topic":
+		if exported = schemaObserver.Schema(query.Get("topic")); exported == nil {
+			http.Error(w, fmt.Sprintf("No messages observed on topic %q", query.Get("topic")), http.StatusNotFound)
+			return
+		}
+	case "subject":
+		number := query.Get("version")
+		if number == "" {
+			number = "latest"
+		}
+		version, found := lookupVersion(query.Get("subject"), number)
+		if !found {
+			http.Error(w, "Version not found", http.StatusNotFound)
+			return
+		}
+		exported = version.Schema
+		subject = version.Subject
+	default:
+		err = fmt.Errorf("unsupported schema source %q", source)
+	}
+	if err == nil {
+		exported, err = schemaRegistry.Resolve(exported)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := query.Get("name"); name != "" {
+		exported.Name = name
+	}
+	if namespace := query.Get("namespace"); namespace != "" {
+		exported.Namespace = namespace
+	}
+
+	var output []byte
+	if query.Get("format") == "proto" && subject != "" {
+		output, err = exportProto(subject, exported)
+	} else {
+		output, err = export(exported)
+	}
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to export schema: %v", err)
+		http.Error(w, "Failed to export schema", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(output); err != nil {
+		log.Printf("Failed to write export response: %v", err)
+	}
+}
+
+// schemaCatalog holds the distinct schemas mapped by the consumer.
+var schemaCatalog = kafka.DefaultCatalog
+
+// SchemaFingerprintsHandler lists the distinct schemas seen by the consumer
+// with their fingerprints and counts. A "fingerprint" query parameter selects
+// a single schema.
+func SchemaFingerprintsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response interface{} = schemaCatalog.Entries()
+	if fingerprint := r.URL.Query().Get("fingerprint"); fingerprint != "" {
+		entry, found := schemaCatalog.Get(fingerprint)
+		if !found {
+			http.Error(w, "Schema not found", http.StatusNotFound)
+			return
+		}
+		response = entry
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// schemaRef points at a schema to diff: an inline schema document, the
+// schema inferred from the messages consumed from a topic, or a version of a
+// registered subject, the latest when Version is zero.
+type schemaRef struct {
+	Schema  json.RawMessage `json:"schema,omitempty"`
+	Topic   string          `json:"topic,omitempty"`
+	Subject string          `json:"subject,omitempty"`
+	Version int             `json:"version,omitempty"`
+}
+
+type diffRequest struct {
+	Old schemaRef `json:"old"`
+	New schemaRef `json:"new"`
+}
+
+// resolveSchema returns the schema a schemaRef designates, with its
+// references to registered schemas resolved.
+func resolveSchema(ref schemaRef) (*schema.Schema, error) {
+	var s *schema.Schema
+	switch {
+	case len(ref.Schema) > 0:
+		parsed, err := schema.Parse(ref.Schema)
+		if err != nil {
+			return nil, err
+		}
+		s = parsed
+	case ref.Topic != "":
+		if s = schemaObserver.Schema(ref.Topic); s == nil {
+			return nil, fmt.Errorf("no messages observed on topic %q", ref.Topic)
+		}
+	case ref.Subject != "":
+		version, found := schemaRegistry.Latest(ref.Subject)
+		if ref.Version != 0 {
+			version, found = schemaRegistry.Version(ref.Subject, ref.Version)
+		}
+		if !found {
+			return nil, fmt.Errorf("version %d of subject %q not found", ref.Version, ref.Subject)
+		}
+		s = version.Schema
+	default:
+		return nil, fmt.Errorf("schema reference needs a schema, a topic or a subject")
+	}
+	return schemaRegistry.Resolve(s)
+}
+
+// DiffSchemaHandler diffs the "old" and "new" schemas of the request and
+// classifies every change as breaking or not for backward and forward
+// compatibility.
+func DiffSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request diffRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid diff request", http.StatusBadRequest)
+		return
+	}
+	oldSchema, err := resolveSchema(request.Old)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("old: %v", err), http.StatusBadRequest)
+		return
+	}
+	newSchema, err := resolveSchema(request.New)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("new: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schema.Compare(oldSchema, newSchema)); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// fieldProfiler holds the field statistics gathered from consumed messages.
+var fieldProfiler = profile.NewProfiler()
+
+// SetProfiler makes the routes serve the statistics gathered by the given
+// profiler.
+func SetProfiler(profiler *profile.Profiler) {
+	fieldProfiler = profiler
+}
+
+// ProfileHandler returns the field profiles of the topic given in the query,
+// or only the profile of the field at the "field" path when given.
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("topic") == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(fieldProfiler.Topics()); err != nil {
+			log.Printf("Failed to encode JSON response: %v", err)
+		}
+		return
+	}
+
+	profiles := fieldProfiler.Profiles(query.Get("topic"))
+	if profiles == nil {
+		http.Error(w, "No profile for topic", http.StatusNotFound)
+		return
+	}
+	var response interface{} = profiles
+	if path := query.Get("field"); path != "" {
+		response = nil
+		for _, p := range profiles {
+			if p.Path == path {
+				response = p
+			}
+		}
+		if response == nil {
+			http.Error(w, "No profile for field", http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// schemaValidator checks consumed messages against the schemas bound to their
+// topics.
+var schemaValidator = kafka.NewSchemaValidator()
+
+// SetSchemaValidator makes the routes bind schemas with the given validator.
+func SetSchemaValidator(validator *kafka.SchemaValidator) {
+	schemaValidator = validator
+	for _, subject := range schemaRegistry.Subjects() {
+		refreshDeprecations(subject)
+	}
+	followBindings()
+}
+
+// refreshDeprecations passes the deprecated and retired versions of a subject
+// on to the schema validator, which warns about messages still matching them.
+func refreshDeprecations(subject string) {
+	var deprecations []kafka.Deprecation
+	numbers, _ := schemaRegistry.Versions(subject)
+	for _, number := range numbers {
+		version, found := schemaRegistry.Version(subject, number)
+		if !found || version.State != registry.StateDeprecated && version.State != registry.StateRetired {
+			continue
+		}
+		resolved, err := schemaRegistry.Resolve(version.Schema)
+		if err != nil {
+			log.Printf("Failed to resolve version %d of %s: %v", number, subject, err)
+			continue
+		}
+		deprecations = append(deprecations, kafka.Deprecation{Version: number, State: string(version.State), Schema: resolved})
+	}
+	schemaValidator.Deprecate(subject, deprecations)
+}
+
+// bindingRequest binds a version of a registered subject to a topic, the
+// latest when Version is zero.
+type bindingRequest struct {
+	Topic   string `json:"topic"`
+	Subject string `json:"subject"`
+	Version int    `json:"version,omitempty"`
+}
+
+// stateRequest moves a version of a subject to a lifecycle state.
+type stateRequest struct {
+	Subject string         `json:"subject"`
+	Version int            `json:"version"`
+	State   registry.State `json:"state"`
+}
+
+// StateHandler moves the version of a subject posted as a stateRequest to its
+// lifecycle state and returns the changed version. Moves the current state of
+// the version does not allow are rejected with a conflict.
+func StateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request stateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid state request", http.StatusBadRequest)
+		return
+	}
+	state, err := registry.ParseState(string(request.State))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	version, found, err := schemaRegistry.SetState(request.Subject, request.Version, state)
+	if redirectToLeader(w, r, err) {
+		return
+	}
+	if transition, ok := err.(*registry.TransitionError); ok {
+		http.Error(w, transition.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to change state: %v", err)
+		http.Error(w, "Failed to change state", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(version); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// ValidationHandler returns the validation report of the topic given in the
+// query, or of every bound topic, on GET; binds a registered schema to a
+// topic posted as a bindingRequest on POST; and unbinds the topic given in
+// the query on DELETE.
+func ValidationHandler(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	switch r.Method {
+	case http.MethodGet:
+		topic := r.URL.Query().Get("topic")
+		if topic == "" {
+			response = schemaValidator.Reports()
+			break
+		}
+		report, found := schemaValidator.Report(topic)
+		if !found {
+			http.Error(w, "No schema bound to topic", http.StatusNotFound)
+			return
+		}
+		response = report
+	case http.MethodPost:
+		var request bindingRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Topic == "" {
+			http.Error(w, "Invalid binding", http.StatusBadRequest)
+			return
+		}
+		version, found := schemaRegistry.Latest(request.Subject)
+		if request.Version != 0 {
+			version, found = schemaRegistry.Version(request.Subject, request.Version)
+		}
+		if !found {
+			http.Error(w, "Version not found", http.StatusNotFound)
+			return
+		}
+		if version.State == registry.StateRetired {
+			http.Error(w, fmt.Sprintf("Version %d of %s is retired", version.Version, version.Subject), http.StatusConflict)
+			return
+		}
+		resolved, err := schemaRegistry.Resolve(version.Schema)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stored := bindingRequest{Topic: request.Topic, Subject: version.Subject, Version: version.Version}
+		err = saveState(bindingsBucket, request.Topic, stored)
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to store binding: %v", err)
+			http.Error(w, "Failed to store binding", http.StatusInternalServerError)
+			return
+		}
+		binding := kafka.Binding{Topic: request.Topic, Subject: version.Subject, Version: version.Version, Schema: resolved}
+		if err := schemaValidator.Bind(binding); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		followBindings()
+		response = binding
+	case http.MethodDelete:
+		topic := r.URL.Query().Get("topic")
+		if _, found := schemaValidator.Report(topic); !found {
+			http.Error(w, "No schema bound to topic", http.StatusNotFound)
+			return
+		}
+		err := stateStore.Delete(bindingsBucket, topic)
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to delete binding: %v", err)
+			http.Error(w, "Failed to delete binding", http.StatusInternalServerError)
+			return
+		}
+		schemaValidator.Unbind(topic)
+		followBindings()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// mappingPipeline maps the messages of the pre-service topic to the
+// post-service topic.
+var mappingPipeline *kafka.MappingPipeline
+
+// SetMappingPipeline makes config updates apply their service topics and
+// brokers to pipeline and "/mapping" configure its mapping.
+func SetMappingPipeline(pipeline *kafka.MappingPipeline) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	mappingPipeline = pipeline
+	mappingPipeline.SetTopics(currentConfig.PreServiceTopic, currentConfig.PostServiceTopic)
+	mappingPipeline.SetConnection(kafkaConnection(currentConfig))
+}
+
+// mappingResponse is the mapping of the pipeline and its status.
+type mappingResponse struct {
+	Mapping *mapping.Spec        `json:"mapping"`
+	Status  kafka.PipelineStatus `json:"status"`
+}
+
+// MappingHandler returns the mapping of the pipeline and its status on GET,
+// replaces the mapping with the YAML or JSON spec posted on POST or PUT, and
+// removes it, stopping the pipeline, on DELETE.
+func MappingHandler(w http.ResponseWriter, r *http.Request) {
+	if mappingPipeline == nil {
+		http.Error(w, "Mapping pipeline not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		spec, err := mapping.Parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = saveState(serviceBucket, mappingKey, spec)
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to store mapping: %v", err)
+			http.Error(w, "Failed to store mapping", http.StatusInternalServerError)
+			return
+		}
+		mappingPipeline.SetMapping(spec)
+	case http.MethodDelete:
+		if mappingPipeline.Mapping() == nil {
+			http.Error(w, "No mapping configured", http.StatusNotFound)
+			return
+		}
+		err := stateStore.Delete(serviceBucket, mappingKey)
+		if redirectToLeader(w, r, err) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to delete mapping: %v", err)
+			http.Error(w, "Failed to delete mapping", http.StatusInternalServerError)
+			return
+		}
+		mappingPipeline.SetMapping(nil)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := mappingResponse{Mapping: mappingPipeline.Mapping(), Status: mappingPipeline.Status()}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
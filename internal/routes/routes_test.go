@@ -2,12 +2,22 @@ package routes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/profile"
+	"github.com/wolfchristopher/thoth/internal/registry"
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"github.com/wolfchristopher/thoth/internal/storage"
 )
 
 func TestUpdateKafkaConfig(t *testing.T) {
@@ -65,6 +75,7 @@ func TestUpdateKafkaConfig(t *testing.T) {
 }
 
 func TestReceiveSchemaHandler(t *testing.T) {
+	SetRegistry(registry.New())
 	t.Run("ValidSchema", func(t *testing.T) {
 		validSchema := map[string]interface{}{
 			"fields": []interface{}{
@@ -108,6 +119,59 @@ func TestReceiveSchemaHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("DefaultSubject", func(t *testing.T) {
+		version, found := schemaRegistry.Latest(defaultSubject)
+		if !found || version.Schema.Field("username") == nil {
+			t.Errorf("Expected the schema under %s, got %+v", defaultSubject, version)
+		}
+	})
+
+	t.Run("ExplicitSubject", func(t *testing.T) {
+		body := []byte(`{"name": "User", "fields": [{"name": "username", "type": "string", "required": true}]}`)
+		req := httptest.NewRequest(http.MethodPost, "/schema?subject=users", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		ReceiveSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		version, found := schemaRegistry.Latest("users")
+		if !found || version.Version != 1 || version.ID == 0 || version.Schema.Field("username") == nil {
+			t.Errorf("Expected version 1 of subject users, got %+v", version)
+		}
+		if _, found := schemaRegistry.Latest("User"); found {
+			t.Error("Expected the subject to take precedence over the schema name")
+		}
+	})
+
+	t.Run("SubjectFromSchemaName", func(t *testing.T) {
+		body := []byte(`{"name": "Order", "namespace": "com.example", "fields": [{"name": "id", "type": "string"}]}`)
+		req := httptest.NewRequest(http.MethodPost, "/schema", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		ReceiveSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		if _, found := schemaRegistry.Latest("com.example.Order"); !found {
+			t.Error("Expected subject com.example.Order")
+		}
+	})
+
+	t.Run("IncompatibleSchema", func(t *testing.T) {
+		body := []byte(`{"fields": [{"name": "username", "type": "integer", "required": true}]}`)
+		req := httptest.NewRequest(http.MethodPost, "/schema?subject=users", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		ReceiveSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusConflict || !strings.Contains(w.Body.String(), `field "username" changed type from string to integer`) {
+			t.Errorf("Expected a conflict explaining the type change, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
 	t.Run("InvalidSchemaFormat", func(t *testing.T) {
 		invalidJSON := []byte("invalid json")
 
@@ -134,3 +198,932 @@ func TestReceiveSchemaHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestExportSchemaHandler(t *testing.T) {
+	t.Run("AvroFromXMLSample", func(t *testing.T) {
+		sample := []byte(`<Transaction><ID>abc</ID><Amount>99.99</Amount></Transaction>`)
+
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=avro&namespace=com.example", bytes.NewBuffer(sample))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		res := w.Result()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v", res.StatusCode)
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if record["name"] != "Transaction" || record["namespace"] != "com.example" {
+			t.Errorf("Expected com.example.Transaction, got %v.%v", record["namespace"], record["name"])
+		}
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=cobol", bytes.NewBufferString(`{"a": 1}`))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("InvalidSample", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=avro", bytes.NewBufferString("not a message"))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/export?format=avro", nil)
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestExportProto(t *testing.T) {
+	export := func(sample string) string {
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=proto&name=Order", bytes.NewBufferString(sample))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v", w.Result().StatusCode)
+		}
+		return w.Body.String()
+	}
+
+	t.Run("StableNumbersAcrossExports", func(t *testing.T) {
+		export(`{"id": "1", "total": 2.5}`)
+		proto := export(`{"id": "1", "note": "x", "total": 2.5}`)
+
+		for _, expected := range []string{"string id = 1;", "double total = 2;", "string note = 3;"} {
+			if !strings.Contains(proto, expected) {
+				t.Errorf("Expected %q in:\n%s", expected, proto)
+			}
+		}
+	})
+
+	t.Run("UnnamedSample", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ExportSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema/export?format=proto", bytes.NewBufferString(`{"a": 1}`)))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "int64 a = 1;") {
+			t.Errorf("Expected the unnamed sample to export, got %v: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("NumbersKeyedBySubject", func(t *testing.T) {
+		SetRegistry(registry.New())
+		ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=orders-value",
+			bytes.NewBufferString(`{"name": "Order", "fields": [{"name": "id", "type": "string"}]}`)))
+		w := httptest.NewRecorder()
+		ExportSchemaHandler(w, httptest.NewRequest(http.MethodGet, "/schema/export?format=proto&source=subject&subject=orders-value", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v", w.Code)
+		}
+		if _, ok := protoNumbers["orders-value"]; !ok {
+			t.Errorf("Expected the numbers kept under the subject, got %+v", protoNumbers)
+		}
+	})
+
+	t.Run("ReadOnlyExport", func(t *testing.T) {
+		previous := stateStore
+		stateStore = refusingStore{previous}
+		defer func() { stateStore = previous }()
+
+		if proto := export(`{"id": "1", "note": "x", "total": 2.5}`); !strings.Contains(proto, "string note = 3;") {
+			t.Errorf("Expected an unchanged numbering to export without storing, got:\n%s", proto)
+		}
+		w := httptest.NewRecorder()
+		ExportSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema/export?format=proto&name=Order", bytes.NewBufferString(`{"id": "1", "extra": true}`)))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected new numbers to need the leader, got %v", w.Code)
+		}
+		if _, ok := protoNumbers["Order"].Messages["Order"]["extra"]; ok {
+			t.Error("Expected numbers that were not stored to be dropped")
+		}
+	})
+}
+
+// refusingStore refuses writes as a store of an instance that does not lead.
+type refusingStore struct {
+	storage.Store
+}
+
+func (refusingStore) Put(bucket, key string, value []byte) error {
+	return &storage.NotLeaderError{}
+}
+
+func TestExportGoStructs(t *testing.T) {
+	t.Run("GoFromRegisteredSchema", func(t *testing.T) {
+		definition := []byte(`{"fields": [
+			{"name": "username", "type": "string", "required": true},
+			{"name": "age", "type": "integer", "required": false}
+		]}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=go&source=schema&name=User", bytes.NewBuffer(definition))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		for _, expected := range []string{"type User struct {", "Age      *int"} {
+			if !strings.Contains(w.Body.String(), expected) {
+				t.Errorf("Expected %q in:\n%s", expected, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("InvalidSchemaDocument", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/schema/export?format=go&source=schema", bytes.NewBufferString(`{"fields": []}`))
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestExportObservedTopic(t *testing.T) {
+	observer := kafka.NewSchemaObserver()
+	SetSchemaObserver(observer)
+	if err := observer.Observe("transactions", []byte(`<Transaction><ID>1</ID></Transaction>`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("XSDFromObservedTopic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/export?format=xsd&source=topic&topic=transactions", nil)
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v", w.Result().StatusCode)
+		}
+		if !strings.Contains(w.Body.String(), `<xs:element name="Transaction">`) {
+			t.Errorf("Expected a Transaction element in:\n%s", w.Body.String())
+		}
+	})
+
+	t.Run("UnknownTopic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/export?format=xsd&source=topic&topic=missing", nil)
+		w := httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestSchemaFingerprintsHandler(t *testing.T) {
+	schemaCatalog = kafka.NewSchemaCatalog()
+	fingerprint, _, _ := schemaCatalog.Add("orders", map[string]interface{}{"id": "string"})
+	schemaCatalog.Add("orders", map[string]interface{}{"id": "string"})
+
+	t.Run("ListFingerprints", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/fingerprints", nil)
+		w := httptest.NewRecorder()
+
+		SchemaFingerprintsHandler(w, req)
+
+		var entries []kafka.CatalogEntry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Fingerprint != fingerprint || entries[0].Count != 2 {
+			t.Errorf("Unexpected entries: %+v", entries)
+		}
+	})
+
+	t.Run("UnknownFingerprint", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/fingerprints?fingerprint=abc", nil)
+		w := httptest.NewRecorder()
+
+		SchemaFingerprintsHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+type recordingWriter struct {
+	topics []string
+}
+
+func (r *recordingWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	for _, msg := range msgs {
+		r.topics = append(r.topics, msg.Topic)
+	}
+	return nil
+}
+
+func (r *recordingWriter) Close() error {
+	return nil
+}
+
+func TestUpdateKafkaConfigDriftTopic(t *testing.T) {
+	writer := &recordingWriter{}
+	var connected []kafka.Connection
+	defer func(connect func(kafka.Connection) (kafka.KafkaWriter, error)) { connectDriftWriter = connect }(connectDriftWriter)
+	connectDriftWriter = func(connection kafka.Connection) (kafka.KafkaWriter, error) {
+		connected = append(connected, connection)
+		return writer, nil
+	}
+	SetDriftDetector(kafka.NewDriftDetector(nil, kafka.DefaultDriftTopic))
+	defer SetDriftDetector(nil)
+
+	jsonData, _ := json.Marshal(KafkaConfig{Brokers: "broker:9092", DriftTopic: "orders-drift"})
+	req := httptest.NewRequest(http.MethodPost, "/kafka_config", bytes.NewBuffer(jsonData))
+	w := httptest.NewRecorder()
+
+	UpdateKafkaConfig(w, req)
+
+	if err := driftDetector.Publish(context.Background(), []kafka.DriftEvent{{Topic: "orders"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(writer.topics) != 1 || writer.topics[0] != "orders-drift" {
+		t.Errorf("Expected the event on orders-drift, got %v", writer.topics)
+	}
+	if len(connected) == 0 || !reflect.DeepEqual(connected[len(connected)-1].Brokers, []string{"broker:9092"}) {
+		t.Errorf("Expected the drift writer to be connected to the posted brokers, got %+v", connected)
+	}
+}
+
+func TestUpdateKafkaConfigTopics(t *testing.T) {
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	c := kafka.NewConsumer(kafka.ParseMessage, kafka.MapSchema)
+	SetConsumer(c)
+	defer func() { consumer = nil }()
+
+	jsonData, _ := json.Marshal(KafkaConfig{Brokers: "broker:9092", Topics: []string{"orders", "payments"}})
+	UpdateKafkaConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/kafka_config", bytes.NewBuffer(jsonData)))
+
+	if topics := c.Topics(); !reflect.DeepEqual(topics, []string{"orders", "payments"}) {
+		t.Errorf("Expected the posted topics to be consumed, got %v", topics)
+	}
+}
+
+func TestValidationHandlerConsumesBoundTopics(t *testing.T) {
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	c := kafka.NewConsumer(kafka.ParseMessage, kafka.MapSchema)
+	SetConsumer(c)
+	defer func() { consumer = nil }()
+	_, _ = schemaRegistry.Register("audit-value", &schema.Schema{Fields: []*schema.Field{{Name: "ID", Type: schema.TypeString}}})
+
+	ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/validation",
+		bytes.NewBufferString(`{"topic": "audit", "subject": "audit-value"}`)))
+	if !slices.Contains(c.Topics(), "audit") {
+		t.Errorf("Expected the bound topic to be consumed, got %v", c.Topics())
+	}
+
+	ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/validation?topic=audit", nil))
+	if slices.Contains(c.Topics(), "audit") {
+		t.Errorf("Expected the unbound topic not to be consumed, got %v", c.Topics())
+	}
+}
+
+func TestDiffSchemaHandler(t *testing.T) {
+	t.Run("InlineSchemas", func(t *testing.T) {
+		body := []byte(`{
+			"old": {"schema": {"fields": [{"name": "id", "type": "string", "required": true}]}},
+			"new": {"schema": {"fields": [
+				{"name": "id", "type": "string", "required": true},
+				{"name": "age", "type": "integer", "required": true}
+			]}}
+		}`)
+		req := httptest.NewRequest(http.MethodPost, "/schema/diff", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		DiffSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		var report schema.DiffReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if report.BackwardCompatible || !report.ForwardCompatible || len(report.Changes) != 1 {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+		if change := report.Changes[0]; change.Kind != schema.FieldAdded || change.Path != "age" || !change.BreaksBackward {
+			t.Errorf("Unexpected change: %+v", change)
+		}
+	})
+
+	t.Run("InferredTopicAgainstSchema", func(t *testing.T) {
+		observer := kafka.NewSchemaObserver()
+		SetSchemaObserver(observer)
+		_ = observer.Observe("users", []byte(`{"id": "1"}`))
+
+		body := []byte(`{"old": {"topic": "users"}, "new": {"schema": {"fields": [{"name": "id", "type": "string", "required": true}]}}}`)
+		req := httptest.NewRequest(http.MethodPost, "/schema/diff", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		DiffSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `"changes":[]`) {
+			t.Errorf("Expected no changes, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("MissingReference", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/schema/diff", bytes.NewBufferString(`{"old": {}, "new": {}}`))
+		w := httptest.NewRecorder()
+
+		DiffSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestProfileHandler(t *testing.T) {
+	fieldProfiler = profile.NewProfiler()
+	fieldProfiler.Observe("transactions", map[string]interface{}{"Amount": 10.0, "Currency": "EUR"})
+	fieldProfiler.Observe("transactions", map[string]interface{}{"Amount": 30.0, "Currency": nil})
+
+	t.Run("TopicProfiles", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/profile?topic=transactions", nil)
+		w := httptest.NewRecorder()
+
+		ProfileHandler(w, req)
+
+		var profiles []profile.FieldProfile
+		if err := json.Unmarshal(w.Body.Bytes(), &profiles); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(profiles) != 2 || profiles[0].Path != "Amount" || profiles[1].NullRate != 0.5 {
+			t.Errorf("Unexpected profiles: %+v", profiles)
+		}
+	})
+
+	t.Run("FieldProfile", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/profile?topic=transactions&field=Amount", nil)
+		w := httptest.NewRecorder()
+
+		ProfileHandler(w, req)
+
+		var field profile.FieldProfile
+		if err := json.Unmarshal(w.Body.Bytes(), &field); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if field.Numbers == nil || field.Numbers.Mean != 20 {
+			t.Errorf("Unexpected profile: %+v", field)
+		}
+	})
+
+	t.Run("UnknownTopic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/profile?topic=missing", nil)
+		w := httptest.NewRecorder()
+
+		ProfileHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestEnumSuggestionsHandler(t *testing.T) {
+	observer := kafka.NewSchemaObserver()
+	SetSchemaObserver(observer)
+	currencies := []string{"EUR", "USD"}
+	for i := 0; i < schema.DefaultEnumMinSamples; i++ {
+		sample := fmt.Sprintf(`{"ID": "tx-%d", "Currency": %q}`, i, currencies[i%2])
+		if err := observer.Observe("transactions", []byte(sample)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("ListSuggestions", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/enums?topic=transactions", nil)
+		w := httptest.NewRecorder()
+
+		EnumSuggestionsHandler(w, req)
+
+		var suggestions []schema.EnumSuggestion
+		if err := json.Unmarshal(w.Body.Bytes(), &suggestions); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(suggestions) != 1 || suggestions[0].Path != "Currency" || !reflect.DeepEqual(suggestions[0].Symbols, currencies) {
+			t.Errorf("Unexpected suggestions: %+v", suggestions)
+		}
+	})
+
+	t.Run("ConfirmAndExportJSONSchema", func(t *testing.T) {
+		body := `{"topic": "transactions", "path": "Currency", "status": "confirmed"}`
+		req := httptest.NewRequest(http.MethodPost, "/schema/enums", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		EnumSuggestionsHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/schema/export?format=jsonschema&source=topic&topic=transactions", nil)
+		w = httptest.NewRecorder()
+
+		ExportSchemaHandler(w, req)
+
+		if !strings.Contains(w.Body.String(), `"enum": [`) {
+			t.Errorf("Expected an enum in:\n%s", w.Body.String())
+		}
+	})
+
+	t.Run("ConfirmHighCardinalityField", func(t *testing.T) {
+		body := `{"topic": "transactions", "path": "ID", "status": "confirmed"}`
+		req := httptest.NewRequest(http.MethodPost, "/schema/enums", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		EnumSuggestionsHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestObservedSchemaHandler(t *testing.T) {
+	observer := kafka.NewSchemaObserver()
+	SetSchemaObserver(observer)
+	if err := observer.Observe("users", []byte(`{"name": "Ann", "age": 41}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("SchemaWithExamples", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/observed?topic=users", nil)
+		w := httptest.NewRecorder()
+
+		ObservedSchemaHandler(w, req)
+
+		observed, err := schema.Parse(w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if !reflect.DeepEqual(observed.Field("name").Examples, []string{"Ann"}) {
+			t.Errorf("Expected [Ann], got %v", observed.Field("name").Examples)
+		}
+		if !reflect.DeepEqual(observed.Field("age").Examples, []string{"41"}) {
+			t.Errorf("Expected [41], got %v", observed.Field("age").Examples)
+		}
+	})
+
+	t.Run("MaskedFields", func(t *testing.T) {
+		body := `{"brokers": "localhost:9092", "masked_fields": ["name"]}`
+		UpdateKafkaConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/kafka_config", strings.NewReader(body)))
+		defer observer.SetMaskedFields(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/schema/observed?topic=users", nil)
+		w := httptest.NewRecorder()
+
+		ObservedSchemaHandler(w, req)
+
+		if !strings.Contains(w.Body.String(), `"examples":["Xxx"]`) {
+			t.Errorf("Expected masked examples in %s", w.Body.String())
+		}
+	})
+
+	t.Run("UnknownTopic", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/schema/observed?topic=missing", nil)
+		w := httptest.NewRecorder()
+
+		ObservedSchemaHandler(w, req)
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestRegistryHandlers(t *testing.T) {
+	SetRegistry(registry.New())
+	for _, body := range []string{
+		`{"fields": [{"name": "id", "type": "string", "required": true}]}`,
+		`{"fields": [{"name": "id", "type": "string", "required": true}, {"name": "email", "type": "string"}]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/schema?subject=users", bytes.NewBufferString(body))
+		ReceiveSchemaHandler(httptest.NewRecorder(), req)
+	}
+
+	t.Run("ListSubjects", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		SubjectsHandler(w, httptest.NewRequest(http.MethodGet, "/schema/subjects", nil))
+
+		if strings.TrimSpace(w.Body.String()) != `["users"]` {
+			t.Errorf("Expected [\"users\"], got %s", w.Body.String())
+		}
+	})
+
+	t.Run("ListVersions", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		VersionsHandler(w, httptest.NewRequest(http.MethodGet, "/schema/versions?subject=users", nil))
+
+		if strings.TrimSpace(w.Body.String()) != `[1,2]` {
+			t.Errorf("Expected [1,2], got %s", w.Body.String())
+		}
+	})
+
+	t.Run("LatestVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		VersionsHandler(w, httptest.NewRequest(http.MethodGet, "/schema/versions?subject=users&version=latest", nil))
+
+		var version registry.Version
+		if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if version.Version != 2 || version.Schema.Field("email") == nil {
+			t.Errorf("Expected version 2, got %+v", version)
+		}
+	})
+
+	t.Run("UnknownVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		VersionsHandler(w, httptest.NewRequest(http.MethodGet, "/schema/versions?subject=users&version=3", nil))
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("SchemaByID", func(t *testing.T) {
+		first, _ := schemaRegistry.Version("users", 1)
+		w := httptest.NewRecorder()
+		SchemaByIDHandler(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/schema/ids?id=%d", first.ID), nil))
+
+		if w.Result().StatusCode != http.StatusOK || strings.Contains(w.Body.String(), "email") {
+			t.Errorf("Expected the first schema, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		SchemaByIDHandler(w, httptest.NewRequest(http.MethodGet, "/schema/ids?id=abc", nil))
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("DiffRegisteredVersions", func(t *testing.T) {
+		body := []byte(`{"old": {"subject": "users", "version": 1}, "new": {"subject": "users"}}`)
+		w := httptest.NewRecorder()
+		DiffSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema/diff", bytes.NewBuffer(body)))
+
+		var report schema.DiffReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(report.Changes) != 1 || report.Changes[0].Path != "email" {
+			t.Errorf("Expected email to be added, got %+v", report)
+		}
+	})
+}
+
+func TestValidationHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	req := httptest.NewRequest(http.MethodPost, "/schema?subject=payments-value",
+		bytes.NewBufferString(`{"fields": [{"name": "ID", "type": "string", "required": true}]}`))
+	ReceiveSchemaHandler(httptest.NewRecorder(), req)
+
+	t.Run("BindLatestVersion", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"topic": "payments", "subject": "payments-value"}`)
+		w := httptest.NewRecorder()
+
+		ValidationHandler(w, httptest.NewRequest(http.MethodPost, "/validation", body))
+
+		if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `"version":1`) {
+			t.Errorf("Expected version 1 to be bound, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		schemaValidator.Handle(kafkago.Message{Topic: "payments", Value: []byte(`{"ID": 7}`)}, nil)
+		w := httptest.NewRecorder()
+
+		ValidationHandler(w, httptest.NewRequest(http.MethodGet, "/validation?topic=payments", nil))
+
+		var report kafka.ValidationReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if report.Messages != 1 || len(report.Violations) != 1 || report.Violations[0].Path != "ID" {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("UnknownSubject", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"topic": "orders", "subject": "orders-value"}`)
+		w := httptest.NewRecorder()
+
+		ValidationHandler(w, httptest.NewRequest(http.MethodPost, "/validation", body))
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("Unbind", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ValidationHandler(w, httptest.NewRequest(http.MethodDelete, "/validation?topic=payments", nil))
+		if w.Result().StatusCode != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %v", w.Result().StatusCode)
+		}
+
+		w = httptest.NewRecorder()
+		ValidationHandler(w, httptest.NewRequest(http.MethodGet, "/validation?topic=payments", nil))
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestStateHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=payments-value",
+		bytes.NewBufferString(`{"fields": [{"name": "ID", "type": "string", "required": true}]}`)))
+	ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/validation",
+		bytes.NewBufferString(`{"topic": "payments", "subject": "payments-value"}`)))
+
+	t.Run("RegisterDraft", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ReceiveSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema?subject=payments-value&state=draft",
+			bytes.NewBufferString(`{"fields": [{"name": "ID", "type": "string", "required": true}, {"name": "Note", "type": "string"}]}`)))
+		if w.Result().StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+		if version, found := schemaRegistry.Version("payments-value", 2); !found || version.State != registry.StateDraft {
+			t.Errorf("Expected a draft, got %+v", version)
+		}
+	})
+
+	t.Run("Deprecate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		StateHandler(w, httptest.NewRequest(http.MethodPost, "/schema/state",
+			bytes.NewBufferString(`{"subject": "payments-value", "version": 1, "state": "deprecated"}`)))
+		if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `"state":"deprecated"`) {
+			t.Fatalf("Expected version 1 to be deprecated, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+
+		schemaValidator.Handle(kafkago.Message{Topic: "payments", Value: []byte(`{"ID": "a"}`)}, nil)
+		if report, _ := schemaValidator.Report("payments"); report.Deprecated != 1 {
+			t.Errorf("Expected the message to match the deprecated version, got %+v", report)
+		}
+	})
+
+	t.Run("Retire", func(t *testing.T) {
+		StateHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema/state",
+			bytes.NewBufferString(`{"subject": "payments-value", "version": 1, "state": "retired"}`)))
+		w := httptest.NewRecorder()
+		StateHandler(w, httptest.NewRequest(http.MethodPost, "/schema/state",
+			bytes.NewBufferString(`{"subject": "payments-value", "version": 1, "state": "active"}`)))
+		if w.Result().StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409, got %v", w.Result().StatusCode)
+		}
+
+		w = httptest.NewRecorder()
+		ValidationHandler(w, httptest.NewRequest(http.MethodPost, "/validation",
+			bytes.NewBufferString(`{"topic": "payments", "subject": "payments-value", "version": 1}`)))
+		if w.Result().StatusCode != http.StatusConflict {
+			t.Errorf("Expected binding a retired version to be refused, got %v", w.Result().StatusCode)
+		}
+	})
+
+	t.Run("UnknownState", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		StateHandler(w, httptest.NewRequest(http.MethodPost, "/schema/state",
+			bytes.NewBufferString(`{"subject": "payments-value", "version": 2, "state": "archived"}`)))
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestMetadataHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=customer",
+		bytes.NewBufferString(`{"fields": [{"name": "Email", "type": "string", "classification": "pii", "doc": "Contact address"}]}`)))
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=orders",
+		bytes.NewBufferString(`{"fields": [
+			{"name": "ID", "type": "string", "required": true, "tags": ["key"], "classification": "public"},
+			{"name": "Customer", "type": "record", "required": true, "ref": {"subject": "customer"}}
+		], "metadata": {"owners": ["orders-team"]}}`)))
+
+	t.Run("LatestVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MetadataHandler(w, httptest.NewRequest(http.MethodGet, "/schema/metadata?subject=orders", nil))
+
+		var response metadataResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Version != 1 || len(response.Metadata.Owners) != 1 || len(response.Fields) != 2 {
+			t.Errorf("Unexpected metadata: %+v", response)
+		}
+	})
+
+	t.Run("Classification", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MetadataHandler(w, httptest.NewRequest(http.MethodGet, "/schema/metadata?subject=orders&classification=PII", nil))
+
+		var response metadataResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(response.Fields) != 1 || response.Fields[0].Path != "Customer.Email" || response.Fields[0].Doc != "Contact address" {
+			t.Errorf("Expected the referenced Customer.Email, got %+v", response.Fields)
+		}
+	})
+
+	t.Run("UnknownClassification", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MetadataHandler(w, httptest.NewRequest(http.MethodGet, "/schema/metadata?subject=orders&classification=secret", nil))
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestSearchHandler(t *testing.T) {
+	SetRegistry(registry.New())
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=users",
+		bytes.NewBufferString(`{"fields": [{"name": "email", "type": "string", "required": true, "classification": "pii"}]}`)))
+	observer := kafka.NewSchemaObserver()
+	SetSchemaObserver(observer)
+	_ = observer.Observe("signups", []byte(`{"Email": "a@example.com", "Amount": 1.5}`))
+
+	search := func(query string) []searchResult {
+		t.Helper()
+		w := httptest.NewRecorder()
+		SearchHandler(w, httptest.NewRequest(http.MethodGet, "/schema/search?"+query, nil))
+		var results []searchResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return results
+	}
+
+	t.Run("FieldName", func(t *testing.T) {
+		results := search("name=email")
+		if len(results) != 2 || results[0].Subject != "users" || results[0].Version != 1 || results[1].Topic != "signups" {
+			t.Errorf("Expected users and signups, got %+v", results)
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		results := search("tag=pii")
+		if len(results) != 1 || results[0].Path != "email" {
+			t.Errorf("Expected the classified email, got %+v", results)
+		}
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		results := search("type=number&source=observed")
+		if len(results) != 1 || results[0].Path != "Amount" {
+			t.Errorf("Expected the observed Amount, got %+v", results)
+		}
+	})
+
+	t.Run("NoCriteria", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		SearchHandler(w, httptest.NewRequest(http.MethodGet, "/schema/search", nil))
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
+
+func TestMappingHandler(t *testing.T) {
+	SetMappingPipeline(kafka.NewMappingPipeline())
+	t.Cleanup(func() { mappingPipeline = nil })
+	config, _ := json.Marshal(KafkaConfig{PreServiceTopic: "pre", PostServiceTopic: "post"})
+	UpdateKafkaConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/kafka_config", bytes.NewBuffer(config)))
+
+	t.Run("PostYAML", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MappingHandler(w, httptest.NewRequest(http.MethodPost, "/mapping",
+			bytes.NewBufferString("fields:\n  - source: ID\n    target: id\n")))
+		var response mappingResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		status := response.Status
+		if len(response.Mapping.Fields) != 1 || !status.Running || status.SourceTopic != "pre" || status.TargetTopic != "post" {
+			t.Errorf("Expected a running pipeline from pre to post, got %+v", response)
+		}
+		if _, found, _ := stateStore.Get(serviceBucket, mappingKey); !found {
+			t.Error("Expected the mapping to be stored")
+		}
+	})
+
+	t.Run("InvalidMapping", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MappingHandler(w, httptest.NewRequest(http.MethodPut, "/mapping", bytes.NewBufferString(`{"fields": [{"source": "ID"}]}`)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		MappingHandler(w, httptest.NewRequest(http.MethodDelete, "/mapping", nil))
+		if w.Code != http.StatusNoContent || mappingPipeline.Status().Running {
+			t.Errorf("Expected the pipeline to stop, got status %d", w.Code)
+		}
+		if _, found, _ := stateStore.Get(serviceBucket, mappingKey); found {
+			t.Error("Expected the mapping to be deleted from the store")
+		}
+	})
+
+	t.Run("Brokers", func(t *testing.T) {
+		connection := kafkaConnection(KafkaConfig{Brokers: "a:9092, b:9092", SASLMechanism: "PLAIN", SASLUsername: "thoth"})
+		if !reflect.DeepEqual(connection.Brokers, []string{"a:9092", "b:9092"}) || connection.SASLUsername != "thoth" {
+			t.Errorf("Expected the brokers and SASL settings of the config, got %+v", connection)
+		}
+		if connection := kafkaConnection(KafkaConfig{}); !reflect.DeepEqual(connection.Brokers, []string{defaultBroker}) {
+			t.Errorf("Expected the default broker, got %+v", connection.Brokers)
+		}
+	})
+}
+
+func TestSchemaReferences(t *testing.T) {
+	SetRegistry(registry.New())
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=customer",
+		bytes.NewBufferString(`{"name": "Customer", "fields": [{"name": "Email", "type": "string", "required": true}]}`)))
+	w := httptest.NewRecorder()
+	ReceiveSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema?subject=orders",
+		bytes.NewBufferString(`{"name": "Order", "fields": [{"name": "Customer", "type": "record", "required": true, "ref": {"subject": "customer"}}]}`)))
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %v: %s", w.Result().StatusCode, w.Body.String())
+	}
+
+	t.Run("ExportResolvesReferences", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ExportSchemaHandler(w, httptest.NewRequest(http.MethodGet, "/schema/export?format=jsonschema&source=subject&subject=orders", nil))
+		if w.Result().StatusCode != http.StatusOK || !strings.Contains(w.Body.String(), `"Email"`) {
+			t.Errorf("Expected the referenced fields to be exported, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("ValidationResolvesReferences", func(t *testing.T) {
+		ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/validation",
+			bytes.NewBufferString(`{"topic": "orders", "subject": "orders"}`)))
+		violations, _ := schemaValidator.Validate("orders", []byte(`{"Customer": {"Email": 7}}`))
+		if len(violations) != 1 || violations[0].Path != "Customer.Email" {
+			t.Errorf("Expected Customer.Email to be validated, got %+v", violations)
+		}
+	})
+
+	t.Run("DeleteReferencedSubject", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		VersionsHandler(w, httptest.NewRequest(http.MethodDelete, "/schema/versions?subject=customer", nil))
+		if w.Result().StatusCode != http.StatusConflict {
+			t.Errorf("Expected status 409, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("DeleteVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		VersionsHandler(w, httptest.NewRequest(http.MethodDelete, "/schema/versions?subject=orders&version=1", nil))
+		if w.Result().StatusCode != http.StatusOK || strings.TrimSpace(w.Body.String()) != `[1]` {
+			t.Errorf("Expected version 1 to be deleted, got %v: %s", w.Result().StatusCode, w.Body.String())
+		}
+	})
+
+	t.Run("UnknownReference", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ReceiveSchemaHandler(w, httptest.NewRequest(http.MethodPost, "/schema?subject=orders",
+			bytes.NewBufferString(`{"fields": [{"name": "Customer", "type": "record", "ref": {"subject": "buyer"}}]}`)))
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %v", w.Result().StatusCode)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/wolfchristopher/thoth/internal/codegen"
+	"github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/mapping"
+	"github.com/wolfchristopher/thoth/internal/storage"
+)
+
+// Buckets of the store holding the service state kept by the routes: the
+// Kafka config and mapping spec, the protobuf field numbers of each subject
+// and the schema bound to each topic.
+const (
+	serviceBucket      = "service"
+	kafkaConfigKey     = "kafka_config"
+	mappingKey         = "mapping"
+	protoNumbersBucket = "proto_numbers"
+	bindingsBucket     = "bindings"
+)
+
+// saslPasswordEnv names the environment variable the SASL password is taken
+// from when the Kafka config is loaded. The password is never stored, since
+// file and bolt stores keep it in plain text and a Kafka store shares it with
+// every instance.
+const saslPasswordEnv = "THOTH_SASL_PASSWORD"
+
+// stateStore keeps the service state across restarts.
+var stateStore storage.Store = storage.NewMemory()
+
+// SetStore makes the routes keep their state in store and loads the state it
+// already holds. Bindings are resolved through the registry, so SetRegistry
+// must be called first; bindings to versions the registry no longer holds are
+// skipped. The mapping is loaded into the pipeline, so SetMappingPipeline
+// must be called first too. When the store is shared with other instances, the changes they
+// make are applied as they come.
+func SetStore(store storage.Store) error {
+	var config KafkaConfig
+	data, found, err := store.Get(serviceBucket, kafkaConfigKey)
+	if err != nil {
+		return fmt.Errorf("error loading kafka config: %v", err)
+	}
+	if found {
+		configMu.Lock()
+		config, err = decodeKafkaConfig(data)
+		configMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	var spec *mapping.Spec
+	data, hasMapping, err := store.Get(serviceBucket, mappingKey)
+	if err != nil {
+		return fmt.Errorf("error loading mapping: %v", err)
+	}
+	if hasMapping {
+		if spec, err = decodeMapping(data); err != nil {
+			return err
+		}
+	}
+
+	numbers := map[string]*codegen.FieldNumbers{}
+	values, err := store.List(protoNumbersBucket)
+	if err != nil {
+		return fmt.Errorf("error loading proto field numbers: %v", err)
+	}
+	for subject, data := range values {
+		if numbers[subject], err = decodeFieldNumbers(subject, data); err != nil {
+			return err
+		}
+	}
+
+	values, err = store.List(bindingsBucket)
+	if err != nil {
+		return fmt.Errorf("error loading bindings: %v", err)
+	}
+	var bindings []kafka.Binding
+	for topic, data := range values {
+		binding, found, err := decodeBinding(topic, data)
+		if err != nil {
+			return err
+		}
+		if found {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	stateStore = store
+	configMu.Lock()
+	currentConfig = config
+	if found {
+		applyKafkaConfig()
+	}
+	configMu.Unlock()
+	if mappingPipeline != nil {
+		mappingPipeline.SetMapping(spec)
+	}
+	protoNumbersMu.Lock()
+	protoNumbers = numbers
+	protoNumbersMu.Unlock()
+	for _, binding := range bindings {
+		if err := schemaValidator.Bind(binding); err != nil {
+			return err
+		}
+	}
+	followBindings()
+	if watcher, ok := store.(storage.Watcher); ok {
+		watcher.Watch(reloadState)
+	}
+	return nil
+}
+
+// decodeKafkaConfig loads a stored config, keeping the SASL password of the
+// current config or else taking it from the environment; the caller holds
+// configMu.
+func decodeKafkaConfig(data []byte) (KafkaConfig, error) {
+	var config KafkaConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return KafkaConfig{}, fmt.Errorf("error loading kafka config: %v", err)
+	}
+	config.SASLPassword = currentConfig.SASLPassword
+	if config.SASLPassword == "" {
+		config.SASLPassword = os.Getenv(saslPasswordEnv)
+	}
+	return config, nil
+}
+
+// withoutSecrets returns the config as it is stored and shown.
+func withoutSecrets(config KafkaConfig) KafkaConfig {
+	config.SASLPassword = ""
+	return config
+}
+
+func decodeMapping(data []byte) (*mapping.Spec, error) {
+	spec, err := mapping.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading mapping: %v", err)
+	}
+	return spec, nil
+}
+
+func decodeFieldNumbers(subject string, data []byte) (*codegen.FieldNumbers, error) {
+	numbers := codegen.NewFieldNumbers()
+	if err := json.Unmarshal(data, numbers); err != nil {
+		return nil, fmt.Errorf("error loading proto field numbers of %s: %v", subject, err)
+	}
+	return numbers, nil
+}
+
+// decodeBinding resolves a stored binding through the registry. It reports
+// whether the bound version exists.
+func decodeBinding(topic string, data []byte) (kafka.Binding, bool, error) {
+	var request bindingRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return kafka.Binding{}, false, fmt.Errorf("error loading binding of %s: %v", topic, err)
+	}
+	version, found := schemaRegistry.Version(request.Subject, request.Version)
+	if !found {
+		log.Printf("Skipping binding of %s: version %d of %s not found", topic, request.Version, request.Subject)
+		return kafka.Binding{}, false, nil
+	}
+	resolved, err := schemaRegistry.Resolve(version.Schema)
+	if err != nil {
+		return kafka.Binding{}, false, fmt.Errorf("error loading binding of %s: %v", topic, err)
+	}
+	return kafka.Binding{Topic: topic, Subject: version.Subject, Version: version.Version, Schema: resolved}, true, nil
+}
+
+// reloadState applies a change another instance made to the state store.
+func reloadState(bucket, key string) {
+	data, found, err := stateStore.Get(bucket, key)
+	if err != nil {
+		log.Printf("Failed to reload %s/%s: %v", bucket, key, err)
+		return
+	}
+	switch {
+	case bucket == serviceBucket && key == kafkaConfigKey && found:
+		configMu.Lock()
+		defer configMu.Unlock()
+		config, err := decodeKafkaConfig(data)
+		if err != nil {
+			log.Printf("Failed to reload kafka config: %v", err)
+			return
+		}
+		currentConfig = config
+		applyKafkaConfig()
+	case bucket == serviceBucket && key == mappingKey && found:
+		spec, err := decodeMapping(data)
+		if err != nil {
+			log.Printf("Failed to reload mapping: %v", err)
+			return
+		}
+		if mappingPipeline != nil {
+			mappingPipeline.SetMapping(spec)
+		}
+	case bucket == serviceBucket && key == mappingKey:
+		if mappingPipeline != nil {
+			mappingPipeline.SetMapping(nil)
+		}
+	case bucket == protoNumbersBucket && found:
+		numbers, err := decodeFieldNumbers(key, data)
+		if err != nil {
+			log.Printf("Failed to reload proto field numbers: %v", err)
+			return
+		}
+		protoNumbersMu.Lock()
+		protoNumbers[key] = numbers
+		protoNumbersMu.Unlock()
+	case bucket == bindingsBucket && found:
+		binding, found, err := decodeBinding(key, data)
+		if err != nil {
+			log.Printf("Failed to reload binding: %v", err)
+			return
+		}
+		if found {
+			_ = schemaValidator.Bind(binding)
+			followBindings()
+		}
+	case bucket == bindingsBucket:
+		schemaValidator.Unbind(key)
+		followBindings()
+	}
+}
+
+// saveState stores value as JSON under key in bucket of the state store.
+func saveState(bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s/%s: %v", bucket, key, err)
+	}
+	return stateStore.Put(bucket, key, data)
+}
+
+// redirectToLeader answers a request whose change was refused because this
+// instance does not lead the shared store, redirecting it to the leader when
+// the leader is known by its URL. It reports whether err was such a refusal.
+func redirectToLeader(w http.ResponseWriter, r *http.Request, err error) bool {
+	var notLeader *storage.NotLeaderError
+	if !errors.As(err, &notLeader) {
+		return false
+	}
+	if strings.HasPrefix(notLeader.Leader, "http://") || strings.HasPrefix(notLeader.Leader, "https://") {
+		http.Redirect(w, r, strings.TrimSuffix(notLeader.Leader, "/")+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		return true
+	}
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	return true
+}
@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/wolfchristopher/thoth/internal/kafka"
+	"github.com/wolfchristopher/thoth/internal/registry"
+	"github.com/wolfchristopher/thoth/internal/schema"
+	"github.com/wolfchristopher/thoth/internal/storage"
+)
+
+func TestSetStore(t *testing.T) {
+	store := storage.NewMemory()
+	r, _ := registry.Open(store)
+	SetRegistry(r)
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	if err := SetStore(store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config, _ := json.Marshal(KafkaConfig{Brokers: "broker:9092", GroupID: "thoth", SASLPassword: "posted-secret"})
+	UpdateKafkaConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/kafka_config", bytes.NewBuffer(config)))
+	ReceiveSchemaHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema?subject=payments-value",
+		bytes.NewBufferString(`{"fields": [{"name": "ID", "type": "string", "required": true}]}`)))
+	ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/validation",
+		bytes.NewBufferString(`{"topic": "payments", "subject": "payments-value"}`)))
+	if _, err := exportProto("Payment", &schema.Schema{Name: "Payment", Fields: []*schema.Field{{Name: "ID", Type: schema.TypeString}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Start over as after a restart, with nothing but the store.
+	t.Setenv(saslPasswordEnv, "env-secret")
+	currentConfig = KafkaConfig{}
+	protoNumbers = nil
+	reopened, err := registry.Open(store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	SetRegistry(reopened)
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	if err := SetStore(store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("KafkaConfig", func(t *testing.T) {
+		if currentConfig.Brokers != "broker:9092" || currentConfig.GroupID != "thoth" {
+			t.Errorf("Expected the stored config, got %+v", currentConfig)
+		}
+	})
+
+	t.Run("SASLPassword", func(t *testing.T) {
+		if data, _, _ := store.Get(serviceBucket, kafkaConfigKey); bytes.Contains(data, []byte("secret")) {
+			t.Errorf("Expected the password not to be stored, got %s", data)
+		}
+		if currentConfig.SASLPassword != "env-secret" {
+			t.Errorf("Expected the password from %s, got %q", saslPasswordEnv, currentConfig.SASLPassword)
+		}
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		if version, found := schemaRegistry.Latest("payments-value"); !found || version.Version != 1 {
+			t.Errorf("Expected version 1 of payments-value, got %+v", version)
+		}
+	})
+
+	t.Run("Bindings", func(t *testing.T) {
+		report, found := schemaValidator.Report("payments")
+		if !found || report.Subject != "payments-value" || report.Version != 1 {
+			t.Errorf("Expected payments to be bound to version 1, got %+v", report)
+		}
+	})
+
+	t.Run("ProtoNumbers", func(t *testing.T) {
+		if numbers, ok := protoNumbers["Payment"]; !ok || numbers.Messages["Payment"]["ID"] != 1 {
+			t.Errorf("Expected the stored field numbers, got %+v", protoNumbers)
+		}
+	})
+
+	t.Run("UnbindForgetsBinding", func(t *testing.T) {
+		ValidationHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/validation?topic=payments", nil))
+		if _, found, _ := store.Get(bindingsBucket, "payments"); found {
+			t.Error("Expected the binding to be deleted from the store")
+		}
+	})
+
+	t.Run("CorruptedConfig", func(t *testing.T) {
+		corrupted := storage.NewMemory()
+		_ = corrupted.Put(serviceBucket, kafkaConfigKey, []byte(`[]`))
+		if err := SetStore(corrupted); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	_ = SetStore(storage.NewMemory())
+}
+
+func TestReloadState(t *testing.T) {
+	store := storage.NewMemory()
+	r, _ := registry.Open(store)
+	SetRegistry(r)
+	SetSchemaValidator(kafka.NewSchemaValidator())
+	_ = SetStore(store)
+	_, _ = r.Register("payments-value", &schema.Schema{Fields: []*schema.Field{{Name: "ID", Type: schema.TypeString}}})
+
+	t.Run("Binding", func(t *testing.T) {
+		_ = store.Put(bindingsBucket, "payments", []byte(`{"topic": "payments", "subject": "payments-value", "version": 1}`))
+		reloadState(bindingsBucket, "payments")
+		if _, found := schemaValidator.Report("payments"); !found {
+			t.Error("Expected payments to be bound")
+		}
+	})
+
+	t.Run("Unbinding", func(t *testing.T) {
+		_ = store.Delete(bindingsBucket, "payments")
+		reloadState(bindingsBucket, "payments")
+		if _, found := schemaValidator.Report("payments"); found {
+			t.Error("Expected payments to be unbound")
+		}
+	})
+
+	t.Run("KafkaConfig", func(t *testing.T) {
+		_ = store.Put(serviceBucket, kafkaConfigKey, []byte(`{"brokers": "other:9092"}`))
+		reloadState(serviceBucket, kafkaConfigKey)
+		if currentConfig.Brokers != "other:9092" {
+			t.Errorf("Expected the config of the other instance, got %+v", currentConfig)
+		}
+	})
+
+	t.Run("ConcurrentKafkaConfig", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				reloadState(serviceBucket, kafkaConfigKey)
+			}()
+			go func() {
+				defer wg.Done()
+				UpdateKafkaConfig(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/kafka_config",
+					bytes.NewBufferString(`{"group_id": "thoth"}`)))
+			}()
+		}
+		wg.Wait()
+		if currentConfig.GroupID != "thoth" {
+			t.Errorf("Expected the posted group, got %+v", currentConfig)
+		}
+	})
+
+	_ = SetStore(storage.NewMemory())
+}
+
+func TestRedirectToLeader(t *testing.T) {
+	t.Run("LeaderURL", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/schema?subject=users", nil)
+		if !redirectToLeader(w, req, &storage.NotLeaderError{Leader: "http://thoth-1:8080/"}) {
+			t.Fatal("Expected the request to be answered")
+		}
+		if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != "http://thoth-1:8080/schema?subject=users" {
+			t.Errorf("Expected a redirect to the leader, got %v to %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("NoLeader", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		redirectToLeader(w, httptest.NewRequest(http.MethodPost, "/schema", nil), &storage.NotLeaderError{})
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %v", w.Code)
+		}
+	})
+
+	t.Run("OtherError", func(t *testing.T) {
+		if redirectToLeader(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/schema", nil), errors.New("disk full")) {
+			t.Error("Expected other errors to be left to the handler")
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// avroParser resolves the named types of an Avro schema, which may be used
+// by name once defined.
+type avroParser struct {
+	named map[string]*Field
+}
+
+// ParseAvro decodes an Avro record schema into thoth's representation. Unions
+// of null and another type become optional fields, other unions fields of
+// type any; int and long are integers, float and double numbers, enums string
+// fields with their symbols, and the timestamp and decimal logical types keep
+// their meaning. Fields renamed by the Avro export regain the name kept in
+// their "originalName" attribute. Field docs and the "tags" and
+// "classification" field attributes, as well as the "owners" and "tags" of
+// the top level record, are kept as metadata.
+func ParseAvro(data []byte) (*Schema, error) {
+	return ParseAvroReferences(data, nil)
+}
+
+// ParseAvroReferences decodes an Avro record schema like ParseAvro, the
+// records named by references being defined by other registered schemas.
+// Fields of those types become referencing fields.
+func ParseAvroReferences(data []byte, references []Reference) (*Schema, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error decoding Avro schema: %v", err)
+	}
+	root, ok := document.(map[string]interface{})
+	if !ok || root["type"] != "record" {
+		return nil, fmt.Errorf("error decoding Avro schema: top level type is not a record")
+	}
+
+	p := &avroParser{named: map[string]*Field{}}
+	for _, reference := range references {
+		ref := reference
+		p.named[ref.Name] = &Field{Type: TypeRecord, Ref: &ref}
+	}
+	field, err := p.parse(root, "")
+	if err != nil {
+		return nil, err
+	}
+	name, _ := root["name"].(string)
+	namespace, _ := root["namespace"].(string)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	doc, _ := root["doc"].(string)
+	metadata := Metadata{Description: doc, Owners: avroStrings(root["owners"]), Tags: avroStrings(root["tags"])}
+	s := &Schema{Name: name, Namespace: namespace, Fields: field.Fields, Metadata: metadata}
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("schema has no fields")
+	}
+	return s, nil
+}
+
+// parse returns the field describing an Avro type. namespace is the enclosing
+// namespace used to qualify the names of named types.
+func (p *avroParser) parse(avroType interface{}, namespace string) (*Field, error) {
+	switch t := avroType.(type) {
+	case string:
+		return p.primitive(t, namespace)
+	case []interface{}:
+		return p.union(t, namespace)
+	case map[string]interface{}:
+		return p.complex(t, namespace)
+	default:
+		return nil, fmt.Errorf("error decoding Avro schema: invalid type %v", avroType)
+	}
+}
+
+func (p *avroParser) primitive(name, namespace string) (*Field, error) {
+	switch name {
+	case "null":
+		return &Field{Type: TypeNull}, nil
+	case "boolean":
+		return &Field{Type: TypeBoolean}, nil
+	case "int", "long":
+		return &Field{Type: TypeInteger}, nil
+	case "float", "double":
+		return &Field{Type: TypeNumber}, nil
+	case "string", "bytes":
+		return &Field{Type: TypeString}, nil
+	}
+	for _, candidate := range []string{name, qualify(name, namespace)} {
+		if field, ok := p.named[candidate]; ok {
+			return field.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("error decoding Avro schema: unknown type %q", name)
+}
+
+func (p *avroParser) union(branches []interface{}, namespace string) (*Field, error) {
+	var types []*Field
+	nullable := false
+	for _, branch := range branches {
+		field, err := p.parse(branch, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if field.Type == TypeNull {
+			nullable = true
+			continue
+		}
+		types = append(types, field)
+	}
+	var field *Field
+	switch len(types) {
+	case 0:
+		field = &Field{Type: TypeNull}
+	case 1:
+		field = types[0]
+	default:
+		field = &Field{Type: TypeAny}
+	}
+	field.Required = !nullable
+	return field, nil
+}
+
+func (p *avroParser) complex(t map[string]interface{}, namespace string) (*Field, error) {
+	typeName, _ := t["type"].(string)
+	if logical, _ := t["logicalType"].(string); logical != "" {
+		switch logical {
+		case "timestamp-millis", "timestamp-micros", "local-timestamp-millis", "local-timestamp-micros", "date":
+			return &Field{Type: TypeTimestamp}, nil
+		case "decimal":
+			precision, _ := t["precision"].(float64)
+			scale, _ := t["scale"].(float64)
+			return &Field{Type: TypeDecimal, Precision: int(precision), Scale: int(scale)}, nil
+		}
+	}
+
+	switch typeName {
+	case "record", "error":
+		name, namespace := avroName(t, namespace)
+		field := &Field{Type: TypeRecord}
+		fields, _ := t["fields"].([]interface{})
+		for _, raw := range fields {
+			definition, ok := raw.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("error decoding Avro schema: invalid field in record %q", name)
+			}
+			child, err := p.parse(definition["type"], namespace)
+			if err != nil {
+				return nil, err
+			}
+			child.Name, _ = definition["name"].(string)
+			if original, ok := definition["originalName"].(string); ok && original != "" {
+				child.Name = original
+			}
+			if child.Name == "" {
+				return nil, fmt.Errorf("error decoding Avro schema: field without a name in record %q", name)
+			}
+			child.Doc, _ = definition["doc"].(string)
+			child.Tags = avroStrings(definition["tags"])
+			if classification, ok := definition["classification"].(string); ok {
+				if child.Classification, err = ParseClassification(classification); err != nil {
+					return nil, fmt.Errorf("error decoding Avro schema: field %q: %v", child.Name, err)
+				}
+			}
+			if _, union := definition["type"].([]interface{}); !union {
+				child.Required = child.Type != TypeNull
+			}
+			// Readers fill in a field with a default when the data lacks it.
+			if _, hasDefault := definition["default"]; hasDefault {
+				child.Required = false
+			}
+			field.Fields = append(field.Fields, child)
+		}
+		p.define(name, field)
+		return field, nil
+	case "enum":
+		name, _ := avroName(t, namespace)
+		field := &Field{Type: TypeString}
+		symbols, _ := t["symbols"].([]interface{})
+		for _, symbol := range symbols {
+			if s, ok := symbol.(string); ok {
+				field.Enum = append(field.Enum, s)
+			}
+		}
+		p.define(name, field)
+		return field, nil
+	case "fixed":
+		name, _ := avroName(t, namespace)
+		field := &Field{Type: TypeString}
+		p.define(name, field)
+		return field, nil
+	case "array":
+		items, err := p.parse(t["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		items.Required = true
+		return &Field{Type: TypeArray, Items: items}, nil
+	case "map":
+		values, err := p.parse(t["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		values.Required = true
+		return &Field{Type: TypeMap, Values: values}, nil
+	default:
+		// Primitive types may be written as {"type": "long"}.
+		return p.parse(t["type"], namespace)
+	}
+}
+
+// avroStrings returns the strings of a JSON array attribute.
+func avroStrings(attribute interface{}) []string {
+	values, _ := attribute.([]interface{})
+	var strs []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// define records a named type under its full name.
+func (p *avroParser) define(name string, field *Field) {
+	p.named[name] = field.clone()
+}
+
+// avroName returns the full name of a named type and the namespace its
+// children inherit.
+func avroName(t map[string]interface{}, namespace string) (string, string) {
+	name, _ := t["name"].(string)
+	if ns, ok := t["namespace"].(string); ok {
+		namespace = ns
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name, name[:i]
+	}
+	return qualify(name, namespace), namespace
+}
+
+func qualify(name, namespace string) string {
+	if namespace == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return namespace + "." + name
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAvro(t *testing.T) {
+	document := []byte(`{
+		"type": "record",
+		"name": "Transaction",
+		"namespace": "com.example",
+		"doc": "A card payment",
+		"fields": [
+			{"name": "ID", "type": "string"},
+			{"name": "Amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+			{"name": "Created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "Status", "type": {"type": "enum", "name": "Status", "symbols": ["OPEN", "PAID"]}},
+			{"name": "Note", "type": ["null", "string"], "default": null},
+			{"name": "customer_email", "originalName": "Customer-Email", "type": "string"},
+			{"name": "Billing", "type": {"type": "record", "name": "Address", "fields": [
+				{"name": "City", "type": "string"}
+			]}},
+			{"name": "Shipping", "type": ["null", "Address"]},
+			{"name": "Items", "type": {"type": "array", "items": {"type": "record", "name": "Item", "fields": [
+				{"name": "Price", "type": "double"}
+			]}}},
+			{"name": "Attributes", "type": {"type": "map", "values": "long"}}
+		]
+	}`)
+
+	t.Run("Given an Avro record, it should parse its fields", func(t *testing.T) {
+		s, err := ParseAvro(document)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if s.Name != "Transaction" || s.Namespace != "com.example" || s.Metadata.Description != "A card payment" {
+			t.Errorf("Unexpected schema header: %+v", s)
+		}
+
+		if f := s.Field("Amount"); f.Type != TypeDecimal || f.Precision != 10 || f.Scale != 2 || !f.Required {
+			t.Errorf("Unexpected decimal field: %+v", f)
+		}
+		if f := s.Field("Created"); f.Type != TypeTimestamp {
+			t.Errorf("Unexpected timestamp field: %+v", f)
+		}
+		if f := s.Field("Status"); f.Type != TypeString || !reflect.DeepEqual(f.Enum, []string{"OPEN", "PAID"}) {
+			t.Errorf("Unexpected enum field: %+v", f)
+		}
+		if f := s.Field("Note"); f.Type != TypeString || f.Required {
+			t.Errorf("Expected an optional string, got %+v", f)
+		}
+		if f := s.Field("Customer-Email"); f == nil {
+			t.Errorf("Expected the original field name to be restored, got %+v", s.Fields)
+		}
+		if f := s.Lookup("Items.Price"); f == nil || f.Type != TypeNumber {
+			t.Errorf("Unexpected array element: %+v", s.Field("Items"))
+		}
+		if f := s.Field("Attributes"); f.Type != TypeMap || f.Values.Type != TypeInteger {
+			t.Errorf("Unexpected map field: %+v", f)
+		}
+	})
+
+	t.Run("Given a field with a default, it should make it optional", func(t *testing.T) {
+		data := []byte(`{"type": "record", "name": "R", "fields": [{"name": "currency", "type": "string", "default": "USD"}]}`)
+		s, err := ParseAvro(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if f := s.Field("currency"); f.Type != TypeString || f.Required {
+			t.Errorf("Expected an optional string, got %+v", f)
+		}
+	})
+
+	t.Run("Given a named type used again, it should resolve it", func(t *testing.T) {
+		s, _ := ParseAvro(document)
+		shipping := s.Field("Shipping")
+		if shipping.Type != TypeRecord || shipping.Required || s.Lookup("Shipping.City") == nil {
+			t.Errorf("Unexpected reused record: %+v", shipping)
+		}
+	})
+
+	t.Run("Given an unknown named type, it should return an error", func(t *testing.T) {
+		data := []byte(`{"type": "record", "name": "R", "fields": [{"name": "a", "type": "Missing"}]}`)
+		if _, err := ParseAvro(data); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given a top level type that is not a record, it should return an error", func(t *testing.T) {
+		if _, err := ParseAvro([]byte(`"string"`)); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given documented fields, it should keep their metadata", func(t *testing.T) {
+		data := []byte(`{"type": "record", "name": "R", "owners": ["crm"], "fields": [
+			{"name": "email", "type": "string", "doc": "Contact address", "tags": ["contact"], "classification": "pii"}
+		]}`)
+		s, err := ParseAvro(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		email := s.Field("email")
+		if email.Doc != "Contact address" || email.Classification != ClassificationPII || len(email.Tags) != 1 || len(s.Metadata.Owners) != 1 {
+			t.Errorf("Unexpected metadata: %+v %+v", email, s.Metadata)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package schema
+
+// ClassifiedChange is a change together with whether it breaks backward
+// compatibility (readers of the new schema reading data written with the old
+// one) or forward compatibility (readers of the old schema reading data
+// written with the new one).
+type ClassifiedChange struct {
+	Change
+	BreaksBackward bool `json:"breaks_backward"`
+	BreaksForward  bool `json:"breaks_forward"`
+}
+
+// DiffReport is the machine readable result of comparing two schemas.
+type DiffReport struct {
+	Changes            []ClassifiedChange `json:"changes"`
+	BackwardCompatible bool               `json:"backward_compatible"`
+	ForwardCompatible  bool               `json:"forward_compatible"`
+}
+
+// Compare diffs two schemas and classifies every change.
+func Compare(old, new *Schema) DiffReport {
+	report := DiffReport{Changes: []ClassifiedChange{}, BackwardCompatible: true, ForwardCompatible: true}
+	for _, change := range Diff(old, new) {
+		classified := Classify(change)
+		report.Changes = append(report.Changes, classified)
+		report.BackwardCompatible = report.BackwardCompatible && !classified.BreaksBackward
+		report.ForwardCompatible = report.ForwardCompatible && !classified.BreaksForward
+	}
+	return report
+}
+
+// Classify decides which compatibility directions a change breaks.
+func Classify(change Change) ClassifiedChange {
+	c := ClassifiedChange{Change: change}
+	switch change.Kind {
+	case FieldAdded:
+		// Old data lacks the new field.
+		c.BreaksBackward = change.NewRequired
+	case FieldRemoved:
+		// Old readers still expect the removed field.
+		c.BreaksForward = change.OldRequired
+	case RequiredChanged:
+		c.BreaksBackward = change.NewRequired
+		c.BreaksForward = change.OldRequired
+	case EnumAdded, SymbolRemoved, VariantRemoved:
+		// Old data may hold values the new schema no longer allows.
+		c.BreaksBackward = true
+	case EnumRemoved, SymbolAdded, VariantAdded:
+		// Old readers do not know the values the new schema allows.
+		c.BreaksForward = true
+	case TypeChanged:
+		switch widen(change.OldType, change.NewType) {
+		case change.NewType:
+			// New readers accept the old values, old readers do not accept
+			// the wider new ones.
+			c.BreaksForward = true
+		case change.OldType:
+			c.BreaksBackward = true
+		default:
+			c.BreaksBackward, c.BreaksForward = true, true
+		}
+	}
+	return c
+}
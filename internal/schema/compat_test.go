@@ -0,0 +1,61 @@
+package schema
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	base := &Schema{Fields: []*Field{
+		{Name: "id", Type: TypeString, Required: true},
+		{Name: "amount", Type: TypeInteger, Required: true},
+		{Name: "note", Type: TypeString},
+	}}
+
+	cases := []struct {
+		name              string
+		fields            []*Field
+		backward, forward bool
+	}{
+		{"optional field added", append(base.Clone().Fields, &Field{Name: "tag", Type: TypeString}), true, true},
+		{"required field added", append(base.Clone().Fields, &Field{Name: "tag", Type: TypeString, Required: true}), false, true},
+		{"optional field removed", base.Clone().Fields[:2], true, true},
+		{"required field removed", []*Field{base.Fields[0], base.Fields[2]}, true, false},
+		{"type widened", []*Field{base.Fields[0], {Name: "amount", Type: TypeNumber, Required: true}, base.Fields[2]}, true, false},
+		{"type narrowed", []*Field{base.Fields[0], base.Fields[1], {Name: "note", Type: TypeTimestamp}}, false, true},
+		{"type replaced", []*Field{base.Fields[0], {Name: "amount", Type: TypeBoolean, Required: true}, base.Fields[2]}, false, false},
+		{"field made required", []*Field{base.Fields[0], base.Fields[1], {Name: "note", Type: TypeString, Required: true}}, false, true},
+		{"note restricted to an enum", []*Field{base.Fields[0], base.Fields[1], {Name: "note", Type: TypeString, Enum: []string{"a"}}}, false, true},
+	}
+	for _, c := range cases {
+		t.Run("Given "+c.name+", it should classify compatibility", func(t *testing.T) {
+			report := Compare(base, &Schema{Fields: c.fields})
+
+			if len(report.Changes) != 1 {
+				t.Fatalf("Expected a single change, got %+v", report.Changes)
+			}
+			if report.BackwardCompatible != c.backward || report.ForwardCompatible != c.forward {
+				t.Errorf("Expected backward=%v forward=%v, got %+v", c.backward, c.forward, report)
+			}
+		})
+	}
+
+	t.Run("Given enum symbols and union variants, it should classify compatibility", func(t *testing.T) {
+		symbols := &Schema{Fields: []*Field{{Name: "status", Type: TypeString, Required: true, Enum: []string{"PENDING"}}}}
+		variants := &Schema{Discriminator: "type", Variants: []*Variant{{Value: "created", Fields: base.Clone().Fields}}}
+		added := func(s *Schema) *Schema {
+			c := s.Clone()
+			if len(c.Variants) > 0 {
+				c.Variants = append(c.Variants, &Variant{Value: "deleted", Fields: base.Clone().Fields})
+			} else {
+				c.Fields[0].Enum = append(c.Fields[0].Enum, "SETTLED")
+			}
+			return c
+		}
+		for _, s := range []*Schema{symbols, variants} {
+			if report := Compare(s, added(s)); !report.BackwardCompatible || report.ForwardCompatible {
+				t.Errorf("Expected an addition to break forward compatibility only, got %+v", report)
+			}
+			if report := Compare(added(s), s); report.BackwardCompatible || !report.ForwardCompatible {
+				t.Errorf("Expected a removal to break backward compatibility only, got %+v", report)
+			}
+		}
+	})
+}
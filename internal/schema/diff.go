@@ -0,0 +1,165 @@
+package schema
+
+import "slices"
+
+// ChangeKind classifies a difference between two schemas.
+type ChangeKind string
+
+const (
+	FieldAdded      ChangeKind = "field_added"
+	FieldRemoved    ChangeKind = "field_removed"
+	TypeChanged     ChangeKind = "type_changed"
+	RequiredChanged ChangeKind = "required_changed"
+	EnumAdded       ChangeKind = "enum_added"
+	EnumRemoved     ChangeKind = "enum_removed"
+	SymbolAdded     ChangeKind = "symbol_added"
+	SymbolRemoved   ChangeKind = "symbol_removed"
+	VariantAdded    ChangeKind = "variant_added"
+	VariantRemoved  ChangeKind = "variant_removed"
+)
+
+// Change is a single difference between two schemas, located by the dot
+// separated path of the field. Symbol is the enum symbol, or the
+// discriminator value of the union variant, that was added or removed.
+type Change struct {
+	Kind        ChangeKind `json:"kind"`
+	Path        string     `json:"path"`
+	Symbol      string     `json:"symbol,omitempty"`
+	OldType     Type       `json:"old_type,omitempty"`
+	NewType     Type       `json:"new_type,omitempty"`
+	OldRequired bool       `json:"old_required"`
+	NewRequired bool       `json:"new_required"`
+}
+
+// Diff lists the changes that turn the old schema into the new one. Fields of
+// records, array elements and map values are compared recursively; a field
+// whose type changed is reported once without descending into it. Union
+// variants are added or removed under the path of the discriminator, and the
+// fields of variants in both schemas are compared under their value.
+func Diff(old, new *Schema) []Change {
+	changes := diffFields("", old.Fields, new.Fields)
+	discriminator := new.Discriminator
+	if discriminator == "" {
+		discriminator = old.Discriminator
+	}
+	for _, variant := range old.Variants {
+		other := findVariant(new.Variants, variant.Value)
+		if other == nil {
+			changes = append(changes, Change{Kind: VariantRemoved, Path: discriminator, Symbol: variant.Value})
+			continue
+		}
+		changes = append(changes, diffFields(variant.Value, variant.Fields, other.Fields)...)
+	}
+	for _, variant := range new.Variants {
+		if findVariant(old.Variants, variant.Value) == nil {
+			changes = append(changes, Change{Kind: VariantAdded, Path: discriminator, Symbol: variant.Value})
+		}
+	}
+	return changes
+}
+
+func findVariant(variants []*Variant, value string) *Variant {
+	for _, variant := range variants {
+		if variant.Value == value {
+			return variant
+		}
+	}
+	return nil
+}
+
+func diffFields(prefix string, old, new []*Field) []Change {
+	var changes []Change
+	for _, field := range old {
+		path := joinPath(prefix, field.Name)
+		other := findField(new, field.Name)
+		if other == nil {
+			changes = append(changes, Change{Kind: FieldRemoved, Path: path, OldType: field.Type, OldRequired: field.Required})
+			continue
+		}
+		changes = append(changes, diffField(path, field, other)...)
+	}
+	for _, field := range new {
+		if findField(old, field.Name) == nil {
+			path := joinPath(prefix, field.Name)
+			changes = append(changes, Change{Kind: FieldAdded, Path: path, NewType: field.Type, NewRequired: field.Required})
+		}
+	}
+	return changes
+}
+
+func diffField(path string, old, new *Field) []Change {
+	var changes []Change
+	if old.Required != new.Required {
+		changes = append(changes, Change{Kind: RequiredChanged, Path: path, OldType: old.Type, NewType: new.Type,
+			OldRequired: old.Required, NewRequired: new.Required})
+	}
+	if old.Type != new.Type {
+		return append(changes, Change{Kind: TypeChanged, Path: path, OldType: old.Type, NewType: new.Type,
+			OldRequired: old.Required, NewRequired: new.Required})
+	}
+	switch old.Type {
+	case TypeString:
+		changes = append(changes, diffEnum(path, old, new)...)
+	case TypeRecord:
+		changes = append(changes, diffFields(path, old.Fields, new.Fields)...)
+	case TypeMap:
+		if old.Values != nil && new.Values != nil {
+			for _, change := range diffField(path, old.Values, new.Values) {
+				// Only the shape of map values matters, like array elements.
+				if change.Kind != RequiredChanged || change.Path != path {
+					changes = append(changes, change)
+				}
+			}
+		}
+	case TypeArray:
+		if old.Items != nil && new.Items != nil {
+			items := diffField(path, old.Items, new.Items)
+			for _, change := range items {
+				// Array elements are always present, so only their shape matters.
+				if change.Kind != RequiredChanged || change.Path != path {
+					changes = append(changes, change)
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// diffEnum lists the symbols added to and removed from the enum of a string
+// field, or whether the field became or stopped being an enum.
+func diffEnum(path string, old, new *Field) []Change {
+	base := Change{Path: path, OldType: old.Type, NewType: new.Type, OldRequired: old.Required, NewRequired: new.Required}
+	switch {
+	case len(old.Enum) == 0 && len(new.Enum) == 0:
+		return nil
+	case len(old.Enum) == 0:
+		base.Kind = EnumAdded
+		return []Change{base}
+	case len(new.Enum) == 0:
+		base.Kind = EnumRemoved
+		return []Change{base}
+	}
+	var changes []Change
+	for _, symbol := range old.Enum {
+		if !slices.Contains(new.Enum, symbol) {
+			change := base
+			change.Kind, change.Symbol = SymbolRemoved, symbol
+			changes = append(changes, change)
+		}
+	}
+	for _, symbol := range new.Enum {
+		if !slices.Contains(old.Enum, symbol) {
+			change := base
+			change.Kind, change.Symbol = SymbolAdded, symbol
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
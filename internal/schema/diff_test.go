@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("Given added, removed and retyped fields, it should list each change", func(t *testing.T) {
+		old := &Schema{Fields: []*Field{
+			{Name: "id", Type: TypeString, Required: true},
+			{Name: "amount", Type: TypeInteger, Required: true},
+			{Name: "customer", Type: TypeRecord, Required: true, Fields: []*Field{
+				{Name: "email", Type: TypeString, Required: true},
+			}},
+		}}
+		new := &Schema{Fields: []*Field{
+			{Name: "id", Type: TypeString},
+			{Name: "amount", Type: TypeNumber, Required: true},
+			{Name: "customer", Type: TypeRecord, Required: true, Fields: []*Field{
+				{Name: "name", Type: TypeString, Required: true},
+			}},
+		}}
+		expected := []Change{
+			{Kind: RequiredChanged, Path: "id", OldType: TypeString, NewType: TypeString, OldRequired: true},
+			{Kind: TypeChanged, Path: "amount", OldType: TypeInteger, NewType: TypeNumber, OldRequired: true, NewRequired: true},
+			{Kind: FieldRemoved, Path: "customer.email", OldType: TypeString, OldRequired: true},
+			{Kind: FieldAdded, Path: "customer.name", NewType: TypeString, NewRequired: true},
+		}
+
+		if changes := Diff(old, new); !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, changes)
+		}
+	})
+
+	t.Run("Given equal schemas, it should report no changes", func(t *testing.T) {
+		s := &Schema{Fields: []*Field{{Name: "tags", Type: TypeArray, Required: true, Items: &Field{Type: TypeString, Required: true}}}}
+
+		if changes := Diff(s, s.Clone()); len(changes) != 0 {
+			t.Errorf("Expected no changes, got %+v", changes)
+		}
+	})
+
+	t.Run("Given changed enum symbols, it should list each symbol", func(t *testing.T) {
+		old := &Schema{Fields: []*Field{
+			{Name: "status", Type: TypeString, Required: true, Enum: []string{"PENDING", "SETTLED"}},
+			{Name: "channel", Type: TypeString, Required: true},
+		}}
+		new := &Schema{Fields: []*Field{
+			{Name: "status", Type: TypeString, Required: true, Enum: []string{"SETTLED", "REFUNDED"}},
+			{Name: "channel", Type: TypeString, Required: true, Enum: []string{"WEB"}},
+		}}
+		base := Change{OldType: TypeString, NewType: TypeString, OldRequired: true, NewRequired: true}
+		removed, added, restricted := base, base, base
+		removed.Kind, removed.Path, removed.Symbol = SymbolRemoved, "status", "PENDING"
+		added.Kind, added.Path, added.Symbol = SymbolAdded, "status", "REFUNDED"
+		restricted.Kind, restricted.Path = EnumAdded, "channel"
+		expected := []Change{removed, added, restricted}
+
+		if changes := Diff(old, new); !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, changes)
+		}
+	})
+
+	t.Run("Given changed union variants, it should list each variant and compare the shared ones", func(t *testing.T) {
+		old := &Schema{Discriminator: "type", Variants: []*Variant{
+			{Value: "created", Fields: []*Field{{Name: "id", Type: TypeString, Required: true}}},
+			{Value: "deleted", Fields: []*Field{{Name: "id", Type: TypeString, Required: true}}},
+		}}
+		new := &Schema{Discriminator: "type", Variants: []*Variant{
+			{Value: "created", Fields: []*Field{{Name: "id", Type: TypeInteger, Required: true}}},
+			{Value: "updated", Fields: []*Field{{Name: "id", Type: TypeString, Required: true}}},
+		}}
+		expected := []Change{
+			{Kind: TypeChanged, Path: "created.id", OldType: TypeString, NewType: TypeInteger, OldRequired: true, NewRequired: true},
+			{Kind: VariantRemoved, Path: "type", Symbol: "deleted"},
+			{Kind: VariantAdded, Path: "type", Symbol: "updated"},
+		}
+
+		if changes := Diff(old, new); !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, changes)
+		}
+	})
+}
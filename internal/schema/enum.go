@@ -0,0 +1,204 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultEnumThreshold is the largest number of distinct values a string
+	// field may take to be proposed as an enum.
+	DefaultEnumThreshold = 10
+	// DefaultEnumMinSamples is the number of values a field must have been
+	// seen with before it is proposed as an enum, so that a handful of
+	// identifiers is not mistaken for one.
+	DefaultEnumMinSamples = 20
+)
+
+// EnumStatus is the state of an enum suggestion.
+type EnumStatus string
+
+const (
+	EnumProposed  EnumStatus = "proposed"
+	EnumConfirmed EnumStatus = "confirmed"
+	EnumRejected  EnumStatus = "rejected"
+)
+
+// EnumSuggestion proposes to restrict the string field at Path to Symbols.
+type EnumSuggestion struct {
+	Path    string     `json:"path"`
+	Symbols []string   `json:"symbols"`
+	Samples int        `json:"samples"`
+	Status  EnumStatus `json:"status"`
+}
+
+// EnumDetector counts the distinct values of the string fields of parsed
+// messages and proposes an enum for the fields taking at most Threshold
+// values across at least MinSamples samples. Proposals can be confirmed,
+// which freezes their symbols, or rejected.
+type EnumDetector struct {
+	Threshold  int
+	MinSamples int
+
+	values    map[string]map[string]bool
+	samples   map[string]int
+	overflow  map[string]bool
+	decisions map[string]EnumSuggestion
+}
+
+// NewEnumDetector returns a detector using the default threshold and sample
+// count.
+func NewEnumDetector() *EnumDetector {
+	return &EnumDetector{
+		Threshold:  DefaultEnumThreshold,
+		MinSamples: DefaultEnumMinSamples,
+		values:     map[string]map[string]bool{},
+		samples:    map[string]int{},
+		overflow:   map[string]bool{},
+		decisions:  map[string]EnumSuggestion{},
+	}
+}
+
+// Observe counts the string values of a message parsed by ParseMessage,
+// using the field paths of the schema Infer builds for it.
+func (d *EnumDetector) Observe(data map[string]interface{}) {
+	visitValues("", data, d.observe)
+}
+
+func (d *EnumDetector) observe(path string, value interface{}, text bool) {
+	v, ok := value.(string)
+	if !ok {
+		return
+	}
+	if text {
+		// XML character data is only a string when it is not a number,
+		// boolean or timestamp.
+		if v = strings.TrimSpace(v); v != "" && inferText(v).Type == TypeString {
+			d.add(path, v)
+		}
+		return
+	}
+	if !isTimestamp(v) {
+		d.add(path, v)
+	}
+}
+
+func (d *EnumDetector) add(path, value string) {
+	d.samples[path]++
+	if d.overflow[path] {
+		return
+	}
+	values, ok := d.values[path]
+	if !ok {
+		values = map[string]bool{}
+		d.values[path] = values
+	}
+	values[value] = true
+	if len(values) > d.threshold() {
+		// The field is not an enum; stop remembering its values.
+		d.overflow[path] = true
+		delete(d.values, path)
+	}
+}
+
+func (d *EnumDetector) threshold() int {
+	if d.Threshold <= 0 {
+		return DefaultEnumThreshold
+	}
+	return d.Threshold
+}
+
+// suggestion returns the decision taken for a path, or else its proposal.
+func (d *EnumDetector) suggestion(path string) (EnumSuggestion, bool) {
+	if decision, ok := d.decisions[path]; ok {
+		decision.Samples = d.samples[path]
+		return decision, true
+	}
+	return d.proposal(path)
+}
+
+// proposal returns the enum made of the values observed for a path, if the
+// field qualifies as one.
+func (d *EnumDetector) proposal(path string) (EnumSuggestion, bool) {
+	values := d.values[path]
+	if d.overflow[path] || len(values) == 0 || len(values) > d.threshold() || d.samples[path] < d.MinSamples {
+		return EnumSuggestion{}, false
+	}
+	symbols := make([]string, 0, len(values))
+	for value := range values {
+		symbols = append(symbols, value)
+	}
+	sort.Strings(symbols)
+	return EnumSuggestion{Path: path, Symbols: symbols, Samples: d.samples[path], Status: EnumProposed}, true
+}
+
+// Suggestions lists the proposed, confirmed and rejected enums sorted by path.
+func (d *EnumDetector) Suggestions() []EnumSuggestion {
+	paths := map[string]bool{}
+	for path := range d.values {
+		paths[path] = true
+	}
+	for path := range d.decisions {
+		paths[path] = true
+	}
+	suggestions := []EnumSuggestion{}
+	for path := range paths {
+		if suggestion, ok := d.suggestion(path); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].Path < suggestions[j].Path })
+	return suggestions
+}
+
+// Decide confirms, rejects or, with EnumProposed, reopens the suggestion for a
+// path. Only current proposals can be confirmed; any observed string field
+// can be rejected.
+func (d *EnumDetector) Decide(path string, status EnumStatus) (EnumSuggestion, error) {
+	switch status {
+	case EnumProposed:
+		delete(d.decisions, path)
+		suggestion, ok := d.proposal(path)
+		if !ok {
+			return EnumSuggestion{}, fmt.Errorf("no enum proposed for %q", path)
+		}
+		return suggestion, nil
+	case EnumConfirmed:
+		if decision, ok := d.decisions[path]; ok && decision.Status == EnumConfirmed {
+			decision.Samples = d.samples[path]
+			return decision, nil
+		}
+		suggestion, ok := d.proposal(path)
+		if !ok {
+			return EnumSuggestion{}, fmt.Errorf("no enum proposed for %q", path)
+		}
+		suggestion.Status = EnumConfirmed
+		d.decisions[path] = suggestion
+		return suggestion, nil
+	case EnumRejected:
+		if d.samples[path] == 0 {
+			return EnumSuggestion{}, fmt.Errorf("no string values observed for %q", path)
+		}
+		suggestion := EnumSuggestion{Path: path, Symbols: []string{}, Samples: d.samples[path], Status: EnumRejected}
+		d.decisions[path] = suggestion
+		return suggestion, nil
+	default:
+		return EnumSuggestion{}, fmt.Errorf("unknown enum status %q", status)
+	}
+}
+
+// Apply sets the symbols of the proposed and confirmed enums on the string
+// fields of the schema.
+func (d *EnumDetector) Apply(s *Schema) {
+	for _, suggestion := range d.Suggestions() {
+		if suggestion.Status == EnumRejected {
+			continue
+		}
+		s.lookupEach(suggestion.Path, func(field *Field) {
+			if field.Element().Type == TypeString && field.Element().Enum == nil {
+				field.Element().Enum = append([]string(nil), suggestion.Symbols...)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func transactionSample(i int) map[string]interface{} {
+	statuses := []string{"PENDING", "SETTLED", "FAILED"}
+	return map[string]interface{}{
+		"ID":       "tx-" + strconv.Itoa(i),
+		"Status":   map[string]interface{}{TextField: statuses[i%len(statuses)]},
+		"Amount":   map[string]interface{}{TextField: "10.50"},
+		"Created":  "2024-01-01T10:00:00Z",
+		"Currency": map[string]interface{}{"@code": "EUR", TextField: "Euro"},
+	}
+}
+
+func TestEnumDetector(t *testing.T) {
+	t.Run("Given low-cardinality fields, it should propose enums with the observed symbols", func(t *testing.T) {
+		d := NewEnumDetector()
+		for i := 0; i < 30; i++ {
+			d.Observe(transactionSample(i))
+		}
+
+		expected := []EnumSuggestion{
+			{Path: "Currency.#text", Symbols: []string{"Euro"}, Samples: 30, Status: EnumProposed},
+			{Path: "Currency.code", Symbols: []string{"EUR"}, Samples: 30, Status: EnumProposed},
+			{Path: "Status", Symbols: []string{"FAILED", "PENDING", "SETTLED"}, Samples: 30, Status: EnumProposed},
+		}
+		if result := d.Suggestions(); !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, result)
+		}
+	})
+
+	t.Run("Given too few samples, it should not propose an enum", func(t *testing.T) {
+		d := NewEnumDetector()
+		for i := 0; i < 5; i++ {
+			d.Observe(transactionSample(i))
+		}
+		if result := d.Suggestions(); len(result) != 0 {
+			t.Errorf("Expected no suggestions, got %+v", result)
+		}
+	})
+
+	t.Run("Given a lower threshold, it should not propose fields above it", func(t *testing.T) {
+		d := NewEnumDetector()
+		d.Threshold = 2
+		for i := 0; i < 30; i++ {
+			d.Observe(transactionSample(i))
+		}
+		for _, suggestion := range d.Suggestions() {
+			if suggestion.Path == "Status" {
+				t.Errorf("Expected Status not to be proposed, got %+v", suggestion)
+			}
+		}
+	})
+
+	t.Run("Given a confirmed enum, it should freeze its symbols and apply them", func(t *testing.T) {
+		d := NewEnumDetector()
+		for i := 0; i < 30; i++ {
+			d.Observe(transactionSample(i))
+		}
+		if _, err := d.Decide("Status", EnumConfirmed); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		d.Observe(map[string]interface{}{"Status": "REFUNDED"})
+
+		s := &Schema{Fields: []*Field{{Name: "Status", Type: TypeString, Required: true}}}
+		d.Apply(s)
+		if !reflect.DeepEqual(s.Field("Status").Enum, []string{"FAILED", "PENDING", "SETTLED"}) {
+			t.Errorf("Unexpected symbols: %v", s.Field("Status").Enum)
+		}
+	})
+
+	t.Run("Given a rejected enum, it should not apply it", func(t *testing.T) {
+		d := NewEnumDetector()
+		for i := 0; i < 30; i++ {
+			d.Observe(transactionSample(i))
+		}
+		suggestion, err := d.Decide("Status", EnumRejected)
+		if err != nil || suggestion.Status != EnumRejected {
+			t.Fatalf("Unexpected result: %+v, %v", suggestion, err)
+		}
+
+		s := &Schema{Fields: []*Field{{Name: "Status", Type: TypeString, Required: true}}}
+		d.Apply(s)
+		if s.Field("Status").Enum != nil {
+			t.Errorf("Expected no symbols, got %v", s.Field("Status").Enum)
+		}
+	})
+
+	t.Run("Given a high-cardinality field, it should refuse to confirm it", func(t *testing.T) {
+		d := NewEnumDetector()
+		for i := 0; i < 30; i++ {
+			d.Observe(transactionSample(i))
+		}
+		if _, err := d.Decide("ID", EnumConfirmed); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+}
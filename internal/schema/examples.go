@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultExampleSize is the number of example values kept per field.
+const DefaultExampleSize = 5
+
+// MaskAll masks the examples of every field when passed to SetMasked.
+const MaskAll = "*"
+
+// ExampleSampler keeps a bounded reservoir sample of the values of every field
+// of parsed messages, so that each value seen has the same chance of being
+// kept however many are observed. Values of masked fields are stored with
+// their letters and digits replaced, preserving only their shape.
+type ExampleSampler struct {
+	size     int
+	random   *rand.Rand
+	masked   map[string]bool
+	seen     map[string]int
+	examples map[string][]string
+}
+
+// NewExampleSampler returns a sampler keeping up to size values per field.
+func NewExampleSampler(size int, random *rand.Rand) *ExampleSampler {
+	if size <= 0 {
+		size = DefaultExampleSize
+	}
+	return &ExampleSampler{
+		size:     size,
+		random:   random,
+		masked:   map[string]bool{},
+		seen:     map[string]int{},
+		examples: map[string][]string{},
+	}
+}
+
+// SetMasked replaces the paths of the fields whose examples are masked;
+// MaskAll masks every field.
+func (e *ExampleSampler) SetMasked(paths []string) {
+	e.masked = map[string]bool{}
+	for _, path := range paths {
+		e.masked[path] = true
+	}
+}
+
+func (e *ExampleSampler) isMasked(path string) bool {
+	return e.masked[MaskAll] || e.masked[path]
+}
+
+// Observe samples the values of a message parsed by ParseMessage, using the
+// field paths of the schema Infer builds for it.
+func (e *ExampleSampler) Observe(data map[string]interface{}) {
+	visitValues("", data, e.observe)
+}
+
+func (e *ExampleSampler) observe(path string, value interface{}, text bool) {
+	var example string
+	switch v := value.(type) {
+	case string:
+		example = v
+		if text {
+			if example = strings.TrimSpace(v); example == "" {
+				return
+			}
+		}
+	case float64:
+		example = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		example = strconv.FormatBool(v)
+	default:
+		return
+	}
+	if e.isMasked(path) {
+		example = Mask(example)
+	}
+
+	// Algorithm R: the n-th value replaces a kept one with probability size/n.
+	e.seen[path]++
+	if len(e.examples[path]) < e.size {
+		e.examples[path] = append(e.examples[path], example)
+	} else if i := e.random.Intn(e.seen[path]); i < e.size {
+		e.examples[path][i] = example
+	}
+}
+
+// Examples returns the values sampled for a field path, masked if the field
+// is masked now.
+func (e *ExampleSampler) Examples(path string) []string {
+	examples := append([]string(nil), e.examples[path]...)
+	if e.isMasked(path) {
+		for i, example := range examples {
+			examples[i] = Mask(example)
+		}
+	}
+	return examples
+}
+
+// Paths lists the sampled field paths in alphabetical order.
+func (e *ExampleSampler) Paths() []string {
+	paths := make([]string, 0, len(e.examples))
+	for path := range e.examples {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Apply sets the sampled examples on the fields of the schema.
+func (e *ExampleSampler) Apply(s *Schema) {
+	for path := range e.examples {
+		examples := e.Examples(path)
+		s.lookupEach(path, func(field *Field) {
+			field.Element().Examples = examples
+		})
+	}
+}
+
+// Mask replaces the letters of a value with "x" or "X" and its digits with
+// "9", keeping punctuation and length so the format stays recognisable.
+func Mask(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsUpper(r):
+			return 'X'
+		case unicode.IsLetter(r):
+			return 'x'
+		case unicode.IsDigit(r):
+			return '9'
+		default:
+			return r
+		}
+	}, value)
+}
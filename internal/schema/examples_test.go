@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestExampleSampler(t *testing.T) {
+	t.Run("Given few values, it should keep all of them", func(t *testing.T) {
+		e := NewExampleSampler(5, rand.New(rand.NewSource(1)))
+		e.Observe(map[string]interface{}{"Amount": map[string]interface{}{TextField: " 10.50 "}, "Paid": true})
+		e.Observe(map[string]interface{}{"Amount": map[string]interface{}{TextField: "3"}, "Paid": false})
+
+		if result := e.Examples("Amount"); !reflect.DeepEqual(result, []string{"10.50", "3"}) {
+			t.Errorf("Expected [10.50 3], got %v", result)
+		}
+		if !reflect.DeepEqual(e.Paths(), []string{"Amount", "Paid"}) {
+			t.Errorf("Expected [Amount Paid], got %v", e.Paths())
+		}
+	})
+
+	t.Run("Given many values, it should keep a bounded sample of them", func(t *testing.T) {
+		e := NewExampleSampler(3, rand.New(rand.NewSource(1)))
+		seen := map[string]bool{}
+		for i := 0; i < 1000; i++ {
+			value := strconv.Itoa(i)
+			seen[value] = true
+			e.Observe(map[string]interface{}{"Items": []interface{}{map[string]interface{}{"Price": float64(i)}}})
+		}
+
+		result := e.Examples("Items.Price")
+		if len(result) != 3 {
+			t.Fatalf("Expected 3 examples, got %v", result)
+		}
+		for _, example := range result {
+			if !seen[example] {
+				t.Errorf("Unexpected example %q", example)
+			}
+		}
+	})
+
+	t.Run("Given masked fields, it should mask their examples", func(t *testing.T) {
+		e := NewExampleSampler(5, rand.New(rand.NewSource(1)))
+		e.Observe(map[string]interface{}{"Email": "Ann.Lee@example.com", "Currency": "EUR"})
+		e.SetMasked([]string{"Email"})
+
+		if result := e.Examples("Email"); !reflect.DeepEqual(result, []string{"Xxx.Xxx@xxxxxxx.xxx"}) {
+			t.Errorf("Expected a masked email, got %v", result)
+		}
+		if result := e.Examples("Currency"); !reflect.DeepEqual(result, []string{"EUR"}) {
+			t.Errorf("Expected [EUR], got %v", result)
+		}
+	})
+
+	t.Run("Given a schema, it should attach the examples to its fields", func(t *testing.T) {
+		e := NewExampleSampler(5, rand.New(rand.NewSource(1)))
+		e.SetMasked([]string{MaskAll})
+		e.Observe(map[string]interface{}{"Customer": map[string]interface{}{"Phone": "+1 555-0100"}})
+
+		s := &Schema{Fields: []*Field{{Name: "Customer", Type: TypeRecord, Fields: []*Field{{Name: "Phone", Type: TypeString}}}}}
+		e.Apply(s)
+		if result := s.Lookup("Customer.Phone").Examples; !reflect.DeepEqual(result, []string{"+9 999-9999"}) {
+			t.Errorf("Expected [+9 999-9999], got %v", result)
+		}
+	})
+}
@@ -0,0 +1,417 @@
+package schema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the formats recognised as timestamps in string values.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// Infer builds a schema from a message parsed by ParseMessage. Every field
+// present in the message is marked required; nil values produce optional
+// fields of type null. Use Merge to combine schemas inferred from several
+// messages of the same topic.
+func Infer(name string, data map[string]interface{}) *Schema {
+	return &Schema{Name: name, Fields: inferFields(data)}
+}
+
+// FromMapSchema converts the type names produced by MapSchema into a schema.
+func FromMapSchema(name string, mapped map[string]interface{}) *Schema {
+	return &Schema{Name: name, Fields: mappedFields(mapped)}
+}
+
+func inferFields(data map[string]interface{}) []*Field {
+	fields := make([]*Field, 0, len(data))
+	for _, key := range sortedKeys(data) {
+		if key == TextField && strings.TrimSpace(textOf(data[key])) == "" {
+			continue // indentation between XML child elements
+		}
+		if text, ok := data[key].(string); ok && (key == TextField || strings.HasPrefix(key, AttributePrefix)) {
+			field := inferText(text)
+			field.Name = strings.TrimPrefix(key, AttributePrefix)
+			field.Attribute = key != TextField
+			fields = append(fields, field)
+			continue
+		}
+		field := inferValue(data[key])
+		field.Name = key
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func inferValue(value interface{}) *Field {
+	switch v := value.(type) {
+	case nil:
+		return &Field{Type: TypeNull}
+	case bool:
+		return &Field{Type: TypeBoolean, Required: true}
+	case float64:
+		if v == float64(int64(v)) {
+			return &Field{Type: TypeInteger, Required: true}
+		}
+		return &Field{Type: TypeNumber, Required: true}
+	case int, int32, int64:
+		return &Field{Type: TypeInteger, Required: true}
+	case float32:
+		return &Field{Type: TypeNumber, Required: true}
+	case string:
+		if isTimestamp(v) {
+			return &Field{Type: TypeTimestamp, Required: true}
+		}
+		return &Field{Type: TypeString, Required: true}
+	case []interface{}:
+		field := &Field{Type: TypeArray, Required: true}
+		for _, item := range v {
+			field.Items = mergeField(field.Items, inferValue(item))
+		}
+		if field.Items == nil {
+			field.Items = &Field{Type: TypeAny}
+		}
+		field.Items.Required = true
+		return field
+	case map[string]interface{}:
+		if text, ok := xmlText(v); ok {
+			return inferText(text)
+		}
+		if IsDynamicMap(v) {
+			return &Field{Type: TypeMap, Required: true, Values: mapValues(v)}
+		}
+		return &Field{Type: TypeRecord, Required: true, Fields: inferFields(v)}
+	default:
+		return &Field{Type: TypeAny, Required: true}
+	}
+}
+
+// xmlText reports whether the map is an XML element holding only text.
+func xmlText(element map[string]interface{}) (string, bool) {
+	if len(element) != 1 {
+		return "", false
+	}
+	text, ok := element[TextField].(string)
+	return text, ok
+}
+
+// visitValues calls fn with the path and value of every scalar in a message
+// parsed by ParseMessage, using the field paths of the schema Infer builds for
+// it. Array elements and the values of dynamic maps are visited under the
+// path of the field holding them and text marks XML character data.
+func visitValues(prefix string, data map[string]interface{}, fn func(path string, value interface{}, text bool)) {
+	for key, value := range data {
+		path := joinPath(prefix, strings.TrimPrefix(key, AttributePrefix))
+		if _, ok := value.(string); ok && (key == TextField || strings.HasPrefix(key, AttributePrefix)) {
+			fn(path, value, true)
+			continue
+		}
+		visitValue(path, value, fn)
+	}
+}
+
+func visitValue(path string, value interface{}, fn func(string, interface{}, bool)) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			visitValue(path, item, fn)
+		}
+	case map[string]interface{}:
+		if text, ok := xmlText(v); ok {
+			fn(path, text, true)
+			return
+		}
+		if IsDynamicMap(v) {
+			for _, value := range v {
+				visitValue(path, value, fn)
+			}
+			return
+		}
+		visitValues(path, v, fn)
+	default:
+		fn(path, value, false)
+	}
+}
+
+// inferText infers the simple type of XML character data.
+func inferText(text string) *Field {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "true" || text == "false":
+		return &Field{Type: TypeBoolean, Required: true}
+	case isInteger(text):
+		return &Field{Type: TypeInteger, Required: true}
+	case isDecimal(text):
+		precision, scale := decimalDigits(text)
+		return &Field{Type: TypeDecimal, Required: true, Precision: precision, Scale: scale}
+	case isTimestamp(text):
+		return &Field{Type: TypeTimestamp, Required: true}
+	default:
+		return &Field{Type: TypeString, Required: true}
+	}
+}
+
+func mappedFields(mapped map[string]interface{}) []*Field {
+	fields := make([]*Field, 0, len(mapped))
+	for _, key := range sortedKeys(mapped) {
+		field := &Field{Name: key, Required: true}
+		switch v := mapped[key].(type) {
+		case map[string]interface{}:
+			if values, ok := v[MapValuesKey]; ok && len(v) == 1 {
+				field.Type = TypeMap
+				field.Values = mappedFields(map[string]interface{}{"": values})[0]
+				field.Values.Name = ""
+				break
+			}
+			field.Type = TypeRecord
+			field.Fields = mappedFields(v)
+		case string:
+			field.Type = goType(v)
+			if field.Type == TypeArray {
+				field.Items = &Field{Type: TypeAny, Required: true}
+			}
+			if field.Type == TypeNull {
+				field.Required = false
+			}
+		default:
+			field.Type = TypeAny
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// goType translates a reflect type name as printed by MapSchema.
+func goType(name string) Type {
+	switch {
+	case name == "<nil>":
+		return TypeNull
+	case name == "string":
+		return TypeString
+	case name == "bool":
+		return TypeBoolean
+	case name == "float32" || name == "float64":
+		return TypeNumber
+	case strings.HasPrefix(name, "int") || strings.HasPrefix(name, "uint"):
+		return TypeInteger
+	case name == "time.Time":
+		return TypeTimestamp
+	case strings.HasPrefix(name, "[]"):
+		return TypeArray
+	case strings.HasPrefix(name, "map["):
+		return TypeRecord
+	default:
+		return TypeAny
+	}
+}
+
+// Merge combines two schemas inferred from messages of the same topic. Fields
+// that are missing from either side become optional and conflicting types are
+// widened.
+func Merge(a, b *Schema) *Schema {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	merged := *a
+	merged.Fields = mergeFields(a.Fields, b.Fields)
+	return &merged
+}
+
+func mergeFields(a, b []*Field) []*Field {
+	merged := make([]*Field, 0, len(a)+len(b))
+	for _, field := range a {
+		other := findField(b, field.Name)
+		if other == nil {
+			optional := field.clone()
+			optional.Required = false
+			merged = append(merged, optional)
+			continue
+		}
+		merged = append(merged, mergeField(field, other))
+	}
+	for _, field := range b {
+		if findField(a, field.Name) == nil {
+			optional := field.clone()
+			optional.Required = false
+			merged = append(merged, optional)
+		}
+	}
+	return merged
+}
+
+func mergeField(a, b *Field) *Field {
+	if a == nil {
+		return b.clone()
+	}
+	if b == nil {
+		return a.clone()
+	}
+	merged := &Field{Name: a.Name, Required: a.Required && b.Required, Attribute: a.Attribute}
+	switch {
+	case a.Type == b.Type:
+		merged.Type = a.Type
+	case a.Type == TypeNull:
+		merged.Type, merged.Required = b.Type, false
+		a = b
+	case b.Type == TypeNull:
+		merged.Type, merged.Required = a.Type, false
+		b = a
+	case a.Type == TypeMap && b.Type == TypeRecord && foldsInto(b, a) || a.Type == TypeRecord && b.Type == TypeMap && foldsInto(a, b):
+		// A record seen with keys that did not look dynamic yet is folded
+		// into the map values; other records make the field any.
+		merged.Type = TypeMap
+		merged.Values = mergeField(mapRecordValues(a), mapRecordValues(b))
+		return merged
+	case a.Type == TypeArray || b.Type == TypeArray:
+		// XmlToMap only produces a slice for repeated elements, so a single
+		// occurrence of the same element is folded into the array.
+		merged.Type = TypeArray
+		merged.Items = mergeField(arrayItems(a), arrayItems(b))
+		return merged
+	default:
+		merged.Type = widen(a.Type, b.Type)
+	}
+	switch merged.Type {
+	case TypeRecord:
+		merged.Fields = mergeFields(a.Fields, b.Fields)
+	case TypeArray:
+		merged.Items = mergeField(a.Items, b.Items)
+	case TypeMap:
+		merged.Values = mergeField(a.Values, b.Values)
+	case TypeDecimal:
+		merged.Scale = max(a.Scale, b.Scale)
+		merged.Precision = max(a.Precision-a.Scale, b.Precision-b.Scale) + merged.Scale
+	}
+	return merged
+}
+
+func arrayItems(field *Field) *Field {
+	if field.Type == TypeArray {
+		return field.Items
+	}
+	item := field.clone()
+	item.Name, item.Required = "", true
+	return item
+}
+
+// foldsInto reports whether every field of record has the shape of the
+// values of a map field.
+func foldsInto(record, m *Field) bool {
+	for _, child := range record.Fields {
+		if m.Values == nil || !sameShape(child, m.Values) {
+			return false
+		}
+	}
+	return true
+}
+
+// mapRecordValues returns the values of a map field, or the merge of the
+// fields of a record field as if they were map values.
+func mapRecordValues(field *Field) *Field {
+	if field.Type == TypeMap {
+		return field.Values
+	}
+	var values *Field
+	for _, child := range field.Fields {
+		value := child.clone()
+		value.Name, value.Attribute = "", false
+		values = mergeField(values, value)
+	}
+	return values
+}
+
+// widen returns the narrowest type able to hold values of both types.
+func widen(a, b Type) Type {
+	numeric := map[Type]int{TypeInteger: 1, TypeDecimal: 2, TypeNumber: 3}
+	if numeric[a] > 0 && numeric[b] > 0 {
+		if numeric[a] > numeric[b] {
+			return a
+		}
+		return b
+	}
+	if isScalar(a) && isScalar(b) && (a == TypeString || b == TypeString || a == TypeTimestamp || b == TypeTimestamp) {
+		return TypeString
+	}
+	return TypeAny
+}
+
+func isScalar(t Type) bool {
+	return t != TypeRecord && t != TypeArray && t != TypeAny && t != TypeNull
+}
+
+func (f *Field) clone() *Field {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Enum = cloneStrings(f.Enum)
+	c.Examples = cloneStrings(f.Examples)
+	c.Tags = cloneStrings(f.Tags)
+	if f.Fields != nil {
+		c.Fields = make([]*Field, len(f.Fields))
+		for i, child := range f.Fields {
+			c.Fields[i] = child.clone()
+		}
+	}
+	c.Items = f.Items.clone()
+	c.Values = f.Values.clone()
+	if f.Ref != nil {
+		ref := *f.Ref
+		c.Ref = &ref
+	}
+	return &c
+}
+
+func isTimestamp(value string) bool {
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isInteger(text string) bool {
+	_, err := strconv.ParseInt(text, 10, 64)
+	return err == nil
+}
+
+func isDecimal(text string) bool {
+	whole, fraction, found := strings.Cut(strings.TrimPrefix(text, "-"), ".")
+	return found && whole != "" && fraction != "" && isDigits(whole) && isDigits(fraction)
+}
+
+func isDigits(text string) bool {
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func decimalDigits(text string) (precision, scale int) {
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(text, "-"), ".")
+	return len(whole) + len(fraction), len(fraction)
+}
+
+func textOf(value interface{}) string {
+	text, _ := value.(string)
+	return text
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	t.Run("Given a JSON message, it should infer field types", func(t *testing.T) {
+		data := map[string]interface{}{
+			"id":        "123",
+			"age":       float64(25),
+			"amount":    99.5,
+			"active":    true,
+			"createdAt": "2024-10-27T12:34:56Z",
+			"promo":     nil,
+		}
+		expected := []*Field{
+			{Name: "active", Type: TypeBoolean, Required: true},
+			{Name: "age", Type: TypeInteger, Required: true},
+			{Name: "amount", Type: TypeNumber, Required: true},
+			{Name: "createdAt", Type: TypeTimestamp, Required: true},
+			{Name: "id", Type: TypeString, Required: true},
+			{Name: "promo", Type: TypeNull},
+		}
+		result := Infer("User", data)
+
+		if !reflect.DeepEqual(result.Fields, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, result.Fields)
+		}
+	})
+
+	t.Run("Given XML text elements, it should infer simple types from their text", func(t *testing.T) {
+		data := map[string]interface{}{
+			"#text":  "\n  ",
+			"Amount": map[string]interface{}{"#text": "123.45"},
+			"Count":  map[string]interface{}{"#text": "7"},
+			"Name":   map[string]interface{}{"#text": "Alice"},
+		}
+		expected := []*Field{
+			{Name: "Amount", Type: TypeDecimal, Required: true, Precision: 5, Scale: 2},
+			{Name: "Count", Type: TypeInteger, Required: true},
+			{Name: "Name", Type: TypeString, Required: true},
+		}
+		result := Infer("Transaction", data)
+
+		if !reflect.DeepEqual(result.Fields, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, result.Fields)
+		}
+	})
+
+	t.Run("Given an array of records, it should merge the element schemas", func(t *testing.T) {
+		data := map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"sku": "a", "price": float64(1)},
+				map[string]interface{}{"sku": "b", "price": 2.5, "note": "gift"},
+			},
+		}
+		items := Infer("Order", data).Field("items")
+
+		if items == nil || items.Type != TypeArray {
+			t.Fatalf("Expected items to be an array, got %+v", items)
+		}
+		if price := items.Items.Fields; len(price) != 3 {
+			t.Fatalf("Expected 3 element fields, got %+v", price)
+		}
+		if field := Infer("Order", data).Lookup("items.price"); field.Type != TypeNumber {
+			t.Errorf("Expected price to widen to number, got %s", field.Type)
+		}
+		if field := Infer("Order", data).Lookup("items.note"); field.Required {
+			t.Errorf("Expected note to be optional")
+		}
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("Given fields missing from one message, it should mark them optional", func(t *testing.T) {
+		a := Infer("T", map[string]interface{}{"id": "1", "discount": 0.5})
+		b := Infer("T", map[string]interface{}{"id": "2"})
+		merged := Merge(a, b)
+
+		if !merged.Field("id").Required {
+			t.Errorf("Expected id to stay required")
+		}
+		if merged.Field("discount").Required {
+			t.Errorf("Expected discount to become optional")
+		}
+	})
+
+	t.Run("Given a single and a repeated XML element, it should merge them into an array", func(t *testing.T) {
+		single := Infer("T", map[string]interface{}{
+			"Item": map[string]interface{}{"Price": map[string]interface{}{"#text": "1.5"}},
+		})
+		repeated := Infer("T", map[string]interface{}{
+			"Item": []interface{}{
+				map[string]interface{}{"Price": map[string]interface{}{"#text": "10.25"}},
+				map[string]interface{}{"Price": map[string]interface{}{"#text": "3.5"}},
+			},
+		})
+		merged := Merge(single, repeated)
+
+		item := merged.Field("Item")
+		if item.Type != TypeArray || item.Items.Type != TypeRecord {
+			t.Fatalf("Expected an array of records, got %+v", item)
+		}
+		price := merged.Lookup("Item.Price")
+		if price.Precision != 4 || price.Scale != 2 {
+			t.Errorf("Expected decimal(4,2), got decimal(%d,%d)", price.Precision, price.Scale)
+		}
+	})
+}
+
+func TestFromMapSchema(t *testing.T) {
+	t.Run("Given MapSchema output, it should translate Go type names", func(t *testing.T) {
+		mapped := map[string]interface{}{
+			"id":    "string",
+			"age":   "int",
+			"tags":  "[]interface {}",
+			"promo": "<nil>",
+			"user":  map[string]interface{}{"active": "bool"},
+		}
+		result := FromMapSchema("User", mapped)
+
+		expected := map[string]Type{
+			"id": TypeString, "age": TypeInteger, "tags": TypeArray, "promo": TypeNull,
+			"user": TypeRecord, "user.active": TypeBoolean,
+		}
+		for path, typ := range expected {
+			if field := result.Lookup(path); field == nil || field.Type != typ {
+				t.Errorf("Expected %s to be %s, got %+v", path, typ, field)
+			}
+		}
+	})
+}
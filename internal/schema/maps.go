@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MinMapKeys is the number of keys an object needs before it is taken for a
+// map, so that records with a few fields holding digits, such as
+// {"ipv4": ..., "ipv6": ...}, stay records.
+const MinMapKeys = 3
+
+// MapKeyThreshold is the number of keys from which an object whose values all
+// have the same shape is taken for a map even when its keys look like names.
+const MapKeyThreshold = 20
+
+// MapValuesKey is the key under which MapSchema describes the values of a map
+// with dynamic keys.
+const MapValuesKey = "*"
+
+// IsDynamicMap reports whether a parsed object is a map keyed by data, such
+// as {"sku-1": {...}, "sku-2": {...}, "sku-3": {...}}, rather than a record
+// with named fields. It needs at least MinMapKeys keys whose values all have
+// the same shape, and either every key contains a digit or there are at least
+// MapKeyThreshold keys. Objects read from XML elements with attributes or
+// text are never maps.
+func IsDynamicMap(object map[string]interface{}) bool {
+	if len(object) < MinMapKeys {
+		return false
+	}
+	dynamic := true
+	for key := range object {
+		if key == TextField || strings.HasPrefix(key, AttributePrefix) {
+			return false
+		}
+		dynamic = dynamic && strings.IndexFunc(key, unicode.IsDigit) >= 0
+	}
+	if !dynamic && len(object) < MapKeyThreshold {
+		return false
+	}
+	var shape *Field
+	for _, key := range sortedKeys(object) {
+		value := inferValue(object[key])
+		if shape != nil && !sameShape(shape, value) {
+			return false
+		}
+		shape = value
+	}
+	return shape.Type != TypeAny
+}
+
+// sameShape reports whether two fields have the same type, counting numbers
+// of any kind and strings of any format as one, and records the same fields
+// of the same shapes. Empty arrays have the shape of any array.
+func sameShape(a, b *Field) bool {
+	if a.Type != b.Type {
+		return a.Type != TypeMap && b.Type != TypeMap && widen(a.Type, b.Type) != TypeAny
+	}
+	switch a.Type {
+	case TypeRecord:
+		if len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for i := range a.Fields {
+			if a.Fields[i].Name != b.Fields[i].Name || !sameShape(a.Fields[i], b.Fields[i]) {
+				return false
+			}
+		}
+	case TypeArray:
+		return a.Items.Type == TypeAny || b.Items.Type == TypeAny || sameShape(a.Items, b.Items)
+	case TypeMap:
+		return sameShape(a.Values, b.Values)
+	}
+	return true
+}
+
+// mapValues infers the field describing every value of a map.
+func mapValues(object map[string]interface{}) *Field {
+	var values *Field
+	for _, key := range sortedKeys(object) {
+		values = mergeField(values, inferValue(object[key]))
+	}
+	return values
+}
@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestIsDynamicMap(t *testing.T) {
+	t.Run("Given objects keyed by IDs, it should detect a map", func(t *testing.T) {
+		object := map[string]interface{}{
+			"sku-1": map[string]interface{}{"price": 1.5, "name": "Pen"},
+			"sku-2": map[string]interface{}{"price": 2.0, "name": "Ink"},
+			"sku-3": map[string]interface{}{"price": 3.0, "name": "Pad"},
+		}
+		if !IsDynamicMap(object) {
+			t.Error("Expected a dynamic map")
+		}
+	})
+
+	t.Run("Given too few keys with digits, it should detect a record", func(t *testing.T) {
+		records := []map[string]interface{}{
+			{"sku-1": map[string]interface{}{"price": 1.5}},
+			{"ipv4": "10.0.0.1", "ipv6": "::1"},
+		}
+		for _, object := range records {
+			if IsDynamicMap(object) {
+				t.Errorf("Expected %v to be a record", object)
+			}
+		}
+	})
+
+	t.Run("Given ID keys with records of different fields, it should detect a record", func(t *testing.T) {
+		object := map[string]interface{}{
+			"address1": map[string]interface{}{"street": "Main St"},
+			"address2": map[string]interface{}{"street": "High St"},
+			"phone1":   map[string]interface{}{"number": "555-0100"},
+		}
+		if IsDynamicMap(object) {
+			t.Error("Expected a record")
+		}
+	})
+
+	t.Run("Given named fields, it should detect a record", func(t *testing.T) {
+		if IsDynamicMap(map[string]interface{}{"name": "Ann", "email": "ann@example.com"}) {
+			t.Error("Expected a record")
+		}
+	})
+
+	t.Run("Given ID keys with mixed value shapes, it should detect a record", func(t *testing.T) {
+		if IsDynamicMap(map[string]interface{}{"line1": "Main St", "line2": "Apt 4", "line3": map[string]interface{}{"x": 1.0}}) {
+			t.Error("Expected a record")
+		}
+	})
+
+	t.Run("Given many homogeneous named keys, it should detect a map", func(t *testing.T) {
+		object := map[string]interface{}{}
+		for i := 0; i < MapKeyThreshold; i++ {
+			object["user"+string(rune('a'+i))] = float64(i)
+		}
+		if !IsDynamicMap(object) {
+			t.Error("Expected a dynamic map")
+		}
+	})
+
+	t.Run("Given an XML element with attributes, it should detect a record", func(t *testing.T) {
+		if IsDynamicMap(map[string]interface{}{"@id1": "a", "@id2": "b", "@id3": "c"}) {
+			t.Error("Expected a record")
+		}
+	})
+}
+
+func TestInferMap(t *testing.T) {
+	items := func(keys ...string) map[string]interface{} {
+		object := map[string]interface{}{}
+		for i, key := range keys {
+			object[key] = map[string]interface{}{"price": float64(i) + 0.5}
+		}
+		return map[string]interface{}{"items": object}
+	}
+
+	t.Run("Given items keyed by SKU, it should infer a map of records", func(t *testing.T) {
+		result := Infer("Order", items("sku-1", "sku-2", "sku-3"))
+
+		field := result.Field("items")
+		if field.Type != TypeMap || field.Values == nil || field.Values.Type != TypeRecord {
+			t.Fatalf("Expected a map of records, got %+v", field)
+		}
+		if price := result.Lookup("items.price"); price == nil || price.Type != TypeNumber {
+			t.Errorf("Expected items.price to resolve to the number field of the values, got %+v", price)
+		}
+	})
+
+	t.Run("Given different keys in each message, it should keep a single map field", func(t *testing.T) {
+		first := Infer("Order", items("sku-1", "sku-2", "sku-3"))
+		merged := Merge(Merge(first, Infer("Order", items("sku-4"))), Infer("Order", items("sku-7", "sku-8", "sku-9")))
+
+		if len(merged.Fields) != 1 || merged.Field("items").Type != TypeMap {
+			t.Errorf("Expected a single map field, got %+v", merged.Fields)
+		}
+		if changes := Diff(first, merged); len(changes) != 0 {
+			t.Errorf("Expected no changes, got %+v", changes)
+		}
+	})
+
+	t.Run("Given a map and a record of the same shape, it should merge into a map", func(t *testing.T) {
+		record := &Schema{Fields: []*Field{{Name: "items", Type: TypeRecord, Required: true, Fields: []*Field{
+			{Name: "pen", Type: TypeRecord, Required: true, Fields: []*Field{{Name: "price", Type: TypeNumber, Required: true}}},
+		}}}}
+		merged := Merge(Infer("Order", items("sku-1", "sku-2", "sku-3")), record)
+
+		field := merged.Field("items")
+		if field.Type != TypeMap || field.Values.Type != TypeRecord || field.Values.Name != "" {
+			t.Errorf("Expected a map of records, got %+v", field)
+		}
+	})
+
+	t.Run("Given a map and a record of another shape, it should not keep the map", func(t *testing.T) {
+		record := &Schema{Fields: []*Field{{Name: "items", Type: TypeRecord, Required: true, Fields: []*Field{
+			{Name: "count", Type: TypeInteger, Required: true},
+		}}}}
+		merged := Merge(Infer("Order", items("sku-1", "sku-2", "sku-3")), record)
+
+		if field := merged.Field("items"); field.Type == TypeMap {
+			t.Errorf("Expected the map to be given up, got %+v", field)
+		}
+	})
+
+	t.Run("Given a map of counts, it should infer integer values", func(t *testing.T) {
+		counts := map[string]interface{}{}
+		for i := 0; i < 3; i++ {
+			counts["2024-01-0"+strconv.Itoa(i+1)] = float64(i)
+		}
+		result := Infer("Stats", map[string]interface{}{"daily": counts})
+		if field := result.Field("daily"); field.Type != TypeMap || field.Values.Type != TypeInteger {
+			t.Errorf("Expected a map of integers, got %+v", field)
+		}
+	})
+}
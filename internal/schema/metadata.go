@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Classification is the sensitivity of the data a field holds.
+type Classification string
+
+const (
+	ClassificationPublic       Classification = "public"
+	ClassificationConfidential Classification = "confidential"
+	ClassificationPII          Classification = "pii"
+)
+
+// ParseClassification returns the classification with the given name,
+// ignoring case.
+func ParseClassification(name string) (Classification, error) {
+	classification := Classification(strings.ToLower(strings.TrimSpace(name)))
+	switch classification {
+	case ClassificationPublic, ClassificationConfidential, ClassificationPII:
+		return classification, nil
+	default:
+		return "", fmt.Errorf("unknown classification %q", name)
+	}
+}
+
+// FieldMetadata is the documentation of the field at Path. Classification is
+// the one the field gives itself or else inherits from the closest enclosing
+// record giving one.
+type FieldMetadata struct {
+	Path           string         `json:"path"`
+	Type           Type           `json:"type"`
+	Doc            string         `json:"doc,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+	Classification Classification `json:"classification,omitempty"`
+}
+
+// FieldMetadata lists the documentation of the fields of the schema and its
+// variants that have a doc, tags or a classification, in schema order. Fields
+// several variants have are listed once.
+func (s *Schema) FieldMetadata() []FieldMetadata {
+	var fields []FieldMetadata
+	collectMetadata("", s.Fields, "", &fields)
+	for _, variant := range s.Variants {
+		collectMetadata("", s.VariantFields(variant), "", &fields)
+	}
+
+	seen := make(map[string]bool, len(fields))
+	unique := fields[:0]
+	for _, field := range fields {
+		if !seen[field.Path] {
+			seen[field.Path] = true
+			unique = append(unique, field)
+		}
+	}
+	return unique
+}
+
+func collectMetadata(prefix string, fields []*Field, inherited Classification, metadata *[]FieldMetadata) {
+	for _, field := range fields {
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+		classification := field.Classification
+		if classification == "" {
+			classification = inherited
+		}
+		if field.Doc != "" || len(field.Tags) > 0 || classification != "" {
+			*metadata = append(*metadata, FieldMetadata{
+				Path:           path,
+				Type:           field.Type,
+				Doc:            field.Doc,
+				Tags:           cloneStrings(field.Tags),
+				Classification: classification,
+			})
+		}
+		collectMetadata(path, field.Element().Fields, classification, metadata)
+	}
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldMetadata(t *testing.T) {
+	t.Run("Given documented fields, it should parse their metadata", func(t *testing.T) {
+		s, err := Parse([]byte(`{
+			"fields": [
+				{"name": "Email", "type": "string", "doc": "Contact address", "tags": ["contact"], "classification": "PII"}
+			],
+			"metadata": {"owners": ["payments-team"], "tags": ["billing"]}
+		}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		email := s.Field("Email")
+		if email.Doc != "Contact address" || email.Classification != ClassificationPII || !reflect.DeepEqual(email.Tags, []string{"contact"}) {
+			t.Errorf("Unexpected field metadata: %+v", email)
+		}
+		if !reflect.DeepEqual(s.Metadata.Owners, []string{"payments-team"}) {
+			t.Errorf("Expected the owners to be parsed, got %+v", s.Metadata)
+		}
+	})
+
+	t.Run("Given an unknown classification, it should return an error", func(t *testing.T) {
+		if _, err := Parse([]byte(`{"fields": [{"name": "Email", "type": "string", "classification": "secret"}]}`)); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a classified record, it should classify its fields too", func(t *testing.T) {
+		s := &Schema{Fields: []*Field{
+			{Name: "ID", Type: TypeString},
+			{Name: "Customer", Type: TypeRecord, Classification: ClassificationConfidential, Fields: []*Field{
+				{Name: "Name", Type: TypeString},
+				{Name: "Email", Type: TypeString, Classification: ClassificationPII},
+			}},
+		}}
+		expected := []FieldMetadata{
+			{Path: "Customer", Type: TypeRecord, Classification: ClassificationConfidential},
+			{Path: "Customer.Name", Type: TypeString, Classification: ClassificationConfidential},
+			{Path: "Customer.Email", Type: TypeString, Classification: ClassificationPII},
+		}
+		if metadata := s.FieldMetadata(); !reflect.DeepEqual(metadata, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, metadata)
+		}
+	})
+
+	t.Run("Given a union, it should list shared and variant fields once", func(t *testing.T) {
+		s := &Schema{Discriminator: "type",
+			Fields: []*Field{{Name: "type", Type: TypeString, Doc: "Event type"}},
+			Variants: []*Variant{
+				{Value: "created", Fields: []*Field{{Name: "type", Type: TypeString, Doc: "Event type"}, {Name: "email", Type: TypeString, Classification: ClassificationPII}}},
+				{Value: "updated", Fields: []*Field{{Name: "type", Type: TypeString, Doc: "Event type"}, {Name: "email", Type: TypeString, Classification: ClassificationPII}}},
+			},
+		}
+		expected := []FieldMetadata{
+			{Path: "type", Type: TypeString, Doc: "Event type"},
+			{Path: "email", Type: TypeString, Classification: ClassificationPII},
+		}
+		if metadata := s.FieldMetadata(); !reflect.DeepEqual(metadata, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, metadata)
+		}
+	})
+
+	t.Run("Given a clone, it should not share the metadata", func(t *testing.T) {
+		s := &Schema{Fields: []*Field{{Name: "ID", Type: TypeString, Tags: []string{"key"}}}, Metadata: Metadata{Tags: []string{"core"}}}
+		c := s.Clone()
+		c.Fields[0].Tags[0] = "changed"
+		c.Metadata.Tags[0] = "changed"
+		if s.Fields[0].Tags[0] != "key" || s.Metadata.Tags[0] != "core" {
+			t.Errorf("Expected the original to be unchanged, got %+v", s)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"fmt"
+)
+
+// Reference points a record field at the schema registered as a version of
+// another subject, so that schemas share definitions such as Customer. The
+// referencing field lists no fields of its own until the schema is resolved.
+// Version zero stands for the latest version and is pinned when the schema is
+// registered. Name is the name the referencing document gives the referenced
+// type, as in the references of Confluent Schema Registry.
+type Reference struct {
+	Name    string `json:"name,omitempty"`
+	Subject string `json:"subject"`
+	Version int    `json:"version,omitempty"`
+}
+
+func (r Reference) String() string {
+	if r.Version == 0 {
+		return fmt.Sprintf("%s (latest)", r.Subject)
+	}
+	return fmt.Sprintf("%s version %d", r.Subject, r.Version)
+}
+
+// References lists the references of the schema and its variants in order,
+// each subject and version once.
+func (s *Schema) References() []Reference {
+	var references []Reference
+	seen := map[Reference]bool{}
+	s.EachReference(func(ref *Reference) {
+		key := Reference{Subject: ref.Subject, Version: ref.Version}
+		if !seen[key] {
+			seen[key] = true
+			references = append(references, *ref)
+		}
+	})
+	return references
+}
+
+// EachReference calls fn with the reference of every referencing field of
+// the schema and its variants, which fn may change.
+func (s *Schema) EachReference(fn func(*Reference)) {
+	visit := func(_ string, field *Field) {
+		if ref := field.Element().Ref; ref != nil {
+			fn(ref)
+		}
+	}
+	walkFields("", s.Fields, visit)
+	for _, variant := range s.Variants {
+		walkFields("", variant.Fields, visit)
+	}
+}
+
+// Resolve returns a copy of the schema in which every referencing field
+// carries the fields of the schema it references, itself resolved. lookup
+// returns the schema a reference points at. Referencing fields keep their
+// reference; a schema referencing itself, directly or not, is an error.
+func Resolve(s *Schema, lookup func(Reference) (*Schema, error)) (*Schema, error) {
+	resolved := s.Clone()
+	if err := resolveFields(resolved.Fields, lookup, nil); err != nil {
+		return nil, err
+	}
+	for _, variant := range resolved.Variants {
+		if err := resolveFields(variant.Fields, lookup, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// resolveFields resolves the references below fields. chain holds the
+// references being resolved, to detect cycles.
+func resolveFields(fields []*Field, lookup func(Reference) (*Schema, error), chain []Reference) error {
+	for _, field := range fields {
+		record := field.Element()
+		if record.Ref == nil {
+			if err := resolveFields(record.Fields, lookup, chain); err != nil {
+				return err
+			}
+			continue
+		}
+		ref := *record.Ref
+		for _, seen := range chain {
+			if seen.Subject == ref.Subject && seen.Version == ref.Version {
+				return fmt.Errorf("error resolving %s: reference cycle", ref)
+			}
+		}
+		referenced, err := lookup(ref)
+		if err != nil {
+			return fmt.Errorf("error resolving field %q: %v", field.Name, err)
+		}
+		record.Fields = cloneFields(referenced.Fields)
+		if err := resolveFields(record.Fields, lookup, append(chain, ref)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	customer := &Schema{Name: "Customer", Fields: []*Field{
+		{Name: "Email", Type: TypeString, Required: true},
+	}}
+	order := &Schema{Name: "Order", Fields: []*Field{
+		{Name: "ID", Type: TypeString, Required: true},
+		{Name: "Buyer", Type: TypeRecord, Required: true, Ref: &Reference{Subject: "customer", Version: 1}},
+		{Name: "Payers", Type: TypeArray, Items: &Field{Type: TypeRecord, Ref: &Reference{Subject: "customer", Version: 1}}},
+	}}
+	lookup := func(ref Reference) (*Schema, error) {
+		if ref.Subject == "customer" && ref.Version == 1 {
+			return customer, nil
+		}
+		return nil, fmt.Errorf("%s not found", ref)
+	}
+
+	t.Run("Given referencing fields, it should list each reference once", func(t *testing.T) {
+		references := order.References()
+		if len(references) != 1 || references[0].Subject != "customer" || references[0].Version != 1 {
+			t.Errorf("Expected a single reference to customer version 1, got %+v", references)
+		}
+	})
+
+	t.Run("Given referencing fields, it should copy the referenced fields into a resolved copy", func(t *testing.T) {
+		resolved, err := Resolve(order, lookup)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if field := resolved.Lookup("Buyer.Email"); field == nil || field.Type != TypeString {
+			t.Errorf("Expected Buyer.Email to be resolved, got %+v", field)
+		}
+		if field := resolved.Lookup("Payers.Email"); field == nil {
+			t.Error("Expected the array items to be resolved")
+		}
+		if order.Lookup("Buyer.Email") != nil {
+			t.Error("Expected the original schema to be left unresolved")
+		}
+	})
+
+	t.Run("Given a missing reference, it should return an error", func(t *testing.T) {
+		missing := &Schema{Fields: []*Field{{Name: "Buyer", Type: TypeRecord, Ref: &Reference{Subject: "buyer", Version: 2}}}}
+		if _, err := Resolve(missing, lookup); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a reference cycle, it should return an error", func(t *testing.T) {
+		cyclic := &Schema{Fields: []*Field{{Name: "Parent", Type: TypeRecord, Ref: &Reference{Subject: "node", Version: 1}}}}
+		if _, err := Resolve(cyclic, func(Reference) (*Schema, error) { return cyclic, nil }); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a reference on a field that is not a record, it should reject the schema", func(t *testing.T) {
+		_, err := Parse([]byte(`{"fields": [{"name": "Buyer", "type": "string", "ref": {"subject": "customer"}}]}`))
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given an Avro schema using a referenced type, it should parse a referencing field", func(t *testing.T) {
+		document := []byte(`{"type": "record", "name": "Order", "namespace": "com.example", "fields": [
+			{"name": "Buyer", "type": "com.example.Customer"},
+			{"name": "Payer", "type": ["null", "Customer"]}
+		]}`)
+		s, err := ParseAvroReferences(document, []Reference{{Name: "com.example.Customer", Subject: "customer", Version: 1}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		buyer, payer := s.Field("Buyer"), s.Field("Payer")
+		if buyer.Ref == nil || buyer.Ref.Subject != "customer" || !buyer.Required {
+			t.Errorf("Expected Buyer to reference customer, got %+v", buyer)
+		}
+		if payer.Ref == nil || payer.Required {
+			t.Errorf("Expected Payer to be an optional reference, got %+v", payer)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the logical type of a schema field.
+type Type string
+
+const (
+	TypeNull      Type = "null"
+	TypeString    Type = "string"
+	TypeInteger   Type = "integer"
+	TypeNumber    Type = "number"
+	TypeBoolean   Type = "boolean"
+	TypeTimestamp Type = "timestamp"
+	TypeDecimal   Type = "decimal"
+	TypeRecord    Type = "record"
+	TypeArray     Type = "array"
+	TypeMap       Type = "map"
+	TypeAny       Type = "any"
+)
+
+// ParseType returns the type with the given name, ignoring case.
+func ParseType(name string) (Type, error) {
+	t := Type(strings.ToLower(strings.TrimSpace(name)))
+	switch t {
+	case TypeNull, TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeTimestamp, TypeDecimal,
+		TypeRecord, TypeArray, TypeMap, TypeAny:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown type %q", name)
+	}
+}
+
+// TextField names the field holding the character data of an XML element
+// that also has attributes.
+const TextField = "#text"
+
+// FormatXML is the Format of schemas describing XML documents, whose lists of
+// elements are wrapped in an element of their own.
+const FormatXML = "xml"
+
+// AttributePrefix marks the keys of parsed XML documents holding attributes,
+// keeping them apart from child elements of the same name.
+const AttributePrefix = "@"
+
+// Field describes a single named value of a schema. Records carry their
+// children in Fields, arrays carry their element description in Items and
+// maps with string keys the description of their values in Values.
+// Attribute marks fields read from XML attributes rather than elements,
+// Enum restricts a string field to the listed symbols and Examples holds
+// values sampled from consumed messages. Records defined by another
+// registered schema carry a Ref instead of their Fields. Doc, Tags and
+// Classification document the field for governance tooling.
+type Field struct {
+	Name           string         `json:"name,omitempty"`
+	Type           Type           `json:"type"`
+	Required       bool           `json:"required"`
+	Attribute      bool           `json:"attribute,omitempty"`
+	Precision      int            `json:"precision,omitempty"`
+	Scale          int            `json:"scale,omitempty"`
+	Enum           []string       `json:"enum,omitempty"`
+	Examples       []string       `json:"examples,omitempty"`
+	Fields         []*Field       `json:"fields,omitempty"`
+	Items          *Field         `json:"items,omitempty"`
+	Values         *Field         `json:"values,omitempty"`
+	Ref            *Reference     `json:"ref,omitempty"`
+	Doc            string         `json:"doc,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+	Classification Classification `json:"classification,omitempty"`
+}
+
+// Metadata is the descriptive block posted alongside a schema: besides its
+// version and description, the teams or people owning it and its tags.
+type Metadata struct {
+	Version     int      `json:"version,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Owners      []string `json:"owners,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Schema is thoth's representation of a record schema. It uses the same JSON
+// layout as the documents accepted by the "/schema" endpoint. Schemas of
+// discriminated unions name their Discriminator field and describe each kind
+// of message in Variants, Fields holding the fields shared by all of them.
+// Format is FormatXML for schemas inferred from XML documents.
+type Schema struct {
+	Name          string     `json:"name,omitempty"`
+	Namespace     string     `json:"namespace,omitempty"`
+	Format        string     `json:"format,omitempty"`
+	Fields        []*Field   `json:"fields"`
+	Discriminator string     `json:"discriminator,omitempty"`
+	Variants      []*Variant `json:"variants,omitempty"`
+	Metadata      Metadata   `json:"metadata"`
+}
+
+// Parse decodes a schema document in the format accepted by "/schema" and
+// checks that every field has a name and a known type.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error decoding schema: %v", err)
+	}
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("schema has no fields")
+	}
+	if s.Format != "" && s.Format != FormatXML {
+		return nil, fmt.Errorf("unknown schema format %q", s.Format)
+	}
+	if err := checkFields("", s.Fields); err != nil {
+		return nil, err
+	}
+	for _, variant := range s.Variants {
+		if err := checkFields(variant.Value, variant.Fields); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.Variants) > 0 && s.Discriminator == "" {
+		return nil, fmt.Errorf("schema has variants but no discriminator")
+	}
+	return &s, nil
+}
+
+func checkFields(prefix string, fields []*Field) error {
+	for _, field := range fields {
+		if field.Name == "" {
+			return fmt.Errorf("field without a name in %q", prefix)
+		}
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+		if err := checkType(path, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkType(path string, field *Field) error {
+	if field.Classification != "" {
+		classification, err := ParseClassification(string(field.Classification))
+		if err != nil {
+			return fmt.Errorf("field %q: %v", path, err)
+		}
+		field.Classification = classification
+	}
+	if len(field.Enum) > 0 && field.Type != TypeString {
+		return fmt.Errorf("field %q of type %q cannot have enum symbols", path, field.Type)
+	}
+	if field.Ref != nil && field.Type != TypeRecord {
+		return fmt.Errorf("field %q of type %q cannot reference a schema", path, field.Type)
+	}
+	switch field.Type {
+	case TypeNull, TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeTimestamp, TypeDecimal, TypeAny:
+		return nil
+	case TypeRecord:
+		if field.Ref != nil {
+			if field.Ref.Subject == "" {
+				return fmt.Errorf("record field %q references no subject", path)
+			}
+			if len(field.Fields) > 0 {
+				return fmt.Errorf("record field %q references %s and cannot list fields", path, field.Ref)
+			}
+			return nil
+		}
+		return checkFields(path, field.Fields)
+	case TypeArray:
+		if field.Items == nil {
+			return fmt.Errorf("array field %q has no items", path)
+		}
+		return checkType(path, field.Items)
+	case TypeMap:
+		if field.Values == nil {
+			return fmt.Errorf("map field %q has no values", path)
+		}
+		return checkType(path, field.Values)
+	default:
+		return fmt.Errorf("field %q has unknown type %q", path, field.Type)
+	}
+}
+
+// Clone returns a deep copy of the schema.
+func (s *Schema) Clone() *Schema {
+	c := *s
+	c.Metadata.Owners = cloneStrings(s.Metadata.Owners)
+	c.Metadata.Tags = cloneStrings(s.Metadata.Tags)
+	c.Fields = cloneFields(s.Fields)
+	if s.Variants != nil {
+		c.Variants = make([]*Variant, len(s.Variants))
+		for i, variant := range s.Variants {
+			c.Variants[i] = &Variant{Value: variant.Value, Fields: cloneFields(variant.Fields)}
+		}
+	}
+	return &c
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+func cloneFields(fields []*Field) []*Field {
+	c := make([]*Field, len(fields))
+	for i, field := range fields {
+		c[i] = field.clone()
+	}
+	return c
+}
+
+// Field returns the top level field with the given name, or nil.
+func (s *Schema) Field(name string) *Field {
+	return findField(s.Fields, name)
+}
+
+// Lookup resolves a dot separated field path such as "Customer.Email".
+// Array elements and map values are traversed transparently, so "Items.Price"
+// resolves the Price field of the Items element record.
+func (s *Schema) Lookup(path string) *Field {
+	return lookup(s.Fields, path)
+}
+
+// lookupEach calls fn for the field at path in the schema and in each of its
+// variants.
+func (s *Schema) lookupEach(path string, fn func(*Field)) {
+	if field := s.Lookup(path); field != nil {
+		fn(field)
+	}
+	for _, variant := range s.Variants {
+		if field := lookup(variant.Fields, path); field != nil {
+			fn(field)
+		}
+	}
+}
+
+func lookup(fields []*Field, path string) *Field {
+	var field *Field
+	for _, name := range strings.Split(path, ".") {
+		field = findField(fields, name)
+		if field == nil {
+			return nil
+		}
+		fields = field.Element().Fields
+	}
+	return field
+}
+
+// Element returns the innermost element of an array field or value of a map
+// field, or the field itself when it is neither.
+func (f *Field) Element() *Field {
+	for {
+		switch {
+		case f.Type == TypeArray && f.Items != nil:
+			f = f.Items
+		case f.Type == TypeMap && f.Values != nil:
+			f = f.Values
+		default:
+			return f
+		}
+	}
+}
+
+// Walk calls fn for every field of the schema in depth first order, passing
+// the dot separated path of the field.
+func (s *Schema) Walk(fn func(path string, field *Field)) {
+	walkFields("", s.Fields, fn)
+}
+
+// OrderFields sorts the fields of every record by document order. order maps
+// the path of a record ("" for the root) to the names of its children in the
+// order they were first seen; fields that are not listed keep their relative
+// order after the listed ones.
+func (s *Schema) OrderFields(order map[string][]string) {
+	orderFields("", s.Fields, order)
+}
+
+func orderFields(path string, fields []*Field, order map[string][]string) {
+	position := map[string]int{}
+	for i, name := range order[path] {
+		position[name] = i + 1
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		pi, pj := position[fields[i].Name], position[fields[j].Name]
+		if pi == 0 || pj == 0 {
+			return pi != 0 && pj == 0
+		}
+		return pi < pj
+	})
+	for _, field := range fields {
+		child := field.Name
+		if path != "" {
+			child = path + "." + field.Name
+		}
+		orderFields(child, field.Element().Fields, order)
+	}
+}
+
+func walkFields(prefix string, fields []*Field, fn func(string, *Field)) {
+	for _, field := range fields {
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+		fn(path, field)
+		walkFields(path, field.Element().Fields, fn)
+	}
+}
+
+func findField(fields []*Field, name string) *Field {
+	for _, field := range fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
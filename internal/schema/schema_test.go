@@ -0,0 +1,108 @@
+package schema
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	t.Run("Given the README schema document, it should parse it", func(t *testing.T) {
+		data := []byte(`{
+			"fields": [
+				{"name": "username", "type": "string", "required": true},
+				{"name": "age", "type": "integer", "required": false}
+			],
+			"metadata": {"version": 1, "description": "User data schema"}
+		}`)
+		result, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !result.Field("username").Required || result.Field("age").Type != TypeInteger {
+			t.Errorf("Unexpected fields: %+v", result.Fields)
+		}
+		if result.Metadata.Version != 1 || result.Metadata.Description != "User data schema" {
+			t.Errorf("Unexpected metadata: %+v", result.Metadata)
+		}
+	})
+
+	t.Run("Given an unknown field type, it should return an error", func(t *testing.T) {
+		data := []byte(`{"fields": [{"name": "user", "type": "record", "fields": [{"name": "age", "type": "int"}]}]}`)
+		if _, err := Parse(data); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given an array without items, it should return an error", func(t *testing.T) {
+		data := []byte(`{"fields": [{"name": "tags", "type": "array"}]}`)
+		if _, err := Parse(data); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given a format, it should only accept xml", func(t *testing.T) {
+		s, err := Parse([]byte(`{"format": "xml", "fields": [{"name": "id", "type": "string"}]}`))
+		if err != nil || s.Format != FormatXML {
+			t.Errorf("Expected an XML schema, got %+v and %v", s, err)
+		}
+		if _, err := Parse([]byte(`{"format": "yaml", "fields": [{"name": "id", "type": "string"}]}`)); err == nil {
+			t.Error("Expected an unknown format to fail")
+		}
+	})
+}
+
+func TestLookup(t *testing.T) {
+	t.Run("Given a path through an array, it should resolve the element field", func(t *testing.T) {
+		s := &Schema{Fields: []*Field{
+			{Name: "Items", Type: TypeArray, Items: &Field{Type: TypeRecord, Fields: []*Field{
+				{Name: "Price", Type: TypeNumber},
+			}}},
+		}}
+
+		if field := s.Lookup("Items.Price"); field == nil || field.Type != TypeNumber {
+			t.Errorf("Expected Items.Price to resolve, got %+v", field)
+		}
+		if field := s.Lookup("Items.Missing"); field != nil {
+			t.Errorf("Expected no field, got %+v", field)
+		}
+	})
+}
+
+func TestParseEnum(t *testing.T) {
+	t.Run("Given enum symbols on a string field, it should keep them", func(t *testing.T) {
+		result, err := Parse([]byte(`{"fields": [{"name": "status", "type": "string", "enum": ["A", "B"]}]}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Field("status").Enum) != 2 {
+			t.Errorf("Unexpected symbols: %v", result.Field("status").Enum)
+		}
+	})
+
+	t.Run("Given enum symbols on a non-string field, it should return an error", func(t *testing.T) {
+		if _, err := Parse([]byte(`{"fields": [{"name": "count", "type": "integer", "enum": ["1"]}]}`)); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+}
+
+func TestParseVariants(t *testing.T) {
+	t.Run("Given variants without a discriminator, it should return an error", func(t *testing.T) {
+		data := []byte(`{"fields": [{"name": "id", "type": "string"}], "variants": [{"value": "a", "fields": [{"name": "id", "type": "string"}]}]}`)
+		if _, err := Parse(data); err == nil {
+			t.Fatal("Expected an error, but got none")
+		}
+	})
+
+	t.Run("Given a union schema, it should clone its variants", func(t *testing.T) {
+		data := []byte(`{"fields": [{"name": "type", "type": "string"}], "discriminator": "type",
+			"variants": [{"value": "a", "fields": [{"name": "type", "type": "string"}, {"name": "x", "type": "integer"}]}]}`)
+		result, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		c := result.Clone()
+		c.Variants[0].Fields[1].Type = TypeString
+		if result.Variants[0].Fields[1].Type != TypeInteger {
+			t.Error("Expected the clone to be independent of the original")
+		}
+	})
+}
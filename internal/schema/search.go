@@ -0,0 +1,75 @@
+package schema
+
+import "strings"
+
+// FieldQuery selects fields by their Name, their Type or the type of their
+// array elements and map values, and a Tag they carry, all ignoring case.
+// A tag also matches the classification of a field, its own or the one it
+// inherits from its enclosing records. Empty criteria match every field.
+type FieldQuery struct {
+	Name string `json:"name,omitempty"`
+	Type Type   `json:"type,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+// FieldMatch is a field found by a FieldQuery.
+type FieldMatch struct {
+	Path string `json:"path"`
+	Type Type   `json:"type"`
+}
+
+// Find returns the fields of the schema and its variants matching the query,
+// in schema order. Fields several variants have are found once.
+func (s *Schema) Find(query FieldQuery) []FieldMatch {
+	var matches []FieldMatch
+	findFields("", s.Fields, "", query, &matches)
+	for _, variant := range s.Variants {
+		findFields("", s.VariantFields(variant), "", query, &matches)
+	}
+
+	seen := make(map[string]bool, len(matches))
+	unique := matches[:0]
+	for _, match := range matches {
+		if !seen[match.Path] {
+			seen[match.Path] = true
+			unique = append(unique, match)
+		}
+	}
+	return unique
+}
+
+func findFields(prefix string, fields []*Field, inherited Classification, query FieldQuery, matches *[]FieldMatch) {
+	for _, field := range fields {
+		path := field.Name
+		if prefix != "" {
+			path = prefix + "." + field.Name
+		}
+		classification := field.Classification
+		if classification == "" {
+			classification = inherited
+		}
+		if query.matches(field, classification) {
+			*matches = append(*matches, FieldMatch{Path: path, Type: field.Type})
+		}
+		findFields(path, field.Element().Fields, classification, query, matches)
+	}
+}
+
+func (q FieldQuery) matches(field *Field, classification Classification) bool {
+	if q.Name != "" && !strings.EqualFold(field.Name, q.Name) {
+		return false
+	}
+	if q.Type != "" && !strings.EqualFold(string(field.Type), string(q.Type)) &&
+		!strings.EqualFold(string(field.Element().Type), string(q.Type)) {
+		return false
+	}
+	if q.Tag == "" || strings.EqualFold(string(classification), q.Tag) {
+		return true
+	}
+	for _, tag := range field.Tags {
+		if strings.EqualFold(tag, q.Tag) {
+			return true
+		}
+	}
+	return false
+}
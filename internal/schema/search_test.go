@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	s := &Schema{Fields: []*Field{
+		{Name: "ID", Type: TypeString, Tags: []string{"key"}},
+		{Name: "Total", Type: TypeDecimal},
+		{Name: "Customer", Type: TypeRecord, Classification: ClassificationPII, Fields: []*Field{
+			{Name: "Email", Type: TypeString},
+		}},
+		{Name: "Prices", Type: TypeArray, Items: &Field{Type: TypeDecimal}},
+	}}
+
+	t.Run("Given a name, it should find the fields so named ignoring case", func(t *testing.T) {
+		expected := []FieldMatch{{Path: "Customer.Email", Type: TypeString}}
+		if matches := s.Find(FieldQuery{Name: "email"}); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, matches)
+		}
+	})
+
+	t.Run("Given a type, it should find fields and arrays of that type", func(t *testing.T) {
+		expected := []FieldMatch{{Path: "Total", Type: TypeDecimal}, {Path: "Prices", Type: TypeArray}}
+		if matches := s.Find(FieldQuery{Type: TypeDecimal}); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, matches)
+		}
+	})
+
+	t.Run("Given a tag, it should match tags and inherited classifications", func(t *testing.T) {
+		if matches := s.Find(FieldQuery{Tag: "PII"}); len(matches) != 2 || matches[1].Path != "Customer.Email" {
+			t.Errorf("Expected Customer and Customer.Email, got %+v", matches)
+		}
+		if matches := s.Find(FieldQuery{Tag: "key", Type: TypeInteger}); len(matches) != 0 {
+			t.Errorf("Expected every criterion to apply, got %+v", matches)
+		}
+	})
+
+	t.Run("Given a union, it should find shared and variant fields once", func(t *testing.T) {
+		union := &Schema{Discriminator: "type",
+			Fields: []*Field{{Name: "type", Type: TypeString, Enum: []string{"created", "shipped"}}},
+			Variants: []*Variant{
+				{Value: "created", Fields: []*Field{{Name: "type", Type: TypeString}, {Name: "note", Type: TypeString}}},
+				{Value: "shipped", Fields: []*Field{{Name: "type", Type: TypeString}, {Name: "note", Type: TypeString}}},
+			},
+		}
+		expected := []FieldMatch{{Path: "type", Type: TypeString}, {Path: "note", Type: TypeString}}
+		if matches := union.Find(FieldQuery{Type: TypeString}); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, matches)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiscriminatorNames are the names, compared case insensitively, of the top
+// level fields that tell apart the event types carried by a topic.
+var DiscriminatorNames = []string{"type", "eventType", "event_type"}
+
+// DefaultMaxVariants is the largest number of discriminator values a topic
+// may have before its messages are described by a single record again.
+const DefaultMaxVariants = 20
+
+// Variant is the sub-schema of the messages whose discriminator field holds
+// Value. Its discriminator field is an enum of that single value.
+type Variant struct {
+	Value  string   `json:"value"`
+	Fields []*Field `json:"fields"`
+}
+
+// VariantFields returns the fields of a variant of the schema that are not
+// shared by all its variants, in the order of the variant.
+func (s *Schema) VariantFields(variant *Variant) []*Field {
+	var fields []*Field
+	for _, field := range variant.Fields {
+		if s.Field(field.Name) == nil {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Discriminator returns the name and value of the discriminator field of a
+// message parsed by ParseMessage, if it has one holding a string.
+func Discriminator(data map[string]interface{}) (name, value string, ok bool) {
+	for _, key := range sortedKeys(data) {
+		name := strings.TrimPrefix(key, AttributePrefix)
+		if !isDiscriminatorName(name) {
+			continue
+		}
+		switch v := data[key].(type) {
+		case string:
+			value = v
+		case map[string]interface{}:
+			value, _ = xmlText(v)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+func isDiscriminatorName(name string) bool {
+	for _, candidate := range DiscriminatorNames {
+		if strings.EqualFold(name, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnionBuilder accumulates the schemas of the messages of a topic. When every
+// message has the same discriminator field and it takes between two and
+// MaxVariants values, the schema it builds holds one variant per value
+// instead of a single record in which every variant specific field is
+// optional.
+type UnionBuilder struct {
+	MaxVariants int
+
+	merged        *Schema
+	discriminator string
+	variants      map[string]*Schema
+	disabled      bool
+}
+
+// NewUnionBuilder returns a builder allowing DefaultMaxVariants variants.
+func NewUnionBuilder() *UnionBuilder {
+	return &UnionBuilder{MaxVariants: DefaultMaxVariants, variants: map[string]*Schema{}}
+}
+
+// Add merges the schema inferred from a parsed message into the variant of
+// its discriminator value.
+func (u *UnionBuilder) Add(data map[string]interface{}, inferred *Schema) {
+	u.merged = Merge(u.merged, inferred)
+	if u.disabled {
+		return
+	}
+	name, value, ok := Discriminator(data)
+	if !ok || u.discriminator != "" && name != u.discriminator {
+		u.disable()
+		return
+	}
+	u.discriminator = name
+	u.variants[value] = Merge(u.variants[value], inferred)
+	if len(u.variants) > u.MaxVariants {
+		u.disable()
+	}
+}
+
+func (u *UnionBuilder) disable() {
+	u.disabled = true
+	u.discriminator = ""
+	u.variants = nil
+}
+
+// Schema returns the accumulated schema. For discriminated unions its fields
+// are those every variant has, the discriminator field being an enum of the
+// variant values, and Variants holds the full schema of each variant.
+func (u *UnionBuilder) Schema() *Schema {
+	if u.merged == nil {
+		return nil
+	}
+	result := u.merged.Clone()
+	if u.disabled || len(u.variants) < 2 {
+		return result
+	}
+
+	values := make([]string, 0, len(u.variants))
+	for value := range u.variants {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	common := make([]*Field, 0, len(result.Fields))
+	for _, field := range result.Fields {
+		shared := true
+		for _, variant := range u.variants {
+			shared = shared && variant.Field(field.Name) != nil
+		}
+		if shared {
+			common = append(common, field)
+		}
+	}
+	result.Fields = common
+	result.Discriminator = u.discriminator
+	if field := result.Field(u.discriminator); field != nil {
+		field.Type, field.Enum = TypeString, values
+	}
+
+	for _, value := range values {
+		variant := u.variants[value].Clone()
+		if field := variant.Field(u.discriminator); field != nil {
+			field.Type, field.Enum = TypeString, []string{value}
+		}
+		result.Variants = append(result.Variants, &Variant{Value: value, Fields: variant.Fields})
+	}
+	return result
+}
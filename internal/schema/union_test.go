@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func addSamples(u *UnionBuilder, samples ...map[string]interface{}) {
+	for _, sample := range samples {
+		u.Add(sample, Infer("Event", sample))
+	}
+}
+
+func TestDiscriminator(t *testing.T) {
+	t.Run("Given a JSON type field, it should return its value", func(t *testing.T) {
+		name, value, ok := Discriminator(map[string]interface{}{"type": "OrderCreated", "id": "1"})
+		if !ok || name != "type" || value != "OrderCreated" {
+			t.Errorf("Unexpected discriminator: %q %q %v", name, value, ok)
+		}
+	})
+
+	t.Run("Given an XML EventType element, it should return its text", func(t *testing.T) {
+		name, value, ok := Discriminator(map[string]interface{}{"EventType": map[string]interface{}{TextField: " Refund "}})
+		if !ok || name != "EventType" || value != "Refund" {
+			t.Errorf("Unexpected discriminator: %q %q %v", name, value, ok)
+		}
+	})
+
+	t.Run("Given no discriminator field, it should report none", func(t *testing.T) {
+		if _, _, ok := Discriminator(map[string]interface{}{"kind": "x", "type": 3.0}); ok {
+			t.Error("Expected no discriminator")
+		}
+	})
+}
+
+func TestUnionBuilder(t *testing.T) {
+	created := map[string]interface{}{"type": "OrderCreated", "id": "1", "total": 10.5}
+	shipped := map[string]interface{}{"type": "OrderShipped", "id": "2", "carrier": "UPS"}
+
+	t.Run("Given several event types, it should produce one variant per type", func(t *testing.T) {
+		u := NewUnionBuilder()
+		addSamples(u, created, shipped, created)
+
+		result := u.Schema()
+		if result.Discriminator != "type" || len(result.Variants) != 2 {
+			t.Fatalf("Expected two variants discriminated by type, got %+v", result)
+		}
+		if len(result.Fields) != 2 || result.Field("id") == nil || !reflect.DeepEqual(result.Field("type").Enum, []string{"OrderCreated", "OrderShipped"}) {
+			t.Errorf("Unexpected common fields: %+v", result.Fields)
+		}
+		createdVariant := result.Variants[0]
+		if createdVariant.Value != "OrderCreated" || findField(createdVariant.Fields, "carrier") != nil {
+			t.Errorf("Unexpected OrderCreated variant: %+v", createdVariant)
+		}
+		if total := findField(createdVariant.Fields, "total"); total == nil || !total.Required {
+			t.Errorf("Expected total to be required in OrderCreated, got %+v", total)
+		}
+		if discriminator := findField(result.Variants[1].Fields, "type"); !reflect.DeepEqual(discriminator.Enum, []string{"OrderShipped"}) {
+			t.Errorf("Expected the OrderShipped discriminator to be a single value enum, got %+v", discriminator)
+		}
+	})
+
+	t.Run("Given a single event type, it should produce a plain record", func(t *testing.T) {
+		u := NewUnionBuilder()
+		addSamples(u, created, created)
+
+		if result := u.Schema(); result.Discriminator != "" || result.Variants != nil {
+			t.Errorf("Expected a plain record, got %+v", result)
+		}
+	})
+
+	t.Run("Given a message without discriminator, it should merge everything", func(t *testing.T) {
+		u := NewUnionBuilder()
+		addSamples(u, created, shipped, map[string]interface{}{"id": "3"})
+
+		result := u.Schema()
+		if result.Variants != nil {
+			t.Fatalf("Expected no variants, got %+v", result.Variants)
+		}
+		if field := result.Field("carrier"); field == nil || field.Required {
+			t.Errorf("Expected an optional carrier field, got %+v", field)
+		}
+	})
+
+	t.Run("Given more values than allowed, it should merge everything", func(t *testing.T) {
+		u := NewUnionBuilder()
+		u.MaxVariants = 1
+		addSamples(u, created, shipped)
+
+		if result := u.Schema(); result.Variants != nil {
+			t.Errorf("Expected no variants, got %+v", result.Variants)
+		}
+	})
+}
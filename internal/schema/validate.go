@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// ViolationKind classifies how a message breaks its schema.
+type ViolationKind string
+
+const (
+	MissingRequired ViolationKind = "missing_required"
+	WrongType       ViolationKind = "wrong_type"
+	UnexpectedField ViolationKind = "unexpected_field"
+	NotInEnum       ViolationKind = "not_in_enum"
+)
+
+// Violation is a single way a message breaks its schema, located by the dot
+// separated path of the field as used by Lookup. Actual is the type inferred
+// for a value of the wrong type.
+type Violation struct {
+	Path     string        `json:"path"`
+	Kind     ViolationKind `json:"kind"`
+	Expected Type          `json:"expected,omitempty"`
+	Actual   Type          `json:"actual,omitempty"`
+}
+
+// Validate checks a message parsed by ParseMessage against the schema: every
+// required field must be present and not null, values must have the type of
+// their field, strings their enum symbols, and no field may be missing from
+// the schema. XML character data is accepted where its inferred type fits,
+// and a single XML element where an array is expected. Messages of
+// discriminated unions are checked against the variant their discriminator
+// names.
+func Validate(s *Schema, data map[string]interface{}) []Violation {
+	fields := s.Fields
+	if s.Discriminator != "" {
+		if _, value, ok := Discriminator(data); ok {
+			for _, variant := range s.Variants {
+				if variant.Value == value {
+					fields = variant.Fields
+				}
+			}
+		}
+	}
+	violations := validateFields("", fields, data)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Path < violations[j].Path })
+	return violations
+}
+
+func validateFields(prefix string, fields []*Field, data map[string]interface{}) []Violation {
+	var violations []Violation
+	known := map[string]bool{}
+	for _, field := range fields {
+		key := field.Name
+		if field.Attribute {
+			key = AttributePrefix + field.Name
+		}
+		known[key] = true
+		path := joinPath(prefix, field.Name)
+		value, present := data[key]
+		if !present || value == nil {
+			if field.Required && field.Type != TypeNull {
+				violations = append(violations, Violation{Path: path, Kind: MissingRequired, Expected: field.Type})
+			}
+			continue
+		}
+		text := field.Attribute || field.Name == TextField
+		violations = append(violations, validateValue(path, field, value, text)...)
+	}
+	for _, key := range sortedKeys(data) {
+		if known[key] || key == TextField && strings.TrimSpace(textOf(data[key])) == "" {
+			continue
+		}
+		violations = append(violations, Violation{Path: joinPath(prefix, strings.TrimPrefix(key, AttributePrefix)), Kind: UnexpectedField})
+	}
+	return violations
+}
+
+// validateValue checks a value present in the message. text marks XML
+// attributes and character data, which are strings whatever they hold.
+func validateValue(path string, field *Field, value interface{}, text bool) []Violation {
+	if s, ok := value.(string); ok && text {
+		value = map[string]interface{}{TextField: s}
+	}
+	switch field.Type {
+	case TypeAny:
+		return nil
+	case TypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			if _, element := value.(map[string]interface{}); !element || field.Items == nil {
+				return []Violation{wrongType(path, field, value)}
+			}
+			items = []interface{}{value}
+		}
+		var violations []Violation
+		for _, item := range items {
+			if field.Items != nil && item != nil {
+				violations = append(violations, validateValue(path, field.Items, item, false)...)
+			}
+		}
+		return violations
+	case TypeMap:
+		entries, ok := value.(map[string]interface{})
+		if !ok || field.Values == nil {
+			return []Violation{wrongType(path, field, value)}
+		}
+		var violations []Violation
+		for _, key := range sortedKeys(entries) {
+			if entries[key] != nil {
+				violations = append(violations, validateValue(path, field.Values, entries[key], false)...)
+			}
+		}
+		return violations
+	case TypeRecord:
+		record, ok := value.(map[string]interface{})
+		if _, isText := xmlText(record); !ok || isText && findField(field.Fields, TextField) == nil {
+			return []Violation{wrongType(path, field, value)}
+		}
+		return validateFields(path, field.Fields, record)
+	}
+
+	actual := inferValue(value)
+	textual := false
+	if element, ok := value.(map[string]interface{}); ok {
+		_, textual = xmlText(element)
+	}
+	if !accepts(field.Type, actual.Type, textual) {
+		return []Violation{{Path: path, Kind: WrongType, Expected: field.Type, Actual: actual.Type}}
+	}
+	if len(field.Enum) > 0 {
+		symbol, _ := value.(string)
+		if textual {
+			symbol, _ = xmlText(value.(map[string]interface{}))
+			symbol = strings.TrimSpace(symbol)
+		}
+		for _, candidate := range field.Enum {
+			if candidate == symbol {
+				return nil
+			}
+		}
+		return []Violation{{Path: path, Kind: NotInEnum, Expected: field.Type}}
+	}
+	return nil
+}
+
+func wrongType(path string, field *Field, value interface{}) Violation {
+	return Violation{Path: path, Kind: WrongType, Expected: field.Type, Actual: inferValue(value).Type}
+}
+
+// accepts reports whether a value of the actual type fits a field of the
+// expected scalar type. Numbers fit numeric fields of any kind, and XML
+// character data fits string fields whatever it looks like.
+func accepts(expected, actual Type, text bool) bool {
+	switch expected {
+	case TypeString:
+		return actual == TypeString || actual == TypeTimestamp || text && isScalar(actual)
+	case TypeNumber, TypeDecimal:
+		return actual == TypeInteger || actual == TypeNumber || actual == TypeDecimal
+	default:
+		return actual == expected
+	}
+}
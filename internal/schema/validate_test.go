@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	s := &Schema{Fields: []*Field{
+		{Name: "ID", Type: TypeString, Required: true},
+		{Name: "Amount", Type: TypeDecimal, Required: true},
+		{Name: "Status", Type: TypeString, Enum: []string{"OPEN", "PAID"}},
+		{Name: "Note", Type: TypeString},
+		{Name: "Customer", Type: TypeRecord, Required: true, Fields: []*Field{
+			{Name: "Email", Type: TypeString, Required: true},
+		}},
+		{Name: "Items", Type: TypeArray, Items: &Field{Type: TypeRecord, Required: true, Fields: []*Field{
+			{Name: "Price", Type: TypeNumber, Required: true},
+		}}},
+		{Name: "Stock", Type: TypeMap, Values: &Field{Type: TypeInteger, Required: true}},
+	}}
+
+	t.Run("Given a valid message, it should report no violations", func(t *testing.T) {
+		data := map[string]interface{}{
+			"ID":       "abc",
+			"Amount":   12.5,
+			"Status":   "PAID",
+			"Note":     nil,
+			"Customer": map[string]interface{}{"Email": "ann@example.com"},
+			"Items":    []interface{}{map[string]interface{}{"Price": 3.0}},
+			"Stock":    map[string]interface{}{"sku-1": 4.0},
+		}
+		if violations := Validate(s, data); len(violations) != 0 {
+			t.Errorf("Expected no violations, got %+v", violations)
+		}
+	})
+
+	t.Run("Given an invalid message, it should report every violation with its path", func(t *testing.T) {
+		data := map[string]interface{}{
+			"Amount":   "12.50",
+			"Status":   "LOST",
+			"Customer": map[string]interface{}{"Phone": "555"},
+			"Items":    []interface{}{map[string]interface{}{"Price": "free"}},
+			"Stock":    map[string]interface{}{"sku-1": 1.5},
+		}
+		expected := []Violation{
+			{Path: "Amount", Kind: WrongType, Expected: TypeDecimal, Actual: TypeString},
+			{Path: "Customer.Email", Kind: MissingRequired, Expected: TypeString},
+			{Path: "Customer.Phone", Kind: UnexpectedField},
+			{Path: "ID", Kind: MissingRequired, Expected: TypeString},
+			{Path: "Items.Price", Kind: WrongType, Expected: TypeNumber, Actual: TypeString},
+			{Path: "Status", Kind: NotInEnum, Expected: TypeString},
+			{Path: "Stock", Kind: WrongType, Expected: TypeInteger, Actual: TypeNumber},
+		}
+		if violations := Validate(s, data); !reflect.DeepEqual(violations, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, violations)
+		}
+	})
+
+	t.Run("Given an XML document, it should check its text by inferred type", func(t *testing.T) {
+		xml := &Schema{Fields: []*Field{
+			{Name: "currency", Type: TypeString, Required: true, Attribute: true},
+			{Name: "Quantity", Type: TypeInteger, Required: true},
+			{Name: "Code", Type: TypeString, Required: true},
+			{Name: "Line", Type: TypeArray, Items: &Field{Type: TypeRecord, Required: true, Fields: []*Field{
+				{Name: "SKU", Type: TypeString, Required: true},
+			}}},
+		}}
+		data := map[string]interface{}{
+			"@currency": "EUR",
+			"Quantity":  map[string]interface{}{"#text": "three"},
+			"Code":      map[string]interface{}{"#text": "42"},
+			"Line":      map[string]interface{}{"SKU": map[string]interface{}{"#text": "A1"}},
+			"#text":     "\n  ",
+		}
+		expected := []Violation{{Path: "Quantity", Kind: WrongType, Expected: TypeInteger, Actual: TypeString}}
+		if violations := Validate(xml, data); !reflect.DeepEqual(violations, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, violations)
+		}
+	})
+
+	t.Run("Given a discriminated union, it should validate the message against its variant", func(t *testing.T) {
+		union := &Schema{
+			Fields:        []*Field{{Name: "type", Type: TypeString, Required: true, Enum: []string{"created", "paid"}}},
+			Discriminator: "type",
+			Variants: []*Variant{
+				{Value: "created", Fields: []*Field{
+					{Name: "type", Type: TypeString, Required: true, Enum: []string{"created"}},
+					{Name: "items", Type: TypeInteger, Required: true},
+				}},
+				{Value: "paid", Fields: []*Field{
+					{Name: "type", Type: TypeString, Required: true, Enum: []string{"paid"}},
+					{Name: "amount", Type: TypeNumber, Required: true},
+				}},
+			},
+		}
+		violations := Validate(union, map[string]interface{}{"type": "paid", "items": 2.0})
+		expected := []Violation{
+			{Path: "amount", Kind: MissingRequired, Expected: TypeNumber},
+			{Path: "items", Kind: UnexpectedField},
+		}
+		if !reflect.DeepEqual(violations, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, violations)
+		}
+	})
+}
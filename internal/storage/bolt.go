@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt is a Store backed by an embedded bbolt database, each bucket of the
+// store being a bbolt bucket.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt opens or creates the bbolt database at path.
+func OpenBolt(path string) (*Bolt, error) {
+	if path == "" {
+		return nil, fmt.Errorf("error opening bolt storage: empty path")
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening bolt storage: %v", err)
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Get(bucket, key string) ([]byte, bool, error) {
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if bkt := tx.Bucket([]byte(bucket)); bkt != nil {
+			if v := bkt.Get([]byte(key)); v != nil {
+				// Values are only valid during the transaction.
+				value = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s/%s: %v", bucket, key, err)
+	}
+	return value, value != nil, nil
+}
+
+func (b *Bolt) Put(bucket, key string, value []byte) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("error storing %s/%s: %v", bucket, key, err)
+	}
+	return nil
+}
+
+func (b *Bolt) Delete(bucket, key string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if bkt := tx.Bucket([]byte(bucket)); bkt != nil {
+			return bkt.Delete([]byte(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting %s/%s: %v", bucket, key, err)
+	}
+	return nil
+}
+
+func (b *Bolt) List(bucket string) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+		return bkt.ForEach(func(k, v []byte) error {
+			values[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %v", bucket, err)
+	}
+	return values, nil
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File is a Store keeping its values in memory and writing them all to a
+// JSON file after every change. The file is replaced atomically, so it holds
+// either the previous or the new state if thoth stops while writing, and a
+// change that cannot be written is undone.
+type File struct {
+	*Memory
+	path string
+	// write serializes changes, so the file is written in the order of the
+	// changes it holds.
+	write sync.Mutex
+}
+
+// OpenFile returns a store backed by the JSON file at path, loading the file
+// when it exists.
+func OpenFile(path string) (*File, error) {
+	if path == "" {
+		return nil, fmt.Errorf("error opening file storage: empty path")
+	}
+	f := &File{Memory: NewMemory(), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening file storage: %v", err)
+	}
+	var buckets map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return nil, fmt.Errorf("error decoding file storage %s: %v", path, err)
+	}
+	for bucket, values := range buckets {
+		for key, value := range values {
+			_ = f.Memory.Put(bucket, key, value)
+		}
+	}
+	return f, nil
+}
+
+func (f *File) Put(bucket, key string, value []byte) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("error storing value of %s/%s: not a JSON document", bucket, key)
+	}
+	f.write.Lock()
+	defer f.write.Unlock()
+	previous, found, _ := f.Memory.Get(bucket, key)
+	if err := f.Memory.Put(bucket, key, value); err != nil {
+		return err
+	}
+	if err := f.save(); err != nil {
+		f.restore(bucket, key, previous, found)
+		return err
+	}
+	return nil
+}
+
+func (f *File) Delete(bucket, key string) error {
+	f.write.Lock()
+	defer f.write.Unlock()
+	previous, found, _ := f.Memory.Get(bucket, key)
+	if err := f.Memory.Delete(bucket, key); err != nil {
+		return err
+	}
+	if err := f.save(); err != nil {
+		f.restore(bucket, key, previous, found)
+		return err
+	}
+	return nil
+}
+
+// restore puts back the value a key had before a change that could not be
+// written.
+func (f *File) restore(bucket, key string, previous []byte, found bool) {
+	if found {
+		_ = f.Memory.Put(bucket, key, previous)
+	} else {
+		_ = f.Memory.Delete(bucket, key)
+	}
+}
+
+// save writes every bucket to a temporary file renamed over the store file
+// once it is flushed to disk; the caller holds the write lock.
+func (f *File) save() error {
+	f.mu.RLock()
+	buckets := make(map[string]map[string]json.RawMessage, len(f.buckets))
+	for bucket, values := range f.buckets {
+		buckets[bucket] = make(map[string]json.RawMessage, len(values))
+		for key, value := range values {
+			buckets[bucket][key] = value
+		}
+	}
+	f.mu.RUnlock()
+
+	data, err := json.MarshalIndent(buckets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding file storage: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing file storage: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing file storage: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing file storage: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing file storage: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing file storage: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,547 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// Reader reads the records of the storage topic in order, blocking until the
+// next one is written.
+type Reader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+// Writer appends records to the storage topic.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+const (
+	// DefaultKafkaTopic is the compacted topic thoth instances share their
+	// state through.
+	DefaultKafkaTopic = "_thoth"
+	// DefaultLeaseDuration is how long an elected leader keeps accepting
+	// writes without renewing its lease.
+	DefaultLeaseDuration = 10 * time.Second
+	// DefaultKafkaTimeout bounds the wait for a record to be written and
+	// read back.
+	DefaultKafkaTimeout = 10 * time.Second
+)
+
+// Buckets of the storage topic reserved for the store itself: the lease of
+// the leader and the markers instances write to know they caught up.
+const (
+	leaseBucket = "_leader"
+	leaseKey    = "lease"
+	syncBucket  = "_sync"
+)
+
+// Headers of the records identifying the instance writing them and the write
+// an instance waits for. Write IDs start with a nonce drawn by each process,
+// so that a restarted instance does not take the records of its previous run
+// for its own writes.
+const (
+	instanceHeader = "thoth-instance"
+	writeHeader    = "thoth-write"
+)
+
+// KafkaOptions configures a Kafka store.
+type KafkaOptions struct {
+	// Instance identifies this instance to the others. When it is the URL
+	// of the instance, writes sent to other instances are redirected to it
+	// while it leads.
+	Instance string
+	// LeaseDuration defaults to DefaultLeaseDuration.
+	LeaseDuration time.Duration
+	// Timeout defaults to DefaultKafkaTimeout.
+	Timeout time.Duration
+}
+
+// NotLeaderError is returned by writes to a Kafka store of an instance that
+// does not hold the lease of the leader.
+type NotLeaderError struct {
+	// Leader is the instance holding the lease, empty when none does.
+	Leader string
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.Leader == "" {
+		return "error storing value: no leader elected"
+	}
+	return fmt.Sprintf("error storing value: writes go to the leader %s", e.Leader)
+}
+
+// recordKey is the key of a record of the storage topic. Values carry the
+// epoch of the lease they were written under, so that compaction keeps the
+// latest value of each key of each bucket per epoch and a record written late
+// by a deposed leader cannot take the place of a value of its successor.
+type recordKey struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Epoch  int    `json:"epoch,omitempty"`
+}
+
+// lease is the value of the lease record. A claim by another instance starts
+// a new epoch and is accepted when the current lease expired before it was
+// written; a renewal keeps the epoch of its holder.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Epoch   int       `json:"epoch"`
+	Expires time.Time `json:"expires"`
+}
+
+// Kafka is a Store shared by several thoth instances through a compacted
+// topic, like the _schemas topic of Confluent Schema Registry. Each instance
+// reads the topic from the start to rebuild its view of the values, then
+// keeps following it. Instances elect a leader with a lease stored in the
+// same topic; only the leader writes. A value written in an older epoch than
+// the one it is read in, or than the value it would replace, is ignored by
+// every instance, and the leader rewrites the current value in its own epoch
+// with tombstones for the older ones, so that compaction cannot bring the
+// ignored record back.
+type Kafka struct {
+	*Memory
+	reader   Reader
+	writer   Writer
+	options  KafkaOptions
+	nonce    string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	followed chan struct{}
+	elected  chan struct{}
+
+	mu       sync.Mutex
+	lease    lease
+	epochs   map[recordKey]int
+	writes   int
+	pending  map[string]chan error
+	watchers []func(bucket, key string)
+	changes  []recordKey
+	changed  *sync.Cond
+	closing  bool
+	closed   bool
+}
+
+// OpenKafka returns a store following topic on the given brokers. It returns
+// once the values already in the topic are loaded.
+func OpenKafka(brokers []string, topic string, options KafkaOptions) (*Kafka, error) {
+	if len(brokers) == 0 || topic == "" {
+		return nil, fmt.Errorf("error opening kafka storage: brokers and topic are required")
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		Topic:       topic,
+		Partition:   0,
+		StartOffset: kafka.FirstOffset,
+		MaxWait:     100 * time.Millisecond,
+	})
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}
+	return NewKafka(reader, writer, options)
+}
+
+// NewKafka returns a store reading its topic with reader and writing to it
+// with writer. The topic must have a single partition so that every instance
+// reads the records in the same order.
+func NewKafka(reader Reader, writer Writer, options KafkaOptions) (*Kafka, error) {
+	if options.Instance == "" {
+		return nil, fmt.Errorf("error opening kafka storage: instance is required")
+	}
+	if options.LeaseDuration <= 0 {
+		options.LeaseDuration = DefaultLeaseDuration
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultKafkaTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &Kafka{
+		Memory:   NewMemory(),
+		reader:   reader,
+		writer:   writer,
+		options:  options,
+		nonce:    uuid.NewString(),
+		ctx:      ctx,
+		cancel:   cancel,
+		followed: make(chan struct{}),
+		elected:  make(chan struct{}),
+		pending:  map[string]chan error{},
+		epochs:   map[recordKey]int{},
+	}
+	k.changed = sync.NewCond(&k.mu)
+	go k.notify()
+	go k.follow(ctx)
+
+	// The marker is read back once every record written before it is loaded.
+	if err := k.send(recordKey{Bucket: syncBucket, Key: options.Instance}, nil, time.Now()); err != nil {
+		_ = k.stop()
+		return nil, fmt.Errorf("error loading kafka storage: %v", err)
+	}
+	go k.elect(ctx)
+	return k, nil
+}
+
+// Leader returns the instance holding the lease and whether it is this one.
+func (k *Kafka) Leader() (string, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !time.Now().Before(k.lease.Expires) {
+		return "", false
+	}
+	return k.lease.Holder, k.lease.Holder == k.options.Instance
+}
+
+func (k *Kafka) Put(bucket, key string, value []byte) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("error storing value of %s/%s: not a JSON document", bucket, key)
+	}
+	return k.write(bucket, key, value)
+}
+
+func (k *Kafka) Delete(bucket, key string) error {
+	return k.write(bucket, key, nil)
+}
+
+// Watch calls fn with the bucket and key of every value changed by another
+// instance, or by a previous run of this one, in the order of the topic,
+// after the change is applied.
+func (k *Kafka) Watch(fn func(bucket, key string)) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.watchers = append(k.watchers, fn)
+}
+
+// Close gives up the lease if this instance holds it, then stops following
+// the topic.
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	if k.closing {
+		k.mu.Unlock()
+		return nil
+	}
+	k.closing = true
+	k.mu.Unlock()
+
+	if _, leader := k.Leader(); leader {
+		now := time.Now()
+		k.mu.Lock()
+		epoch := k.lease.Epoch
+		k.mu.Unlock()
+		release, _ := json.Marshal(lease{Holder: k.options.Instance, Epoch: epoch, Expires: now})
+		if err := k.send(recordKey{Bucket: leaseBucket, Key: leaseKey}, release, now); err != nil {
+			log.Printf("Failed to release storage lease: %v", err)
+		}
+	}
+	k.cancel()
+	<-k.elected
+	return k.stop()
+}
+
+// stop ends following the topic and notifying the watchers.
+func (k *Kafka) stop() error {
+	k.cancel()
+	readErr := k.reader.Close()
+	<-k.followed
+	writeErr := k.writer.Close()
+
+	k.mu.Lock()
+	k.closed = true
+	k.changed.Broadcast()
+	k.mu.Unlock()
+	if readErr != nil {
+		return readErr
+	}
+	return writeErr
+}
+
+// write stores a value in the epoch of the lease of this instance, followed by
+// tombstones for the records of the key kept from the stale epochs and the
+// epoch of its current value.
+func (k *Kafka) write(bucket, key string, value []byte, stale ...int) error {
+	leader, ok := k.Leader()
+	if !ok {
+		return &NotLeaderError{Leader: leader}
+	}
+	k.mu.Lock()
+	epoch := k.lease.Epoch
+	if current, written := k.epochs[recordKey{Bucket: bucket, Key: key}]; written {
+		stale = append(stale, current)
+	}
+	k.mu.Unlock()
+
+	var superseded []recordKey
+	seen := map[int]bool{epoch: true}
+	for _, old := range stale {
+		if old < epoch && !seen[old] {
+			seen[old] = true
+			superseded = append(superseded, recordKey{Bucket: bucket, Key: key, Epoch: old})
+		}
+	}
+	return k.send(recordKey{Bucket: bucket, Key: key, Epoch: epoch}, value, time.Now(), superseded...)
+}
+
+// send writes a record, followed by tombstones for the superseded keys, and
+// waits until this instance reads the record back, so that the change is
+// visible once send returns.
+func (k *Kafka) send(record recordKey, value []byte, at time.Time, superseded ...recordKey) error {
+	bucket, key := record.Bucket, record.Key
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding key %s/%s: %v", bucket, key, err)
+	}
+	messages := make([]kafka.Message, 0, len(superseded)+1)
+	k.mu.Lock()
+	k.writes++
+	id := k.nonce + "-" + strconv.Itoa(k.writes)
+	done := make(chan error, 1)
+	k.pending[id] = done
+	k.mu.Unlock()
+	defer func() {
+		k.mu.Lock()
+		delete(k.pending, id)
+		k.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(k.ctx, k.options.Timeout)
+	defer cancel()
+	messages = append(messages, kafka.Message{
+		Key:   data,
+		Value: value,
+		Time:  at,
+		Headers: []kafka.Header{
+			{Key: instanceHeader, Value: []byte(k.options.Instance)},
+			{Key: writeHeader, Value: []byte(id)},
+		},
+	})
+	for _, old := range superseded {
+		oldKey, err := json.Marshal(old)
+		if err != nil {
+			return fmt.Errorf("error encoding key %s/%s: %v", bucket, key, err)
+		}
+		messages = append(messages, kafka.Message{
+			Key:     oldKey,
+			Time:    at,
+			Headers: []kafka.Header{{Key: instanceHeader, Value: []byte(k.options.Instance)}},
+		})
+	}
+	err = k.writer.WriteMessages(ctx, messages...)
+	if err != nil {
+		return fmt.Errorf("error storing %s/%s: %v", bucket, key, err)
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("error storing %s/%s: %v", bucket, key, ctx.Err())
+	}
+}
+
+// follow applies the records of the topic until the store is closed.
+func (k *Kafka) follow(ctx context.Context) {
+	defer close(k.followed)
+	for {
+		message, err := k.reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to read storage topic: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		k.apply(message)
+	}
+}
+
+func (k *Kafka) apply(message kafka.Message) {
+	var key recordKey
+	if err := json.Unmarshal(message.Key, &key); err != nil {
+		log.Printf("Skipping storage record with invalid key %q: %v", message.Key, err)
+		return
+	}
+	instance := header(message, instanceHeader)
+	write := header(message, writeHeader)
+	own := strings.HasPrefix(write, k.nonce+"-")
+
+	var err error
+	switch key.Bucket {
+	case syncBucket:
+	case leaseBucket:
+		err = k.applyLease(message)
+	default:
+		err = k.applyValue(key, instance, own, message)
+	}
+
+	if !own {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if done, ok := k.pending[write]; ok {
+		done <- err
+	}
+}
+
+func (k *Kafka) applyLease(message kafka.Message) error {
+	if message.Value == nil {
+		return nil
+	}
+	var claim lease
+	if err := json.Unmarshal(message.Value, &claim); err != nil {
+		log.Printf("Skipping invalid storage lease: %v", err)
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.lease.Holder != claim.Holder && (message.Time.Before(k.lease.Expires) || claim.Epoch <= k.lease.Epoch) {
+		return &NotLeaderError{Leader: k.lease.Holder}
+	}
+	if k.lease.Holder == claim.Holder && claim.Epoch != k.lease.Epoch {
+		return &NotLeaderError{Leader: k.lease.Holder}
+	}
+	if k.lease.Holder != claim.Holder {
+		log.Printf("Storage leader is now %s", claim.Holder)
+	}
+	k.lease = claim
+	return nil
+}
+
+// applyValue applies a value record, passing it on to the watchers unless
+// this process wrote it. Records of an older epoch than the value of their key
+// or than the lease, and records of the current epoch not written by its
+// holder, are ignored; the leader then rewrites the value of the key so that
+// the ignored record is compacted away.
+func (k *Kafka) applyValue(record recordKey, instance string, own bool, message kafka.Message) error {
+	key := recordKey{Bucket: record.Bucket, Key: record.Key}
+	k.mu.Lock()
+	current := k.lease
+	applied, written := k.epochs[key]
+	superseded := written && record.Epoch < applied
+	stale := record.Epoch < current.Epoch || record.Epoch == current.Epoch && current.Holder != "" && instance != current.Holder
+	if superseded || stale {
+		// Tombstones of superseded records are the cleanup itself.
+		reassert := current.Holder == k.options.Instance && (stale || message.Value != nil)
+		k.mu.Unlock()
+		if reassert {
+			go k.reassert(key, record.Epoch)
+		}
+		return &NotLeaderError{Leader: current.Holder}
+	}
+	k.epochs[key] = record.Epoch
+	k.mu.Unlock()
+
+	if message.Value == nil {
+		_ = k.Memory.Delete(key.Bucket, key.Key)
+	} else {
+		_ = k.Memory.Put(key.Bucket, key.Key, message.Value)
+	}
+	if !own {
+		k.mu.Lock()
+		k.changes = append(k.changes, key)
+		k.changed.Signal()
+		k.mu.Unlock()
+	}
+	return nil
+}
+
+// reassert rewrites the current value of a key in the epoch of the leader,
+// superseding the records of the key kept from the stale epoch.
+func (k *Kafka) reassert(key recordKey, stale int) {
+	value, found, _ := k.Memory.Get(key.Bucket, key.Key)
+	if !found {
+		value = nil
+	}
+	err := k.write(key.Bucket, key.Key, value, stale)
+	var notLeader *NotLeaderError
+	if err != nil && !errors.As(err, &notLeader) {
+		log.Printf("Failed to supersede stale record of %s/%s: %v", key.Bucket, key.Key, err)
+	}
+}
+
+// notify passes the changes not made by this process to the watchers. It runs
+// apart from follow so that watchers may wait on writes of this instance.
+func (k *Kafka) notify() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for {
+		for len(k.changes) == 0 && !k.closed {
+			k.changed.Wait()
+		}
+		if k.closed {
+			return
+		}
+		key := k.changes[0]
+		k.changes = k.changes[1:]
+		watchers := k.watchers
+		k.mu.Unlock()
+		for _, watch := range watchers {
+			watch(key.Bucket, key.Key)
+		}
+		k.mu.Lock()
+	}
+}
+
+// elect claims the lease whenever it is free, and renews it while this
+// instance holds it.
+func (k *Kafka) elect(ctx context.Context) {
+	defer close(k.elected)
+	ticker := time.NewTicker(k.options.LeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		k.claim()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (k *Kafka) claim() {
+	k.mu.Lock()
+	current, closing := k.lease, k.closing
+	k.mu.Unlock()
+	now := time.Now()
+	if closing || current.Holder != k.options.Instance && now.Before(current.Expires) {
+		return
+	}
+	epoch := current.Epoch
+	if current.Holder != k.options.Instance {
+		epoch++
+	}
+	data, _ := json.Marshal(lease{Holder: k.options.Instance, Epoch: epoch, Expires: now.Add(k.options.LeaseDuration)})
+	err := k.send(recordKey{Bucket: leaseBucket, Key: leaseKey}, data, now)
+	var notLeader *NotLeaderError
+	if err != nil && !errors.As(err, &notLeader) {
+		log.Printf("Failed to claim storage lease: %v", err)
+	}
+}
+
+func header(message kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
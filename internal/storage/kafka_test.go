@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// topic is an in-memory single partition topic shared by the instances of a
+// test.
+type topic struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	written  chan struct{}
+}
+
+func newTopic() *topic {
+	return &topic{written: make(chan struct{})}
+}
+
+func (t *topic) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, message := range msgs {
+		message.Offset = int64(len(t.messages))
+		t.messages = append(t.messages, message)
+	}
+	close(t.written)
+	t.written = make(chan struct{})
+	return nil
+}
+
+func (t *topic) Close() error {
+	return nil
+}
+
+// compact keeps the last record of each key, like the log cleaner.
+func (t *topic) compact() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	last := map[string]int{}
+	for i, message := range t.messages {
+		last[string(message.Key)] = i
+	}
+	var kept []kafka.Message
+	for i, message := range t.messages {
+		if last[string(message.Key)] == i {
+			kept = append(kept, message)
+		}
+	}
+	t.messages = kept
+}
+
+// has reports whether the topic holds a record with the given key.
+func (t *topic) has(key recordKey) bool {
+	data, _ := json.Marshal(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, message := range t.messages {
+		if string(message.Key) == string(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicReader reads a topic from its first record, waiting delay before
+// each record.
+type topicReader struct {
+	topic  *topic
+	offset int
+	delay  time.Duration
+}
+
+func (r *topicReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	time.Sleep(r.delay)
+	for {
+		r.topic.mu.Lock()
+		if r.offset < len(r.topic.messages) {
+			message := r.topic.messages[r.offset]
+			r.offset++
+			r.topic.mu.Unlock()
+			return message, nil
+		}
+		written := r.topic.written
+		r.topic.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-written:
+		}
+	}
+}
+
+func (r *topicReader) Close() error {
+	return nil
+}
+
+func openInstance(t *testing.T, topic *topic, instance string) *Kafka {
+	t.Helper()
+	return openReader(t, &topicReader{topic: topic}, instance)
+}
+
+func openReader(t *testing.T, reader *topicReader, instance string) *Kafka {
+	t.Helper()
+	store, err := NewKafka(reader, reader.topic, KafkaOptions{
+		Instance:      instance,
+		LeaseDuration: 300 * time.Millisecond,
+		Timeout:       time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return store
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func isLeader(store *Kafka) func() bool {
+	return func() bool {
+		_, leader := store.Leader()
+		return leader
+	}
+}
+
+func TestKafka(t *testing.T) {
+	t.Run("Given a single instance, it should lead and share the contract of every store", func(t *testing.T) {
+		store := openInstance(t, newTopic(), "http://thoth-1:8080")
+		defer store.Close()
+		waitFor(t, "the lease", isLeader(store))
+		testStore(t, store)
+	})
+
+	t.Run("Given a restarted instance, it should rebuild its values from the topic", func(t *testing.T) {
+		shared := newTopic()
+		first := openInstance(t, shared, "thoth-1")
+		waitFor(t, "the lease", isLeader(first))
+		_ = first.Put("subjects", "users", []byte(`[1]`))
+		_ = first.Put("subjects", "orders", []byte(`[2]`))
+		_ = first.Delete("subjects", "orders")
+		first.Close()
+
+		restarted := openInstance(t, shared, "thoth-1")
+		defer restarted.Close()
+		if value, found, _ := restarted.Get("subjects", "users"); !found || string(value) != `[1]` {
+			t.Errorf("Expected the stored value, got %q", value)
+		}
+		if _, found, _ := restarted.Get("subjects", "orders"); found {
+			t.Error("Expected the deleted key to stay deleted")
+		}
+	})
+
+	t.Run("Given a slow restart, it should return once every value of the previous run is loaded", func(t *testing.T) {
+		shared := newTopic()
+		first := openInstance(t, shared, "thoth-1")
+		waitFor(t, "the lease", isLeader(first))
+		for i := 0; i < 6; i++ {
+			_ = first.Put("subjects", fmt.Sprintf("subject-%d", i), []byte(`[1]`))
+		}
+		first.Close()
+
+		restarted := openReader(t, &topicReader{topic: shared, delay: 5 * time.Millisecond}, "thoth-1")
+		defer restarted.Close()
+		if values, _ := restarted.List("subjects"); len(values) != 6 {
+			t.Errorf("Expected 6 subjects loaded at return, got %d", len(values))
+		}
+	})
+
+	t.Run("Given another process with the same instance, it should notify its changes", func(t *testing.T) {
+		shared := newTopic()
+		first := openInstance(t, shared, "thoth-1")
+		defer first.Close()
+		waitFor(t, "the lease", isLeader(first))
+		second := openInstance(t, shared, "thoth-1")
+		defer second.Close()
+
+		watched := make(chan string, 1)
+		second.Watch(func(bucket, key string) { watched <- bucket + "/" + key })
+		if err := first.Put("subjects", "users", []byte(`[1]`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case change := <-watched:
+			if change != "subjects/users" {
+				t.Errorf("Expected subjects/users to be notified, got %s", change)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("Expected the change of the other process to be notified")
+		}
+	})
+
+	t.Run("Given two instances, it should let only the leader write and the other follow", func(t *testing.T) {
+		shared := newTopic()
+		leader := openInstance(t, shared, "http://thoth-1:8080")
+		defer leader.Close()
+		waitFor(t, "the lease", isLeader(leader))
+		follower := openInstance(t, shared, "http://thoth-2:8080")
+		defer follower.Close()
+
+		var mu sync.Mutex
+		var watched []string
+		follower.Watch(func(bucket, key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			watched = append(watched, bucket+"/"+key)
+		})
+
+		err := follower.Put("subjects", "users", []byte(`[1]`))
+		var notLeader *NotLeaderError
+		if !errors.As(err, &notLeader) || notLeader.Leader != "http://thoth-1:8080" {
+			t.Fatalf("Expected a NotLeaderError naming the leader, got %v", err)
+		}
+		if err := leader.Put("subjects", "users", []byte(`[1]`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		waitFor(t, "the follower to be notified", func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(watched) == 1 && watched[0] == "subjects/users"
+		})
+		if value, found, _ := follower.Get("subjects", "users"); !found || string(value) != `[1]` {
+			t.Errorf("Expected the follower to read the value, got %q", value)
+		}
+	})
+
+	t.Run("Given a record written by an instance without the lease, it should ignore it", func(t *testing.T) {
+		shared := newTopic()
+		store := openInstance(t, shared, "thoth-1")
+		defer store.Close()
+		waitFor(t, "the lease", isLeader(store))
+
+		key, _ := json.Marshal(recordKey{Bucket: "subjects", Key: "users"})
+		_ = shared.WriteMessages(context.Background(), kafka.Message{
+			Key:     key,
+			Value:   []byte(`["rogue"]`),
+			Time:    time.Now(),
+			Headers: []kafka.Header{{Key: instanceHeader, Value: []byte("thoth-2")}},
+		})
+		if err := store.Put("subjects", "orders", []byte(`[1]`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, found, _ := store.Get("subjects", "users"); found {
+			t.Error("Expected the record of thoth-2 to be ignored")
+		}
+	})
+
+	t.Run("Given a late write of a deposed leader, it should stay ignored once the topic is compacted", func(t *testing.T) {
+		shared := newTopic()
+		deposed := openInstance(t, shared, "thoth-1")
+		waitFor(t, "the lease", isLeader(deposed))
+		if err := deposed.Put("subjects", "users", []byte(`["valid"]`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		leader := openInstance(t, shared, "thoth-2")
+		defer leader.Close()
+		deposed.Close()
+		waitFor(t, "the lease to be handed over", isLeader(leader))
+
+		// A write of thoth-1 still in flight when it lost the lease.
+		key, _ := json.Marshal(recordKey{Bucket: "subjects", Key: "users", Epoch: 1})
+		_ = shared.WriteMessages(context.Background(), kafka.Message{
+			Key:     key,
+			Value:   []byte(`["stale"]`),
+			Time:    time.Now(),
+			Headers: []kafka.Header{{Key: instanceHeader, Value: []byte("thoth-1")}},
+		})
+		waitFor(t, "the value to be rewritten", func() bool {
+			return shared.has(recordKey{Bucket: "subjects", Key: "users", Epoch: 2})
+		})
+		shared.compact()
+
+		replayed := openInstance(t, shared, "thoth-3")
+		defer replayed.Close()
+		for name, store := range map[string]*Kafka{"leader": leader, "replayed": replayed} {
+			if value, _, _ := store.Get("subjects", "users"); string(value) != `["valid"]` {
+				t.Errorf("Expected the %s to keep the valid value, got %q", name, value)
+			}
+		}
+	})
+
+	t.Run("Given a closed leader, it should hand the lease over", func(t *testing.T) {
+		shared := newTopic()
+		leader := openInstance(t, shared, "thoth-1")
+		waitFor(t, "the lease", isLeader(leader))
+		follower := openInstance(t, shared, "thoth-2")
+		defer follower.Close()
+
+		leader.Close()
+		waitFor(t, "the lease to be handed over", isLeader(follower))
+		if err := follower.Put("subjects", "users", []byte(`[1]`)); err != nil {
+			t.Errorf("Expected the new leader to write, got %v", err)
+		}
+	})
+}
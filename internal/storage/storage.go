@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store is a key-value store in which thoth keeps its state, the keys being
+// grouped in named buckets. Values are JSON documents.
+type Store interface {
+	// Get returns the value of a key and whether it exists.
+	Get(bucket, key string) ([]byte, bool, error)
+	// Put creates or replaces the value of a key.
+	Put(bucket, key string, value []byte) error
+	// Delete removes a key; deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// List returns every key of a bucket with its value.
+	List(bucket string) (map[string][]byte, error)
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// Watcher is implemented by stores shared by several thoth instances. Watch
+// registers fn to be called with the bucket and key of every value another
+// instance changes.
+type Watcher interface {
+	Watch(fn func(bucket, key string))
+}
+
+// Open returns the store described by spec: "memory", "file:<path>" for a
+// JSON file, "bolt:<path>" for a bbolt database or
+// "kafka:<broker>,<broker>/<topic>?instance=<url>" for a compacted Kafka
+// topic, the topic defaulting to DefaultKafkaTopic and the instance to the
+// host name.
+func Open(spec string) (Store, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "memory":
+		return NewMemory(), nil
+	case "file":
+		return OpenFile(path)
+	case "bolt":
+		return OpenBolt(path)
+	case "kafka":
+		return openKafkaSpec(path)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", spec)
+	}
+}
+
+func openKafkaSpec(spec string) (*Kafka, error) {
+	spec, rawQuery, _ := strings.Cut(spec, "?")
+	brokers, topic, _ := strings.Cut(spec, "/")
+	if topic == "" {
+		topic = DefaultKafkaTopic
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error opening kafka storage: %v", err)
+	}
+	options := KafkaOptions{Instance: query.Get("instance")}
+	if options.Instance == "" {
+		if options.Instance, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("error opening kafka storage: %v", err)
+		}
+	}
+	var addresses []string
+	for _, broker := range strings.Split(brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			addresses = append(addresses, broker)
+		}
+	}
+	return OpenKafka(addresses, topic, options)
+}
+
+// Keys returns the keys of a bucket in alphabetical order.
+func Keys(store Store, bucket string) ([]string, error) {
+	values, err := store.List(bucket)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Memory is a Store keeping its values in memory only.
+type Memory struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]map[string][]byte{}}
+}
+
+func (m *Memory) Get(bucket, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.buckets[bucket][key]
+	return append([]byte(nil), value...), ok, nil
+}
+
+func (m *Memory) Put(bucket, key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("error storing value: empty key")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values, ok := m.buckets[bucket]
+	if !ok {
+		values = map[string][]byte{}
+		m.buckets[bucket] = values
+	}
+	values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *Memory) Delete(bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *Memory) List(bucket string) (map[string][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	values := make(map[string][]byte, len(m.buckets[bucket]))
+	for key, value := range m.buckets[bucket] {
+		values[key] = append([]byte(nil), value...)
+	}
+	return values, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
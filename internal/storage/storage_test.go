@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testStore checks the behaviour every Store shares.
+func testStore(t *testing.T, store Store) {
+	t.Run("Given a stored value, it should return it", func(t *testing.T) {
+		if err := store.Put("subjects", "users", []byte(`{"version": 1}`)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		value, found, err := store.Get("subjects", "users")
+		if err != nil || !found || string(value) != `{"version": 1}` {
+			t.Errorf("Expected the stored value, got %q (%v, %v)", value, found, err)
+		}
+	})
+
+	t.Run("Given a missing key or bucket, it should report it as not found", func(t *testing.T) {
+		if _, found, err := store.Get("subjects", "orders"); found || err != nil {
+			t.Errorf("Expected no value, got %v (%v)", found, err)
+		}
+		if _, found, err := store.Get("unknown", "users"); found || err != nil {
+			t.Errorf("Expected no value, got %v (%v)", found, err)
+		}
+	})
+
+	t.Run("Given several keys, it should list them by bucket", func(t *testing.T) {
+		_ = store.Put("subjects", "orders", []byte(`{}`))
+		_ = store.Put("config", "level", []byte(`"FULL"`))
+		keys, err := Keys(store, "subjects")
+		if err != nil || len(keys) != 2 || keys[0] != "orders" || keys[1] != "users" {
+			t.Errorf("Expected [orders users], got %v (%v)", keys, err)
+		}
+	})
+
+	t.Run("Given a deleted key, it should forget it", func(t *testing.T) {
+		if err := store.Delete("subjects", "orders"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, found, _ := store.Get("subjects", "orders"); found {
+			t.Error("Expected the key to be deleted")
+		}
+		if err := store.Delete("unknown", "orders"); err != nil {
+			t.Errorf("Expected deleting a missing key to succeed, got %v", err)
+		}
+	})
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thoth.json")
+	store, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testStore(t, store)
+
+	t.Run("Given a reopened file, it should load the stored values", func(t *testing.T) {
+		reopened, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if value, found, _ := reopened.Get("config", "level"); !found || string(value) != `"FULL"` {
+			t.Errorf("Expected the stored level, got %q", value)
+		}
+	})
+
+	t.Run("Given a value that is not JSON, it should return an error", func(t *testing.T) {
+		if err := store.Put("config", "level", []byte("FULL")); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a change that cannot be written, it should undo it", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "removed")
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		store, _ := OpenFile(filepath.Join(dir, "thoth.json"))
+		_ = store.Put("config", "level", []byte(`"FULL"`))
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := store.Put("config", "level", []byte(`"NONE"`)); err == nil {
+			t.Error("Expected an error")
+		}
+		if err := store.Delete("config", "level"); err == nil {
+			t.Error("Expected an error")
+		}
+		if err := store.Put("config", "mode", []byte(`"READWRITE"`)); err == nil {
+			t.Error("Expected an error")
+		}
+		if value, _, _ := store.Get("config", "level"); string(value) != `"FULL"` {
+			t.Errorf("Expected the level to stay FULL, got %q", value)
+		}
+		if _, found, _ := store.Get("config", "mode"); found {
+			t.Error("Expected the mode not to be stored")
+		}
+	})
+}
+
+func TestBolt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thoth.db")
+	store, err := OpenBolt(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testStore(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Given a reopened database, it should load the stored values", func(t *testing.T) {
+		reopened, err := OpenBolt(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer reopened.Close()
+		if value, found, _ := reopened.Get("subjects", "users"); !found || string(value) != `{"version": 1}` {
+			t.Errorf("Expected the stored value, got %q", value)
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("Given an unknown kind of storage, it should return an error", func(t *testing.T) {
+		if _, err := Open("redis:localhost"); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Given a file spec, it should open a file store", func(t *testing.T) {
+		store, err := Open("file:" + filepath.Join(t.TempDir(), "thoth.json"))
+		if _, ok := store.(*File); !ok || err != nil {
+			t.Errorf("Expected a file store, got %T (%v)", store, err)
+		}
+	})
+}